@@ -0,0 +1,14 @@
+--
+-- Tabla de sesiones web (cookie firmada -> token de Autenticacion)
+--
+
+CREATE TABLE IF NOT EXISTS `sesiones` (
+  `TOKEN` varchar(64) NOT NULL COMMENT 'Token aleatorio generado por Autenticacion.GenerarToken',
+  `USUARIO_ID` bigint NOT NULL COMMENT 'Usuario dueño de la sesion',
+  `PERFIL_ID` bigint DEFAULT NULL COMMENT 'Perfil seleccionado dentro de la sesion',
+  `FECHA_CREACION` datetime NOT NULL COMMENT 'Momento en que se inicio la sesion',
+  `FECHA_EXPIRACION` datetime NOT NULL COMMENT 'Momento a partir del cual el token deja de ser valido',
+  PRIMARY KEY (`TOKEN`),
+  KEY `SESIONES_USUARIOS_FK` (`USUARIO_ID`),
+  CONSTRAINT `SESIONES_USUARIOS_FK` FOREIGN KEY (`USUARIO_ID`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para gestionar las sesiones activas';
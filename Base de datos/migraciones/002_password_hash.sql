@@ -0,0 +1,7 @@
+--
+-- Ampliar PASSWORD_HASH para hashes codificados (argon2id ~100 caracteres, bcrypt 60).
+-- Las filas existentes ("HASH_..." o texto plano) se migran al iniciar sesión.
+--
+
+ALTER TABLE `usuarios`
+  MODIFY `PASSWORD_HASH` varchar(255) DEFAULT NULL COMMENT 'Hash codificado de la contraseña (algoritmo y parametros incluidos)';
@@ -0,0 +1,15 @@
+--
+-- Roles por usuario (viewer, content-editor, billing-admin, super-admin).
+-- Un usuario sin filas se considera viewer.
+--
+
+CREATE TABLE IF NOT EXISTS `usuario_roles` (
+  `USUARIO_ID` bigint NOT NULL COMMENT 'Usuario al que se asigna el rol',
+  `ROL` varchar(20) NOT NULL COMMENT 'Nombre del rol (viewer, content-editor, billing-admin, super-admin)',
+  PRIMARY KEY (`USUARIO_ID`, `ROL`),
+  CONSTRAINT `USUARIO_ROLES_USUARIOS_FK` FOREIGN KEY (`USUARIO_ID`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para gestionar los roles de los usuarios';
+
+-- La cuenta administrativa histórica pasa a ser super-admin.
+INSERT IGNORE INTO `usuario_roles` (`USUARIO_ID`, `ROL`)
+SELECT `USUARIO_ID`, 'super-admin' FROM `usuarios` WHERE `EMAIL` = 'admin@stream.com';
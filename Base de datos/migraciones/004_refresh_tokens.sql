@@ -0,0 +1,17 @@
+--
+-- Refresh tokens de la API JSON. Solo se guarda el SHA-256 del token.
+-- Los tokens rotados quedan con USADO = 1; presentarlos de nuevo revoca su FAMILIA.
+--
+
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `TOKEN_HASH` char(64) NOT NULL COMMENT 'SHA-256 en hexadecimal del refresh token',
+  `USUARIO_ID` bigint NOT NULL COMMENT 'Usuario dueño del token',
+  `FAMILIA` varchar(32) NOT NULL COMMENT 'Cadena de rotacion iniciada en un mismo login',
+  `FECHA_EXPIRACION` datetime NOT NULL COMMENT 'Fecha limite de uso del token',
+  `USADO` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'El token ya fue cambiado por uno nuevo',
+  `REVOCADO` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'El token esta en la lista de revocacion',
+  PRIMARY KEY (`TOKEN_HASH`),
+  KEY `REFRESH_TOKENS_FAMILIA_IDX` (`FAMILIA`),
+  KEY `REFRESH_TOKENS_USUARIOS_FK` (`USUARIO_ID`),
+  CONSTRAINT `REFRESH_TOKENS_USUARIOS_FK` FOREIGN KEY (`USUARIO_ID`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para gestionar los refresh tokens y su revocacion';
@@ -0,0 +1,13 @@
+--
+-- Tokens de un solo uso para restablecer la contraseña. Solo se guarda el SHA-256.
+--
+
+CREATE TABLE IF NOT EXISTS `tokens_recuperacion` (
+  `TOKEN_HASH` char(64) NOT NULL COMMENT 'SHA-256 en hexadecimal del token enviado por correo',
+  `USUARIO_ID` bigint NOT NULL COMMENT 'Usuario que solicito la recuperacion',
+  `FECHA_EXPIRACION` datetime NOT NULL COMMENT 'Fecha limite de uso (UTC)',
+  `USADO` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'El token ya se utilizo o fue invalidado',
+  PRIMARY KEY (`TOKEN_HASH`),
+  KEY `TOKENS_RECUPERACION_USUARIOS_FK` (`USUARIO_ID`),
+  CONSTRAINT `TOKENS_RECUPERACION_USUARIOS_FK` FOREIGN KEY (`USUARIO_ID`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para gestionar la recuperacion de contraseñas';
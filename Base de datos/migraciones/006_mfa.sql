@@ -0,0 +1,29 @@
+--
+-- Verificación en dos pasos (TOTP, RFC 6238) y códigos de recuperación.
+--
+
+ALTER TABLE `sesiones`
+  ADD COLUMN `MFA_PENDIENTE` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'La sesion aun no verifico el segundo factor' AFTER `PERFIL_ID`;
+
+CREATE TABLE IF NOT EXISTS `mfa_usuarios` (
+  `USUARIO_ID` bigint NOT NULL COMMENT 'Usuario inscrito',
+  `SECRETO` varchar(64) NOT NULL COMMENT 'Secreto TOTP en base32',
+  `ACTIVO` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'El usuario confirmo el primer codigo',
+  `ULTIMO_PASO` bigint NOT NULL DEFAULT 0 COMMENT 'Ultimo paso TOTP aceptado (evita reutilizar codigos)',
+  PRIMARY KEY (`USUARIO_ID`),
+  CONSTRAINT `MFA_USUARIOS_USUARIOS_FK` FOREIGN KEY (`USUARIO_ID`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para gestionar el segundo factor de los usuarios';
+
+CREATE TABLE IF NOT EXISTS `codigos_recuperacion` (
+  `USUARIO_ID` bigint NOT NULL COMMENT 'Usuario dueño del codigo',
+  `CODIGO_HASH` char(64) NOT NULL COMMENT 'SHA-256 del codigo de recuperacion',
+  `USADO` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'El codigo ya se utilizo',
+  PRIMARY KEY (`USUARIO_ID`, `CODIGO_HASH`),
+  CONSTRAINT `CODIGOS_RECUPERACION_USUARIOS_FK` FOREIGN KEY (`USUARIO_ID`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para gestionar los codigos de recuperacion MFA';
+
+CREATE TABLE IF NOT EXISTS `roles_mfa` (
+  `ROL` varchar(20) NOT NULL COMMENT 'Nombre del rol',
+  `MFA_OBLIGATORIO` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'Los usuarios con este rol deben usar MFA',
+  PRIMARY KEY (`ROL`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para la politica de MFA por rol';
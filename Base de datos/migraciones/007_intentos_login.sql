@@ -0,0 +1,22 @@
+--
+-- Limitador de intentos de inicio de sesión (backend MySQL para varias instancias).
+-- CLAVE es "cuenta:<email>", "ip:<direccion>" o "mfa:<usuario>".
+--
+
+CREATE TABLE IF NOT EXISTS `login_intentos` (
+  `CLAVE` varchar(100) NOT NULL COMMENT 'Cuenta, IP o usuario MFA al que se aplica el contador',
+  `FALLOS` int NOT NULL DEFAULT 0 COMMENT 'Fallos consecutivos dentro de la ventana',
+  `ULTIMO_FALLO` datetime NOT NULL COMMENT 'Momento del ultimo fallo',
+  `BLOQUEADO_HASTA` datetime DEFAULT NULL COMMENT 'Espera o bloqueo vigente',
+  PRIMARY KEY (`CLAVE`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para limitar intentos de inicio de sesion';
+
+CREATE TABLE IF NOT EXISTS `login_bloqueos` (
+  `BLOQUEO_ID` bigint NOT NULL AUTO_INCREMENT COMMENT 'ID del evento',
+  `CLAVE` varchar(100) NOT NULL COMMENT 'Clave bloqueada',
+  `FALLOS` int NOT NULL COMMENT 'Fallos acumulados al bloquear',
+  `FECHA` datetime NOT NULL COMMENT 'Momento del bloqueo',
+  `BLOQUEADO_HASTA` datetime NOT NULL COMMENT 'Fin del bloqueo',
+  PRIMARY KEY (`BLOQUEO_ID`),
+  KEY `LOGIN_BLOQUEOS_FECHA_IDX` (`FECHA`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para registrar los bloqueos de cuentas';
@@ -0,0 +1,11 @@
+--
+-- Verificación del correo electrónico al registrarse.
+-- Las cuentas existentes se consideran verificadas.
+-- EMAIL se amplía a 254 caracteres (límite de RFC 5321).
+--
+
+ALTER TABLE `usuarios`
+  MODIFY `EMAIL` varchar(254) NOT NULL COMMENT 'Usuario registra su correo para iniciar sesion',
+  ADD COLUMN `EMAIL_VERIFICADO` tinyint(1) NOT NULL DEFAULT '0' COMMENT 'El usuario confirmo su correo con el enlace enviado al registrarse';
+
+UPDATE `usuarios` SET `EMAIL_VERIFICADO` = 1;
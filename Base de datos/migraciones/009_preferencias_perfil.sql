@@ -0,0 +1,11 @@
+--
+-- Avatares y preferencias por perfil.
+-- NOMBRE_PERFIL se amplía a 30 caracteres.
+--
+
+ALTER TABLE `perfiles`
+  MODIFY `NOMBRE_PERFIL` varchar(30) DEFAULT NULL COMMENT 'Registro del nombre del perfil',
+  ADD COLUMN `AVATAR` varchar(20) NOT NULL DEFAULT 'clasico' COMMENT 'Avatar elegido del conjunto incluido en la aplicacion',
+  ADD COLUMN `IDIOMA_INTERFAZ` char(2) NOT NULL DEFAULT 'es' COMMENT 'Idioma de la interfaz (ISO 639-1)',
+  ADD COLUMN `IDIOMA_SUBTITULOS` varchar(2) NOT NULL DEFAULT '' COMMENT 'Idioma de los subtitulos; vacio si estan desactivados',
+  ADD COLUMN `AUTOPLAY` tinyint(1) NOT NULL DEFAULT '1' COMMENT 'Reproducir automaticamente el siguiente episodio';
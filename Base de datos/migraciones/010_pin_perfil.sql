@@ -0,0 +1,6 @@
+--
+-- PIN opcional de 4 dígitos por perfil (solo se guarda su hash).
+--
+
+ALTER TABLE `perfiles`
+  ADD COLUMN `PIN_HASH` varchar(255) DEFAULT NULL COMMENT 'Hash del PIN del perfil; NULL si el perfil no tiene PIN';
@@ -0,0 +1,8 @@
+--
+-- Dispositivo y último acceso de cada sesión, para /account/devices.
+--
+
+ALTER TABLE `sesiones`
+  ADD COLUMN `ULTIMO_ACCESO` datetime DEFAULT NULL COMMENT 'Última petición con la sesión (se actualiza como mucho una vez por minuto)',
+  ADD COLUMN `USER_AGENT` varchar(255) DEFAULT NULL COMMENT 'User agent del navegador o app que inició la sesión',
+  ADD COLUMN `IP` varchar(45) DEFAULT NULL COMMENT 'IP de la última petición (IPv4 o IPv6)';
@@ -0,0 +1,14 @@
+--
+-- Identidades de proveedores OpenID Connect vinculadas a usuarios locales.
+--
+
+CREATE TABLE IF NOT EXISTS `identidades_externas` (
+  `ISSUER` varchar(255) NOT NULL COMMENT 'Issuer del proveedor OIDC',
+  `SUBJECT` varchar(255) NOT NULL COMMENT 'Claim sub del id_token, unico dentro del issuer',
+  `USUARIO_ID` bigint NOT NULL COMMENT 'Usuario local vinculado',
+  `EMAIL` varchar(254) DEFAULT NULL COMMENT 'Correo verificado con el que se vinculo',
+  `FECHA_VINCULACION` datetime NOT NULL COMMENT 'Primer inicio de sesion con esta identidad',
+  PRIMARY KEY (`ISSUER`, `SUBJECT`),
+  KEY `IDENTIDADES_EXTERNAS_USUARIOS_FK` (`USUARIO_ID`),
+  CONSTRAINT `IDENTIDADES_EXTERNAS_USUARIOS_FK` FOREIGN KEY (`USUARIO_ID`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla para vincular identidades OIDC con usuarios';
@@ -0,0 +1,18 @@
+--
+-- Baja de cuentas con periodo de gracia. Las transacciones y suscripciones de
+-- una cuenta eliminada se conservan para contabilidad, reasignadas a filas
+-- centinela (ID -1) para no romper sus claves foráneas NOT NULL.
+--
+
+ALTER TABLE `usuarios`
+  ADD COLUMN `FECHA_BAJA_PROGRAMADA` datetime DEFAULT NULL COMMENT 'Momento en que la cuenta se elimina definitivamente; NULL si no hay baja pendiente',
+  ADD KEY `IDX_USUARIOS_BAJA` (`FECHA_BAJA_PROGRAMADA`);
+
+INSERT IGNORE INTO `usuarios` (`USUARIO_ID`, `NOMBRE_USUARIO`, `EMAIL`, `PASSWORD_HASH`, `EMAIL_VERIFICADO`, `METODOS_PAGO_METODO_PAGO_ID`)
+VALUES (-1, 'anonimo', 'anonimo@invalid', NULL, 0, -1);
+
+INSERT IGNORE INTO `metodos_pago` (`METODO_PAGO_ID`, `USUARIO_ID`, `TIPO_PAGO`, `ES_PREDETERMINADO`, `USUARIOS_USUARIO_ID`)
+VALUES (-1, NULL, 'anonimizado', 0, -1);
+
+INSERT IGNORE INTO `perfiles` (`PERFIL_ID`, `USUARIO_ID`, `NOMBRE_PERFIL`, `USUARIOS_USUARIO_ID`)
+VALUES (-1, NULL, 'anonimo', -1);
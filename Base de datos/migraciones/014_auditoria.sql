@@ -0,0 +1,33 @@
+--
+-- Registro de auditoría de solo inserción, encadenado por hash: cada evento
+-- guarda el SHA-256 del anterior, y auditoria_cabeza serializa las escrituras.
+--
+
+CREATE TABLE IF NOT EXISTS `auditoria` (
+  `EVENTO_ID` bigint NOT NULL AUTO_INCREMENT COMMENT 'Orden de insercion del evento',
+  `FECHA` datetime(6) NOT NULL COMMENT 'Momento del evento (UTC)',
+  `ACTOR` varchar(254) NOT NULL COMMENT 'Correo de quien actua, o sistema',
+  `ACCION` varchar(40) NOT NULL COMMENT 'Tipo de evento (login, contenido_eliminado...)',
+  `OBJETIVO` varchar(255) NOT NULL DEFAULT '' COMMENT 'Usuario, contenido o clave afectada',
+  `IP` varchar(45) NOT NULL DEFAULT '' COMMENT 'IP de la peticion',
+  `DETALLE` varchar(500) NOT NULL DEFAULT '' COMMENT 'Informacion adicional',
+  `HASH_ANTERIOR` char(64) NOT NULL COMMENT 'HASH del evento anterior (vacio en el primero)',
+  `HASH` char(64) NOT NULL COMMENT 'SHA-256 de HASH_ANTERIOR y los campos del evento',
+  PRIMARY KEY (`EVENTO_ID`),
+  KEY `IDX_AUDITORIA_FECHA` (`FECHA`),
+  KEY `IDX_AUDITORIA_ACCION` (`ACCION`, `FECHA`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla de auditoria de seguridad (solo insercion)';
+
+CREATE TABLE IF NOT EXISTS `auditoria_cabeza` (
+  `ID` tinyint NOT NULL COMMENT 'Fila unica',
+  `ULTIMO_HASH` char(64) NOT NULL DEFAULT '' COMMENT 'HASH del ultimo evento insertado',
+  PRIMARY KEY (`ID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Cabeza de la cadena de auditoria';
+
+INSERT IGNORE INTO `auditoria_cabeza` (`ID`, `ULTIMO_HASH`) VALUES (1, '');
+
+CREATE TRIGGER `AUDITORIA_SIN_UPDATE` BEFORE UPDATE ON `auditoria`
+  FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'auditoria es de solo insercion';
+
+CREATE TRIGGER `AUDITORIA_SIN_DELETE` BEFORE DELETE ON `auditoria`
+  FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'auditoria es de solo insercion';
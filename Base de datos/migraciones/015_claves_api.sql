@@ -0,0 +1,24 @@
+--
+-- Claves de API para trabajos automáticos (ingesta, reportes). Solo se guarda
+-- el SHA-256 de la clave; el valor en claro se muestra una vez al crearla.
+--
+
+CREATE TABLE IF NOT EXISTS `claves_api` (
+  `CLAVE_ID` bigint NOT NULL AUTO_INCREMENT COMMENT 'Identificador de la clave',
+  `NOMBRE` varchar(100) NOT NULL COMMENT 'Descripcion (p. ej. ingesta nocturna)',
+  `PREFIJO` varchar(16) NOT NULL COMMENT 'Primeros caracteres de la clave, para reconocerla',
+  `CLAVE_HASH` char(64) NOT NULL COMMENT 'SHA-256 de la clave completa',
+  `USUARIO_ID` bigint DEFAULT NULL COMMENT 'Usuario propietario; NULL si es de una cuenta de servicio',
+  `CUENTA_SERVICIO` varchar(100) DEFAULT NULL COMMENT 'Cuenta de servicio propietaria',
+  `ALCANCES` varchar(255) NOT NULL COMMENT 'Alcances separados por coma (catalog:write, billing:read, reporting)',
+  `CREADA_POR` varchar(254) NOT NULL COMMENT 'Correo del administrador que la creo',
+  `FECHA_CREACION` datetime NOT NULL COMMENT 'Creacion (UTC)',
+  `FECHA_EXPIRACION` datetime DEFAULT NULL COMMENT 'Expiracion (UTC); NULL si no expira',
+  `ULTIMO_USO` datetime DEFAULT NULL COMMENT 'Ultima peticion autenticada con la clave',
+  `ULTIMA_IP` varchar(45) DEFAULT NULL COMMENT 'IP de la ultima peticion',
+  `FECHA_REVOCACION` datetime DEFAULT NULL COMMENT 'Revocacion; NULL si sigue activa',
+  PRIMARY KEY (`CLAVE_ID`),
+  UNIQUE KEY `CLAVES_API_HASH_UK` (`CLAVE_HASH`),
+  KEY `CLAVES_API_USUARIOS_FK` (`USUARIO_ID`),
+  CONSTRAINT `CLAVES_API_USUARIOS_FK` FOREIGN KEY (`USUARIO_ID`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Tabla de claves de API con alcances';
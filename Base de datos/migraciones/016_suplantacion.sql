@@ -0,0 +1,11 @@
+--
+-- Suplantación por el equipo de soporte: la sesión del agente guarda a qué
+-- suscriptor está suplantando. Añade además el rol support a la lista.
+--
+
+ALTER TABLE `sesiones`
+  ADD COLUMN `SUPLANTANDO_A` bigint DEFAULT NULL COMMENT 'Usuario suplantado por el titular de la sesion (soporte)',
+  ADD CONSTRAINT `SESIONES_SUPLANTADO_FK` FOREIGN KEY (`SUPLANTANDO_A`) REFERENCES `usuarios` (`USUARIO_ID`) ON DELETE SET NULL;
+
+ALTER TABLE `usuario_roles`
+  MODIFY `ROL` varchar(20) NOT NULL COMMENT 'Nombre del rol (viewer, support, content-editor, billing-admin, super-admin)';
@@ -0,0 +1,35 @@
+--
+-- Tipo de cada contenido y datos propios de los documentales: tema, narrador
+-- y, si está dividido en episodios, la lista de episodios.
+--
+
+ALTER TABLE `contenidos`
+  ADD COLUMN `TIPO` varchar(20) NOT NULL DEFAULT 'pelicula' COMMENT 'Tipo de contenido (pelicula, serie, documental)';
+
+CREATE TABLE IF NOT EXISTS `documentales` (
+  `CONTENIDO_ID` bigint NOT NULL COMMENT 'Contenido al que pertenecen los datos',
+  `GENERO` varchar(50) DEFAULT NULL COMMENT 'Genero (naturaleza, historia, ciencia...)',
+  `TEMA` varchar(100) DEFAULT NULL COMMENT 'Tema que trata el documental',
+  `NARRADOR` varchar(100) DEFAULT NULL COMMENT 'Voz narradora',
+  `DURACION_MINUTOS` float DEFAULT NULL COMMENT 'Duracion si no esta dividido en episodios',
+  PRIMARY KEY (`CONTENIDO_ID`),
+  CONSTRAINT `DOCUMENTALES_CONTENIDOS_FK` FOREIGN KEY (`CONTENIDO_ID`) REFERENCES `contenidos` (`CONTENIDO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Datos propios de los documentales';
+
+CREATE TABLE IF NOT EXISTS `documental_episodios` (
+  `CONTENIDO_ID` bigint NOT NULL COMMENT 'Documental al que pertenece el episodio',
+  `NUMERO` int NOT NULL COMMENT 'Orden del episodio, desde 1',
+  `TITULO` varchar(100) NOT NULL COMMENT 'Titulo del episodio',
+  `DURACION_MINUTOS` float NOT NULL COMMENT 'Duracion del episodio',
+  PRIMARY KEY (`CONTENIDO_ID`, `NUMERO`),
+  CONSTRAINT `DOCUMENTAL_EPISODIOS_FK` FOREIGN KEY (`CONTENIDO_ID`) REFERENCES `documentales` (`CONTENIDO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Episodios de los documentales episodicos';
+
+-- Los contenidos de ejemplo de BDD_Streaming.sql ya existían: sin esto
+-- "Planeta Tierra II" se cargaría como película y la importación no podría
+-- corregirlo, porque no permite cambiar el tipo de un contenido.
+UPDATE `contenidos` SET `TIPO` = 'documental' WHERE `CONTENIDO_ID` = 203 AND `TITULO` = 'Planeta Tierra II';
+
+INSERT IGNORE INTO `documentales` (`CONTENIDO_ID`, `GENERO`, `TEMA`, `NARRADOR`)
+SELECT `CONTENIDO_ID`, 'Naturaleza', 'Vida salvaje', 'David Attenborough' FROM `contenidos`
+WHERE `CONTENIDO_ID` = 203 AND `TIPO` = 'documental';
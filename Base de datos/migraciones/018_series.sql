@@ -0,0 +1,38 @@
+--
+-- Series con temporadas y episodios numerados. Cada episodio tiene su propia
+-- descripción, duración y fecha de emisión.
+--
+
+CREATE TABLE IF NOT EXISTS `series` (
+  `CONTENIDO_ID` bigint NOT NULL COMMENT 'Contenido al que pertenecen los datos',
+  `GENERO` varchar(50) DEFAULT NULL COMMENT 'Genero de la serie',
+  PRIMARY KEY (`CONTENIDO_ID`),
+  CONSTRAINT `SERIES_CONTENIDOS_FK` FOREIGN KEY (`CONTENIDO_ID`) REFERENCES `contenidos` (`CONTENIDO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Datos propios de las series';
+
+CREATE TABLE IF NOT EXISTS `temporadas` (
+  `CONTENIDO_ID` bigint NOT NULL COMMENT 'Serie a la que pertenece la temporada',
+  `NUMERO` int NOT NULL COMMENT 'Numero de temporada, desde 1',
+  `TITULO` varchar(100) DEFAULT NULL COMMENT 'Titulo opcional de la temporada',
+  PRIMARY KEY (`CONTENIDO_ID`, `NUMERO`),
+  CONSTRAINT `TEMPORADAS_SERIES_FK` FOREIGN KEY (`CONTENIDO_ID`) REFERENCES `series` (`CONTENIDO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Temporadas de las series';
+
+CREATE TABLE IF NOT EXISTS `episodios` (
+  `CONTENIDO_ID` bigint NOT NULL COMMENT 'Serie a la que pertenece el episodio',
+  `TEMPORADA` int NOT NULL COMMENT 'Numero de temporada',
+  `NUMERO` int NOT NULL COMMENT 'Numero del episodio dentro de la temporada, desde 1',
+  `TITULO` varchar(100) NOT NULL COMMENT 'Titulo del episodio',
+  `DESCRIPCION` varchar(500) DEFAULT NULL COMMENT 'Sinopsis del episodio',
+  `DURACION_MINUTOS` float NOT NULL COMMENT 'Duracion del episodio',
+  `FECHA_EMISION` date DEFAULT NULL COMMENT 'Fecha de emision original',
+  PRIMARY KEY (`CONTENIDO_ID`, `TEMPORADA`, `NUMERO`),
+  CONSTRAINT `EPISODIOS_TEMPORADAS_FK` FOREIGN KEY (`CONTENIDO_ID`, `TEMPORADA`) REFERENCES `temporadas` (`CONTENIDO_ID`, `NUMERO`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Episodios de las series';
+
+-- "Stranger Things", de los datos de ejemplo, es una serie (ver 017).
+UPDATE `contenidos` SET `TIPO` = 'serie' WHERE `CONTENIDO_ID` = 202 AND `TITULO` = 'Stranger Things';
+
+INSERT IGNORE INTO `series` (`CONTENIDO_ID`, `GENERO`)
+SELECT `CONTENIDO_ID`, 'Ciencia ficción' FROM `contenidos`
+WHERE `CONTENIDO_ID` = 202 AND `TIPO` = 'serie';
@@ -0,0 +1,16 @@
+--
+-- Metadatos propios de las películas, que hasta ahora solo existían en
+-- memoria con valores "N/A".
+--
+
+CREATE TABLE IF NOT EXISTS `peliculas` (
+  `CONTENIDO_ID` bigint NOT NULL COMMENT 'Contenido al que pertenecen los datos',
+  `GENEROS` varchar(255) DEFAULT NULL COMMENT 'Generos separados por coma (Drama, Comedia...)',
+  `DIRECTOR` varchar(100) DEFAULT NULL COMMENT 'Director o directora',
+  `TRAILER_URL` varchar(255) DEFAULT NULL COMMENT 'Enlace http(s) al trailer',
+  `DURACION_MINUTOS` float DEFAULT NULL COMMENT 'Duracion; NULL si no se conoce',
+  `ANIO_ESTRENO` smallint DEFAULT NULL COMMENT 'Anio de estreno; NULL si no se conoce',
+  `IDIOMA_ORIGINAL` char(2) DEFAULT NULL COMMENT 'Codigo ISO 639-1 del idioma original',
+  PRIMARY KEY (`CONTENIDO_ID`),
+  CONSTRAINT `PELICULAS_CONTENIDOS_FK` FOREIGN KEY (`CONTENIDO_ID`) REFERENCES `contenidos` (`CONTENIDO_ID`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='Datos propios de las peliculas';
@@ -0,0 +1,9 @@
+--
+-- El catálogo no pertenece a ningún perfil: el panel admin y la importación
+-- crean contenidos sin PERFILES_PERFIL_ID, y con la columna NOT NULL MySQL en
+-- modo estricto rechazaba el INSERT. La clave foránea se conserva para los
+-- contenidos antiguos que sí lo tienen.
+--
+
+ALTER TABLE `contenidos`
+  MODIFY COLUMN `PERFILES_PERFIL_ID` bigint DEFAULT NULL COMMENT 'Perfil que registró el contenido; NULL para el catálogo gestionado desde el panel';
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"streaming-system/pkg/auth"
+)
+
+func handleAdminBilling(w http.ResponseWriter, r *http.Request) {
+	resumen, err := dbStore.LoadSubscriptionSummaries()
+	if err != nil {
+		fmt.Printf("❌ Error al cargar suscripciones: %v\n", err)
+	}
+	renderizar(w, r, "admin_billing.html", resumen)
+}
+
+// handleAdminRoles reemplaza los roles del usuario indicado por email.
+func handleAdminRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/admin", http.StatusSeeOther)
+		return
+	}
+	u, err := dbStore.LoadUserByEmail(r.FormValue("email"))
+	if err != nil {
+		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+		return
+	}
+	var roles []auth.Rol
+	for _, v := range r.Form["roles"] {
+		if rol := auth.Rol(v); auth.EsRolValido(rol) {
+			roles = append(roles, rol)
+		}
+	}
+	if err := dbStore.SaveUserRoles(u.GetID(), roles); err != nil {
+		fmt.Printf("❌ Error al guardar roles: %v\n", err)
+	} else {
+		auditarPeticion(r, auth.AccionRolesCambiados, u.GetCorreo(), fmt.Sprint(roles))
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"streaming-system/pkg/auth"
+	"strings"
+)
+
+// nuevoEmisorTokens lee las claves JWT de STREAMGO_JWT_CLAVES con el formato
+// "kid:alg:base64[,kid:alg:base64...]", donde alg es hs256 (secreto) o
+// ed25519 (semilla de 32 bytes). La primera clave firma; el resto solo verifica.
+func nuevoEmisorTokens() *auth.EmisorTokens {
+	var almacen auth.AlmacenRefresco = dbStore
+	if os.Getenv("STREAMGO_ALMACEN_SESIONES") == "memoria" {
+		almacen = auth.NuevoAlmacenRefrescoMemoria()
+	}
+
+	var claves []*auth.ClaveFirma
+	for _, def := range strings.Split(os.Getenv("STREAMGO_JWT_CLAVES"), ",") {
+		if def = strings.TrimSpace(def); def == "" {
+			continue
+		}
+		c, err := parsearClaveFirma(def)
+		if err != nil {
+			fmt.Printf("❌ FATAL: clave JWT inválida: %v\n", err)
+			os.Exit(1)
+		}
+		claves = append(claves, c)
+	}
+	if len(claves) == 0 {
+		fmt.Println("⚠️ STREAMGO_JWT_CLAVES no definida: se usa una clave HS256 aleatoria")
+		secreto := make([]byte, 32)
+		rand.Read(secreto)
+		claves = append(claves, auth.NuevaClaveHS256("local", secreto))
+	}
+	return auth.NuevoEmisorTokens("streamgo", almacen, claves[0], claves[1:]...)
+}
+
+func parsearClaveFirma(def string) (*auth.ClaveFirma, error) {
+	partes := strings.SplitN(def, ":", 3)
+	if len(partes) != 3 {
+		return nil, fmt.Errorf("%q no tiene el formato kid:alg:base64", def)
+	}
+	material, err := base64.StdEncoding.DecodeString(partes[2])
+	if err != nil {
+		return nil, fmt.Errorf("kid %q: %v", partes[0], err)
+	}
+	switch strings.ToLower(partes[1]) {
+	case "hs256":
+		if len(material) < 32 {
+			return nil, fmt.Errorf("kid %q: el secreto HS256 debe tener al menos 32 bytes", partes[0])
+		}
+		return auth.NuevaClaveHS256(partes[0], material), nil
+	case "ed25519":
+		if len(material) != ed25519.SeedSize {
+			return nil, fmt.Errorf("kid %q: la semilla Ed25519 debe tener %d bytes", partes[0], ed25519.SeedSize)
+		}
+		return auth.NuevaClaveEd25519(partes[0], ed25519.NewKeyFromSeed(material)), nil
+	}
+	return nil, fmt.Errorf("kid %q: algoritmo %q no soportado", partes[0], partes[1])
+}
+
+// conBearer exige un access token válido en la cabecera Authorization.
+func conBearer(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			errorJSON(w, http.StatusUnauthorized, "falta el token de acceso")
+			return
+		}
+		claims, err := tokens.VerificarAcceso(token)
+		if err != nil {
+			errorJSON(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		u, err := dbStore.LoadUserByID(claims.Subject)
+		if err != nil {
+			errorJSON(w, http.StatusUnauthorized, auth.ErrTokenInvalido.Error())
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), claveUsuario, u)))
+	}
+}
+
+func handleAPIToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		OTP      string `json:"otp"`
+	}
+	if !leerJSON(w, r, &req) {
+		return
+	}
+	u, err := autenticar(req.Email, req.Password, ipCliente(r))
+	if errors.Is(err, auth.ErrDemasiadosIntentos) {
+		errorJSON(w, http.StatusTooManyRequests, mensajeLogin(err))
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusUnauthorized, mensajeLogin(err))
+		return
+	}
+	cfg, err := dbStore.LoadMFA(u.GetID())
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, mensajeMFA(err))
+		return
+	}
+	if cfg.EstaActivo() {
+		if req.OTP == "" {
+			errorJSON(w, http.StatusUnauthorized, "se requiere el código de verificación (otp)")
+			return
+		}
+		if err := verificarSegundoFactor(u, req.OTP); err != nil {
+			if errors.Is(err, auth.ErrCodigoMFAInvalido) {
+				auditar(u.GetCorreo(), auth.AccionLoginFallido, u.GetID(), ipCliente(r), "api: código de verificación incorrecto")
+			}
+			status := http.StatusUnauthorized
+			if errors.Is(err, auth.ErrDemasiadosIntentos) {
+				status = http.StatusTooManyRequests
+			}
+			errorJSON(w, status, mensajeMFA(err))
+			return
+		}
+	} else if obligatorio, _ := mfaObligatorioPara(u); obligatorio {
+		errorJSON(w, http.StatusForbidden, "tu rol exige la verificación en dos pasos: actívala desde la web")
+		return
+	}
+	par, err := tokens.EmitirPar(u)
+	if err != nil {
+		fmt.Printf("❌ Error al emitir tokens: %v\n", err)
+		errorJSON(w, http.StatusInternalServerError, "no se pudo emitir el token")
+		return
+	}
+	auditar(u.GetCorreo(), auth.AccionLogin, u.GetID(), ipCliente(r), "api")
+	responderJSON(w, http.StatusOK, par)
+}
+
+func handleAPIRefresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if !leerJSON(w, r, &req) {
+		return
+	}
+	par, err := tokens.Refrescar(req.RefreshToken, dbStore.LoadUserRoles)
+	switch {
+	case errors.Is(err, auth.ErrReutilizacionRefresco), errors.Is(err, auth.ErrTokenInvalido):
+		errorJSON(w, http.StatusUnauthorized, err.Error())
+	case err != nil:
+		fmt.Printf("❌ Error al rotar refresh token: %v\n", err)
+		errorJSON(w, http.StatusInternalServerError, "no se pudo renovar el token")
+	default:
+		responderJSON(w, http.StatusOK, par)
+	}
+}
+
+func handleAPIRevoke(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if !leerJSON(w, r, &req) {
+		return
+	}
+	// RFC 7009: un token desconocido no es un error para el cliente.
+	if err := tokens.RevocarRefresco(req.RefreshToken); err != nil && !errors.Is(err, auth.ErrTokenInvalido) {
+		errorJSON(w, http.StatusInternalServerError, "no se pudo revocar el token")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleAPIMe(w http.ResponseWriter, r *http.Request) {
+	u := usuarioDe(r)
+	responderJSON(w, http.StatusOK, map[string]any{
+		"id":             u.GetID(),
+		"nombre":         u.GetNombre(),
+		"email":          u.GetCorreo(),
+		"email_verified": u.EmailVerificado(),
+		"roles":          u.GetRoles(),
+	})
+}
+
+// --- utilidades JSON ---
+
+func leerJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.Method != http.MethodPost {
+		errorJSON(w, http.StatusMethodNotAllowed, "método no permitido")
+		return false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v); err != nil {
+		errorJSON(w, http.StatusBadRequest, "JSON inválido")
+		return false
+	}
+	return true
+}
+
+func responderJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func errorJSON(w http.ResponseWriter, status int, msg string) {
+	responderJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"streaming-system/pkg/auth"
+	"streaming-system/pkg/content"
+	"time"
+)
+
+// Rutas para trabajos automáticos autenticados con clave de API (ver conClaveAPI).
+
+// contenidoAPI es la representación JSON de un contenido del catálogo.
+type contenidoAPI struct {
+	ID          string         `json:"id"`
+	Tipo        string         `json:"type,omitempty"`
+	Titulo      string         `json:"title"`
+	Descripcion string         `json:"description"`
+	Genero      string         `json:"genre,omitempty"`
+	Generos     []string       `json:"genres,omitempty"`
+	Edad        int            `json:"age_rating"`
+	Estreno     bool           `json:"new_release"`
+	Director    string         `json:"director,omitempty"`
+	Trailer     string         `json:"trailer_url,omitempty"`
+	Anio        int            `json:"release_year,omitempty"`
+	Idioma      string         `json:"original_language,omitempty"`
+	Tema        string         `json:"topic,omitempty"`
+	Narrador    string         `json:"narrator,omitempty"`
+	Duracion    float32        `json:"duration_minutes,omitempty"`
+	Episodios   []episodioAPI  `json:"episodes,omitempty"`
+	Temporadas  []temporadaAPI `json:"seasons,omitempty"`
+}
+
+type episodioAPI struct {
+	Numero      int     `json:"number,omitempty"`
+	Titulo      string  `json:"title"`
+	Descripcion string  `json:"description,omitempty"`
+	Duracion    float32 `json:"duration_minutes"`
+	Emision     string  `json:"air_date,omitempty"` // AAAA-MM-DD
+}
+
+type temporadaAPI struct {
+	Numero    int           `json:"number"`
+	Titulo    string        `json:"title,omitempty"`
+	Episodios []episodioAPI `json:"episodes"`
+}
+
+func nuevoEpisodioAPI(ep *content.Episodio) episodioAPI {
+	res := episodioAPI{Numero: ep.GetNumero(), Titulo: ep.GetTitulo(), Descripcion: ep.GetDescripcion(), Duracion: ep.GetDuracion()}
+	if !ep.GetEmision().IsZero() {
+		res.Emision = ep.GetEmision().Format(time.DateOnly)
+	}
+	return res
+}
+
+// tiposAPI traduce los tipos del catálogo a los nombres que expone la API.
+var tiposAPI = map[string]string{
+	content.TipoPelicula:   "movie",
+	content.TipoSerie:      "series",
+	content.TipoDocumental: "documentary",
+}
+
+func nuevoContenidoAPI(c content.Contenible) contenidoAPI {
+	res := contenidoAPI{ID: c.GetID(), Tipo: tiposAPI[content.TipoDe(c)], Titulo: c.GetTitulo(), Descripcion: c.GetDescripcion(),
+		Genero: c.GetGenero(), Edad: c.GetClasificacionEdad(), Estreno: c.EsEstreno()}
+	switch v := c.(type) {
+	case *content.Pelicula:
+		res.Generos, res.Director, res.Trailer = v.GetGeneros(), v.GetDirector(), v.GetTrailerLink()
+		res.Duracion, res.Anio, res.Idioma = v.GetDuracionTotal(), v.GetAnio(), v.GetIdioma()
+	case *content.Documental:
+		res.Tema, res.Narrador, res.Duracion = v.GetTema(), v.GetNarrador(), v.GetDuracionTotal()
+		for _, ep := range v.ObtenerEpisodios() {
+			res.Episodios = append(res.Episodios, nuevoEpisodioAPI(ep))
+		}
+	case *content.Serie:
+		for _, t := range v.ObtenerTemporadas() {
+			ta := temporadaAPI{Numero: t.GetNumero(), Titulo: t.GetTitulo(), Episodios: make([]episodioAPI, 0)}
+			for _, ep := range t.ObtenerEpisodios() {
+				ta.Episodios = append(ta.Episodios, nuevoEpisodioAPI(ep))
+			}
+			res.Temporadas = append(res.Temporadas, ta)
+		}
+	}
+	return res
+}
+
+// contenidoDeAPI construye la película o el documental que describe la petición.
+func contenidoDeAPI(req contenidoAPI) content.Contenible {
+	if req.Tipo != tiposAPI[content.TipoDocumental] {
+		generos := req.Generos
+		if len(generos) == 0 {
+			generos = content.SepararGeneros(req.Genero)
+		}
+		p := content.NuevaPelicula(req.ID, req.Titulo, req.Descripcion, generos, req.Director, req.Trailer, req.Duracion, req.Anio, req.Idioma, req.Edad)
+		content.MarcarEstreno(p, req.Estreno)
+		return p
+	}
+	d := content.NuevoDocumental(req.ID, req.Titulo, req.Descripcion, req.Genero, req.Tema, req.Narrador, req.Duracion, req.Edad)
+	for _, ep := range req.Episodios {
+		d.AgregarEpisodio(ep.Titulo, ep.Duracion)
+	}
+	content.MarcarEstreno(d, req.Estreno)
+	return d
+}
+
+// handleAPICatalogo lista (GET), crea o actualiza películas y documentales (POST) y borra
+// contenidos (DELETE ?id=) con el alcance catalog:write.
+func handleAPICatalogo(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		lista := make([]contenidoAPI, 0)
+		for _, c := range gestor.ObtenerTodo() {
+			lista = append(lista, nuevoContenidoAPI(c))
+		}
+		sort.Slice(lista, func(i, j int) bool { return lista[i].ID < lista[j].ID })
+		responderJSON(w, http.StatusOK, map[string]any{"contents": lista})
+	case http.MethodPost:
+		guardarContenidoAPI(w, r)
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if _, err := gestor.ObtenerPorID(id); err != nil {
+			errorJSON(w, http.StatusNotFound, content.ErrContenidoNoEncontrado.Error())
+			return
+		}
+		if err := dbStore.DeleteContent(id); err != nil {
+			fmt.Printf("❌ Error al borrar el contenido %s: %v\n", id, err)
+			errorJSON(w, http.StatusInternalServerError, "no se pudo borrar el contenido")
+			return
+		}
+		gestor.BorrarContenido(id)
+		auditarClaveAPI(r, auth.AccionContenidoEliminado, id, "api")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		errorJSON(w, http.StatusMethodNotAllowed, "método no permitido")
+	}
+}
+
+// guardarContenidoAPI crea el contenido si el id no existe y si no lo actualiza.
+// El tipo de un contenido existente no puede cambiar.
+func guardarContenidoAPI(w http.ResponseWriter, r *http.Request) {
+	var req contenidoAPI
+	if !leerJSON(w, r, &req) {
+		return
+	}
+	if req.Tipo == "" {
+		req.Tipo = tiposAPI[content.TipoPelicula]
+	}
+	if req.Tipo != tiposAPI[content.TipoPelicula] && req.Tipo != tiposAPI[content.TipoDocumental] {
+		errorJSON(w, http.StatusBadRequest, "type debe ser movie o documentary")
+		return
+	}
+	for _, ep := range req.Episodios {
+		if ep.Titulo == "" || ep.Duracion <= 0 {
+			errorJSON(w, http.StatusBadRequest, "cada episodio necesita title y duration_minutes positivo")
+			return
+		}
+	}
+
+	nueva := contenidoDeAPI(req)
+	if err := content.Validar(nueva); err != nil {
+		responderJSON(w, http.StatusBadRequest, map[string]any{"error": "datos no válidos", "errors": mensajesError(err)})
+		return
+	}
+	// Si existe se reemplaza sin soltar el gestor entre la comprobación y la
+	// escritura; si no, se crea.
+	status, accion := http.StatusOK, auth.AccionContenidoActualizado
+	err := gestor.Actualizar(req.ID, func(c content.Contenible) error {
+		if err := content.ReemplazarDatos(c, nueva); err != nil {
+			return err
+		}
+		return dbStore.UpdateContentFull(c)
+	})
+	if errors.Is(err, content.ErrContenidoNoEncontrado) {
+		status, accion = http.StatusCreated, auth.AccionContenidoCreado
+		if err = dbStore.SaveContent(nueva); err == nil {
+			gestor.InsertarContenido(nueva)
+		}
+	}
+	if errors.Is(err, content.ErrCambioDeTipo) {
+		errorJSON(w, http.StatusConflict, "el contenido ya existe con otro tipo")
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error al guardar el contenido %s: %v\n", req.ID, err)
+		errorJSON(w, http.StatusInternalServerError, "no se pudo guardar el contenido")
+		return
+	}
+	auditarClaveAPI(r, accion, req.ID, fmt.Sprintf("api: título %q, clasificación %d", nueva.GetTitulo(), nueva.GetClasificacionEdad()))
+	responderJSON(w, status, nuevoContenidoAPI(nueva))
+}
+
+// handleAPIFacturacion devuelve las suscripciones (alcance billing:read).
+func handleAPIFacturacion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorJSON(w, http.StatusMethodNotAllowed, "método no permitido")
+		return
+	}
+	resumen, err := dbStore.LoadSubscriptionSummaries()
+	if err != nil {
+		fmt.Printf("❌ Error al cargar suscripciones: %v\n", err)
+		errorJSON(w, http.StatusInternalServerError, "no se pudieron cargar las suscripciones")
+		return
+	}
+	type suscripcionAPI struct {
+		UsuarioID string    `json:"user_id"`
+		Email     string    `json:"email"`
+		Plan      string    `json:"plan"`
+		Estado    string    `json:"status"`
+		FechaFin  time.Time `json:"ends_at"`
+		Precio    float64   `json:"price"`
+	}
+	lista := make([]suscripcionAPI, 0, len(resumen))
+	for _, s := range resumen {
+		lista = append(lista, suscripcionAPI{s.UsuarioID, s.Email, s.Plan, s.Estado, s.FechaFin, s.Precio})
+	}
+	responderJSON(w, http.StatusOK, map[string]any{"subscriptions": lista})
+}
+
+// handleAPIReportes devuelve totales de usuarios, planes y visualizaciones
+// (alcance reporting). ?top=N limita los contenidos más vistos (1-100, 10 por defecto).
+func handleAPIReportes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorJSON(w, http.StatusMethodNotAllowed, "método no permitido")
+		return
+	}
+	top := 10
+	if v := r.URL.Query().Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			errorJSON(w, http.StatusBadRequest, "top debe estar entre 1 y 100")
+			return
+		}
+		top = n
+	}
+	rep, err := dbStore.LoadReport(top)
+	if err != nil {
+		fmt.Printf("❌ Error al generar el reporte: %v\n", err)
+		errorJSON(w, http.StatusInternalServerError, "no se pudo generar el reporte")
+		return
+	}
+	responderJSON(w, http.StatusOK, rep)
+}
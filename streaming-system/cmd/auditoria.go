@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"streaming-system/pkg/auth"
+	"strings"
+	"time"
+)
+
+// actorSistema identifica los eventos que no inicia ningún usuario
+// (bloqueos automáticos, bajas programadas...).
+const actorSistema = "sistema"
+
+// limiteAuditoriaPanel acota las filas que muestra /admin/audit; el CSV no tiene límite.
+const limiteAuditoriaPanel = 200
+
+// nuevoRegistroAuditoria usa MySQL por defecto; STREAMGO_ALMACEN_AUDITORIA=memoria
+// guarda los eventos solo mientras dure el proceso.
+func nuevoRegistroAuditoria() *auth.RegistroAuditoria {
+	var almacen auth.AlmacenAuditoria = dbStore
+	if os.Getenv("STREAMGO_ALMACEN_AUDITORIA") == "memoria" {
+		almacen = auth.NuevoAlmacenAuditoriaMemoria()
+	}
+	return auth.NuevoRegistroAuditoria(almacen)
+}
+
+// auditar añade un evento al registro. Un fallo al auditar no interrumpe la
+// acción del usuario, pero queda en el log del servidor.
+func auditar(actor string, accion auth.AccionAuditoria, objetivo, ip, detalle string) {
+	if err := auditoria.Registrar(actor, accion, objetivo, ip, detalle); err != nil {
+		fmt.Printf("❌ Error al registrar auditoría (%s %s): %v\n", accion, objetivo, err)
+	}
+}
+
+// auditarPeticion registra un evento iniciado por el usuario de la sesión.
+func auditarPeticion(r *http.Request, accion auth.AccionAuditoria, objetivo, detalle string) {
+	auditar(actorDe(r), accion, objetivo, ipCliente(r), detalle)
+}
+
+// filtroAuditoriaDe lee los filtros del panel; las fechas son días completos en UTC.
+func filtroAuditoriaDe(q url.Values) auth.FiltroAuditoria {
+	f := auth.FiltroAuditoria{
+		Actor:    q.Get("actor"),
+		Accion:   auth.AccionAuditoria(q.Get("accion")),
+		Objetivo: q.Get("objetivo"),
+		IP:       q.Get("ip"),
+	}
+	if d, err := time.Parse(time.DateOnly, q.Get("desde")); err == nil {
+		f.Desde = d
+	}
+	if h, err := time.Parse(time.DateOnly, q.Get("hasta")); err == nil {
+		f.Hasta = h.AddDate(0, 0, 1)
+	}
+	return f
+}
+
+// handleAdminAudit muestra el registro de auditoría filtrado y el estado de la cadena.
+func handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := filtroAuditoriaDe(q)
+	f.Limite = limiteAuditoriaPanel
+	eventos, err := auditoria.Buscar(f)
+	if err != nil {
+		fmt.Printf("❌ Error al cargar la auditoría: %v\n", err)
+	}
+	alterado, err := auditoria.Verificar()
+	if err != nil {
+		fmt.Printf("❌ Error al verificar la cadena de auditoría: %v\n", err)
+	}
+	renderizar(w, r, "admin_audit.html", struct {
+		Eventos        []*auth.EventoAuditoria
+		Acciones       []auth.AccionAuditoria
+		Filtro         url.Values
+		Limite         int
+		Verificada     bool
+		PrimerAlterado int64
+		URLCSV         string
+	}{eventos, auth.AccionesAuditoria(), q, limiteAuditoriaPanel, err == nil, alterado, "/admin/audit.csv?" + q.Encode()})
+}
+
+// handleAdminAuditCSV exporta como CSV los eventos que cumplen los mismos filtros.
+func handleAdminAuditCSV(w http.ResponseWriter, r *http.Request) {
+	eventos, err := auditoria.Buscar(filtroAuditoriaDe(r.URL.Query()))
+	if err != nil {
+		fmt.Printf("❌ Error al exportar la auditoría: %v\n", err)
+		http.Error(w, "No se pudo exportar la auditoría", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="auditoria-%s.csv"`, time.Now().Format("20060102-150405")))
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "fecha", "actor", "accion", "objetivo", "ip", "detalle", "hash_anterior", "hash"})
+	for _, e := range eventos {
+		cw.Write([]string{
+			fmt.Sprint(e.GetID()), e.GetFecha().Format(time.RFC3339Nano), celdaCSV(e.GetActor()), string(e.GetAccion()),
+			celdaCSV(e.GetObjetivo()), e.GetIP(), celdaCSV(e.GetDetalle()), e.GetHashAnterior(), e.GetHash(),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		fmt.Printf("❌ Error al escribir el CSV de auditoría: %v\n", err)
+	}
+}
+
+// celdaCSV evita que una hoja de cálculo interprete como fórmula un valor
+// controlado por el usuario (por ejemplo el correo de un login fallido).
+func celdaCSV(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
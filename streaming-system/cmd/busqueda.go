@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"streaming-system/pkg/auth"
+	"streaming-system/pkg/busqueda"
+	"streaming-system/pkg/content"
+	"strings"
+)
+
+// buscador indexa el catálogo de gestor; se sincroniza solo antes de cada consulta.
+var buscador *busqueda.Buscador
+
+// Límites de resultados de /search.json y /search/suggest.
+const (
+	limiteBusqueda      = 20
+	limiteBusquedaMax   = 100
+	limiteSugerencias   = 8
+	longitudConsultaMax = 200
+)
+
+// resultadoBusquedaAPI es un contenido encontrado con su puntuación.
+type resultadoBusquedaAPI struct {
+	contenidoAPI
+	Puntuacion float64 `json:"score"`
+}
+
+// sugerenciaAPI es un título propuesto por el autocompletado.
+type sugerenciaAPI struct {
+	ID     string `json:"id"`
+	Titulo string `json:"title"`
+	Tipo   string `json:"type"`
+}
+
+// consultaDe devuelve el parámetro q recortado a longitudConsultaMax caracteres.
+func consultaDe(r *http.Request) string {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if runas := []rune(q); len(runas) > longitudConsultaMax {
+		q = string(runas[:longitudConsultaMax])
+	}
+	return q
+}
+
+// filtroParental descarta lo que el perfil no puede ver, como el dashboard.
+func filtroParental(perfil *auth.Perfil) func(content.Contenible) bool {
+	return func(c content.Contenible) bool {
+		return content.ApropiadoPara(c, perfil.GetClasificacionMaxima())
+	}
+}
+
+// handleSearch muestra la página de resultados de búsqueda del catálogo.
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	usuario, perfil := usuarioDe(r), perfilDe(r)
+	if usuario.GetSuscripcion() == nil {
+		http.Redirect(w, r, "/checkout", http.StatusSeeOther)
+		return
+	}
+	if perfil == nil {
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+	q := consultaDe(r)
+	var resultados []content.Contenible
+	if q != "" {
+		for _, res := range buscador.Buscar(q, filtroParental(perfil)) {
+			resultados = append(resultados, res.Contenido)
+		}
+	}
+	renderizar(w, r, "search.html", struct {
+		Usuario    *auth.Usuario
+		Perfil     *auth.Perfil
+		Consulta   string
+		Resultados []content.Contenible
+	}{usuario, perfil, q, resultados})
+}
+
+// perfilParaBusquedaJSON devuelve el perfil activo o responde con un error
+// JSON si el usuario no puede ver el catálogo.
+func perfilParaBusquedaJSON(w http.ResponseWriter, r *http.Request) *auth.Perfil {
+	if usuarioDe(r).GetSuscripcion() == nil {
+		errorJSON(w, http.StatusForbidden, "se necesita una suscripción activa")
+		return nil
+	}
+	perfil := perfilDe(r)
+	if perfil == nil {
+		errorJSON(w, http.StatusForbidden, "selecciona un perfil")
+	}
+	return perfil
+}
+
+// handleSearchJSON devuelve los resultados de ?q= ordenados por relevancia.
+// ?limit= admite hasta limiteBusquedaMax.
+func handleSearchJSON(w http.ResponseWriter, r *http.Request) {
+	perfil := perfilParaBusquedaJSON(w, r)
+	if perfil == nil {
+		return
+	}
+	limite := limiteBusqueda
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			errorJSON(w, http.StatusBadRequest, "limit no válido")
+			return
+		}
+		limite = min(n, limiteBusquedaMax)
+	}
+	q := consultaDe(r)
+	resultados := make([]resultadoBusquedaAPI, 0)
+	if q != "" {
+		encontrados := buscador.Buscar(q, filtroParental(perfil))
+		for _, res := range encontrados[:min(limite, len(encontrados))] {
+			resultados = append(resultados, resultadoBusquedaAPI{nuevoContenidoAPI(res.Contenido), res.Puntuacion})
+		}
+	}
+	responderJSON(w, http.StatusOK, map[string]any{"query": q, "results": resultados})
+}
+
+// handleSearchSuggest autocompleta títulos para la caja de búsqueda.
+func handleSearchSuggest(w http.ResponseWriter, r *http.Request) {
+	perfil := perfilParaBusquedaJSON(w, r)
+	if perfil == nil {
+		return
+	}
+	sugerencias := make([]sugerenciaAPI, 0)
+	for _, c := range buscador.Sugerir(consultaDe(r), limiteSugerencias, filtroParental(perfil)) {
+		sugerencias = append(sugerencias, sugerenciaAPI{c.GetID(), c.GetTitulo(), tiposAPI[content.TipoDe(c)]})
+	}
+	responderJSON(w, http.StatusOK, map[string]any{"suggestions": sugerencias})
+}
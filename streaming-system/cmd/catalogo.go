@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"streaming-system/pkg/content"
+)
+
+// Parámetros de URL de la navegación del catálogo (dashboard y panel admin):
+// tipo, genero y clasificacion se pueden repetir; desde y hasta son años;
+// estreno=1 deja solo los estrenos; orden y cursor paginan.
+
+// etiquetasTipo nombra los tipos de contenido en los filtros.
+var etiquetasTipo = map[string]string{
+	content.TipoPelicula:   "Películas",
+	content.TipoSerie:      "Series",
+	content.TipoDocumental: "Documentales",
+}
+
+// consultaCatalogoDe lee los filtros de la URL. Los valores que no son
+// números se ignoran; el orden y el cursor los valida content.
+func consultaCatalogoDe(v url.Values) content.ConsultaCatalogo {
+	q := content.ConsultaCatalogo{
+		Tipos: v["tipo"], Generos: v["genero"], SoloEstrenos: v.Get("estreno") == "1",
+		Orden: v.Get("orden"), Cursor: v.Get("cursor"),
+	}
+	for _, c := range v["clasificacion"] {
+		if edad, err := strconv.Atoi(c); err == nil {
+			q.Clasificaciones = append(q.Clasificaciones, edad)
+		}
+	}
+	q.AnioDesde, _ = strconv.Atoi(v.Get("desde"))
+	q.AnioHasta, _ = strconv.Atoi(v.Get("hasta"))
+	return q
+}
+
+// opcionFiltro es un valor de faceta listo para pintar como enlace: URL
+// activa o desactiva ese valor manteniendo los demás filtros.
+type opcionFiltro struct {
+	Etiqueta string
+	Cantidad int
+	URL      string
+	Activa   bool
+}
+
+// vistaCatalogo alimenta la plantilla "filtros_catalogo" y la paginación.
+type vistaCatalogo struct {
+	Total           int
+	Filtrado        bool
+	Tipos           []opcionFiltro
+	Generos         []opcionFiltro
+	Clasificaciones []opcionFiltro
+	Anios           []opcionFiltro
+	Estrenos        opcionFiltro
+	Ordenes         []opcionFiltro
+	URLLimpiar      string
+	URLSiguiente    string // vacía en la última página
+	URLPrimera      string // vacía en la primera página
+}
+
+// enlace compone ruta?v sin el cursor, porque cambiar un filtro vuelve a la
+// primera página.
+func enlace(ruta string, v url.Values, cambio func(url.Values)) string {
+	copia := url.Values{}
+	for k, vs := range v {
+		if k != "cursor" {
+			copia[k] = slices.Clone(vs)
+		}
+	}
+	cambio(copia)
+	if len(copia) == 0 {
+		return ruta
+	}
+	return ruta + "?" + copia.Encode()
+}
+
+// alternar añade o quita un valor de un parámetro repetible.
+func alternar(clave, valor string) func(url.Values) {
+	return func(v url.Values) {
+		if i := slices.Index(v[clave], valor); i >= 0 {
+			v[clave] = slices.Delete(v[clave], i, i+1)
+			if len(v[clave]) == 0 {
+				delete(v, clave)
+			}
+			return
+		}
+		v.Add(clave, valor)
+	}
+}
+
+func nuevaVistaCatalogo(ruta string, v url.Values, p *content.PaginaCatalogo) vistaCatalogo {
+	opciones := func(clave string, valores []content.ValorFaceta, etiqueta func(string) string) []opcionFiltro {
+		var res []opcionFiltro
+		for _, f := range valores {
+			res = append(res, opcionFiltro{etiqueta(f.Valor), f.Cantidad, enlace(ruta, v, alternar(clave, f.Valor)), slices.Contains(v[clave], f.Valor)})
+		}
+		return res
+	}
+	vista := vistaCatalogo{
+		Total:    p.Total,
+		Tipos:    opciones("tipo", p.Facetas.Tipos, func(t string) string { return etiquetasTipo[t] }),
+		Generos:  opciones("genero", p.Facetas.Generos, func(g string) string { return g }),
+		Estrenos: opcionFiltro{"Estrenos", p.Facetas.Estrenos, enlace(ruta, v, alternar("estreno", "1")), v.Get("estreno") == "1"},
+		Clasificaciones: opciones("clasificacion", p.Facetas.Clasificaciones, func(e string) string {
+			edad, _ := strconv.Atoi(e)
+			return content.EtiquetaClasificacion(edad)
+		}),
+		URLLimpiar: ruta,
+	}
+	// Un año de la faceta es un rango desde = hasta = año.
+	for _, a := range p.Facetas.Anios {
+		activo := v.Get("desde") == a.Valor && v.Get("hasta") == a.Valor
+		vista.Anios = append(vista.Anios, opcionFiltro{a.Valor, a.Cantidad, enlace(ruta, v, func(c url.Values) {
+			c.Del("desde")
+			c.Del("hasta")
+			if !activo {
+				c.Set("desde", a.Valor)
+				c.Set("hasta", a.Valor)
+			}
+		}), activo})
+	}
+	orden := v.Get("orden")
+	for _, o := range []struct{ valor, etiqueta string }{
+		{content.OrdenTitulo, "A-Z"}, {content.OrdenRecientes, "Más recientes"}, {content.OrdenAntiguos, "Más antiguos"},
+	} {
+		vista.Ordenes = append(vista.Ordenes, opcionFiltro{Etiqueta: o.etiqueta, Activa: orden == o.valor || orden == "" && o.valor == content.OrdenTitulo,
+			URL: enlace(ruta, v, func(c url.Values) { c.Set("orden", o.valor) })})
+	}
+	for _, k := range []string{"tipo", "genero", "clasificacion", "desde", "hasta", "estreno"} {
+		vista.Filtrado = vista.Filtrado || v.Has(k)
+	}
+	if p.Siguiente != "" {
+		vista.URLSiguiente = enlace(ruta, v, func(c url.Values) { c.Set("cursor", p.Siguiente) })
+	}
+	if v.Get("cursor") != "" {
+		vista.URLPrimera = enlace(ruta, v, func(url.Values) {})
+	}
+	return vista
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"streaming-system/pkg/auth"
+	"strings"
+	"time"
+)
+
+// diasExpiracionClaveAPI son las opciones de expiración del formulario; 0 = sin expiración.
+var diasExpiracionClaveAPI = []int{30, 90, 365, 0}
+
+// conClaveAPI exige una clave de API vigente que conceda el alcance. Si la
+// clave pertenece a un usuario, este debe conservar el permiso equivalente:
+// quitarle el rol desactiva también sus claves.
+func conClaveAPI(alcance auth.AlcanceAPI, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		verificarClaveAPI(dbStore, alcance, next)(w, r)
+	}
+}
+
+// almacenClavesAPI es lo que verificarClaveAPI necesita de dbStore.
+type almacenClavesAPI interface {
+	LoadAPIKeyByHash(hash string) (*auth.ClaveAPI, error)
+	LoadUserByID(uid string) (*auth.Usuario, error)
+	TouchAPIKey(id int64, fecha time.Time, ip string) error
+}
+
+func verificarClaveAPI(almacen almacenClavesAPI, alcance auth.AlcanceAPI, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		plano, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(plano, auth.PrefijoClaveAPI) {
+			errorJSON(w, http.StatusUnauthorized, "falta la clave de API (Authorization: Bearer "+auth.PrefijoClaveAPI+"...)")
+			return
+		}
+		ahora := time.Now().UTC()
+		c, err := almacen.LoadAPIKeyByHash(auth.HashToken(plano))
+		if err != nil {
+			fmt.Printf("❌ Error al cargar la clave de API: %v\n", err)
+			errorJSON(w, http.StatusInternalServerError, "no se pudo verificar la clave de API")
+			return
+		}
+		if c == nil || !c.EstaVigente(ahora) {
+			errorJSON(w, http.StatusUnauthorized, auth.ErrClaveAPIInvalida.Error())
+			return
+		}
+		if !c.Concede(alcance) {
+			errorJSON(w, http.StatusForbidden, auth.ErrAlcanceInsuficiente.Error())
+			return
+		}
+		ctx := context.WithValue(r.Context(), claveCredencialAPI, c)
+		if !c.EsDeCuentaServicio() {
+			u, err := almacen.LoadUserByID(c.GetUsuarioID())
+			if err != nil {
+				errorJSON(w, http.StatusUnauthorized, auth.ErrClaveAPIInvalida.Error())
+				return
+			}
+			if !u.TienePermiso(alcance.Permiso()) {
+				errorJSON(w, http.StatusForbidden, auth.ErrAlcanceInsuficiente.Error())
+				return
+			}
+			ctx = context.WithValue(ctx, claveUsuario, u)
+		}
+		if c.RegistrarUso(ahora, ipCliente(r)) {
+			if err := almacen.TouchAPIKey(c.GetID(), ahora, ipCliente(r)); err != nil {
+				fmt.Printf("❌ Error al registrar el uso de la clave %s: %v\n", c.GetPrefijo(), err)
+			}
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func claveAPIDe(r *http.Request) *auth.ClaveAPI {
+	c, _ := r.Context().Value(claveCredencialAPI).(*auth.ClaveAPI)
+	return c
+}
+
+// auditarClaveAPI registra un evento iniciado con una clave de API. El actor
+// es el propietario, seguido del prefijo de la clave.
+func auditarClaveAPI(r *http.Request, accion auth.AccionAuditoria, objetivo, detalle string) {
+	c := claveAPIDe(r)
+	actor := "servicio:" + c.GetCuentaServicio()
+	if u := usuarioDe(r); u != nil {
+		actor = u.GetCorreo()
+	}
+	auditar(actor+" ("+c.GetPrefijo()+")", accion, objetivo, ipCliente(r), detalle)
+}
+
+// filaClaveAPI es una clave tal como se muestra en el panel.
+type filaClaveAPI struct {
+	*auth.ClaveAPI
+	Propietario string
+	Estado      string
+}
+
+type datosClavesAPI struct {
+	Claves     []filaClaveAPI
+	Alcances   []auth.AlcanceAPI
+	Dias       []int
+	Nueva      string // valor en claro de la clave recién creada; solo se muestra una vez
+	NuevaDatos *auth.ClaveAPI
+	Error      string
+}
+
+// handleAdminAPIKeys lista, crea y revoca claves de API.
+func handleAdminAPIKeys(w http.ResponseWriter, r *http.Request) {
+	datos := datosClavesAPI{Alcances: auth.AlcancesAPI(), Dias: diasExpiracionClaveAPI}
+	if r.Method == http.MethodPost {
+		switch r.FormValue("accion") {
+		case "crear":
+			plano, c, err := crearClaveAPI(r)
+			if err != nil {
+				datos.Error = err.Error()
+				break
+			}
+			datos.Nueva, datos.NuevaDatos = plano, c
+		case "revocar":
+			id, _ := strconv.ParseInt(r.FormValue("id"), 10, 64)
+			if err := dbStore.RevokeAPIKey(id, time.Now().UTC()); err != nil {
+				if !errors.Is(err, auth.ErrClaveAPIInvalida) {
+					fmt.Printf("❌ Error al revocar la clave de API %d: %v\n", id, err)
+				}
+			} else {
+				auditarPeticion(r, auth.AccionClaveAPIRevocada, r.FormValue("prefijo"), fmt.Sprintf("id %d", id))
+			}
+			http.Redirect(w, r, "/admin/api-keys", http.StatusSeeOther)
+			return
+		}
+	}
+
+	claves, err := dbStore.LoadAPIKeys()
+	if err != nil {
+		fmt.Printf("❌ Error al cargar las claves de API: %v\n", err)
+	}
+	ahora := time.Now().UTC()
+	correos := map[string]string{}
+	for _, c := range claves {
+		f := filaClaveAPI{ClaveAPI: c, Propietario: "servicio: " + c.GetCuentaServicio(), Estado: "Activa"}
+		if !c.EsDeCuentaServicio() {
+			if _, ok := correos[c.GetUsuarioID()]; !ok {
+				if u, err := dbStore.LoadUserByID(c.GetUsuarioID()); err == nil {
+					correos[c.GetUsuarioID()] = u.GetCorreo()
+				}
+			}
+			f.Propietario = correos[c.GetUsuarioID()]
+		}
+		switch {
+		case c.EstaRevocada():
+			f.Estado = "Revocada"
+		case c.Expiro(ahora):
+			f.Estado = "Expirada"
+		}
+		datos.Claves = append(datos.Claves, f)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	renderizar(w, r, "admin_api_keys.html", datos)
+}
+
+// crearClaveAPI valida el formulario del panel y guarda la clave nueva. Para
+// un usuario propietario, cada alcance exige que ya tenga el permiso equivalente.
+func crearClaveAPI(r *http.Request) (string, *auth.ClaveAPI, error) {
+	var alcances []auth.AlcanceAPI
+	for _, v := range r.Form["alcances"] {
+		alcances = append(alcances, auth.AlcanceAPI(v))
+	}
+	var expira time.Time
+	if dias, _ := strconv.Atoi(r.FormValue("dias")); dias > 0 {
+		expira = time.Now().UTC().Truncate(time.Second).AddDate(0, 0, dias)
+	}
+
+	var uid, servicio string
+	if r.FormValue("propietario") == "servicio" {
+		servicio = r.FormValue("servicio")
+	} else {
+		u, err := dbStore.LoadUserByEmail(r.FormValue("email"))
+		if err != nil {
+			return "", nil, errors.New("no existe ningún usuario con ese correo")
+		}
+		for _, a := range alcances {
+			if auth.EsAlcanceValido(a) && !u.TienePermiso(a.Permiso()) {
+				return "", nil, fmt.Errorf("%s no tiene el permiso %s que exige el alcance %s", u.GetCorreo(), a.Permiso(), a)
+			}
+		}
+		uid = u.GetID()
+	}
+
+	admin := usuarioDe(r).GetCorreo()
+	plano, c, err := auth.NuevaClaveAPI(r.FormValue("nombre"), uid, servicio, alcances, expira, admin)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := dbStore.SaveAPIKey(c); err != nil {
+		fmt.Printf("❌ Error al guardar la clave de API: %v\n", err)
+		return "", nil, errors.New("no se pudo guardar la clave, inténtalo de nuevo")
+	}
+	auditarPeticion(r, auth.AccionClaveAPICreada, c.GetPrefijo(), fmt.Sprintf("%q, alcances %v", c.GetNombre(), c.GetAlcances()))
+	return plano, c, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"streaming-system/pkg/auth"
+	"testing"
+	"time"
+)
+
+// almacenClavesAPIMemoria sustituye a dbStore en las pruebas de verificarClaveAPI.
+type almacenClavesAPIMemoria struct {
+	claves   map[string]*auth.ClaveAPI // hash -> clave
+	usuarios map[string]*auth.Usuario
+	usos     int
+	err      error // si no es nil, lo devuelve LoadAPIKeyByHash
+}
+
+func (m *almacenClavesAPIMemoria) LoadAPIKeyByHash(hash string) (*auth.ClaveAPI, error) {
+	return m.claves[hash], m.err
+}
+
+func (m *almacenClavesAPIMemoria) LoadUserByID(uid string) (*auth.Usuario, error) {
+	if u, ok := m.usuarios[uid]; ok {
+		return u, nil
+	}
+	return nil, auth.ErrUsuarioNoEncontrado
+}
+
+func (m *almacenClavesAPIMemoria) TouchAPIKey(id int64, fecha time.Time, ip string) error {
+	m.usos++
+	return nil
+}
+
+func TestVerificarClaveAPI(t *testing.T) {
+	ahora := time.Now().UTC()
+	catalogo := []auth.AlcanceAPI{auth.AlcanceCatalogoEscritura}
+	// clave guarda una clave persistida y devuelve su valor en claro.
+	clave := func(m *almacenClavesAPIMemoria, plano, usuarioID, cuentaServicio string, alcances []auth.AlcanceAPI, expira, revocada time.Time) string {
+		m.claves[auth.HashToken(plano)] = auth.RecreateClaveAPIFromDB(int64(len(m.claves)+1), "ci", plano[:12], auth.HashToken(plano),
+			usuarioID, cuentaServicio, alcances, "admin@example.com", ahora.Add(-time.Hour), expira, time.Time{}, "", revocada)
+		return plano
+	}
+	usuario := func(m *almacenClavesAPIMemoria, uid string, roles ...auth.Rol) {
+		u := auth.RecreateUsuarioFromDB(uid, "ana", "ana"+uid+"@example.com", "", nil)
+		u.AsignarRoles(roles)
+		m.usuarios[uid] = u
+	}
+
+	casos := []struct {
+		nombre      string
+		preparar    func(m *almacenClavesAPIMemoria) string // devuelve la cabecera Authorization
+		status      int
+		propietario string // ID del usuario en el contexto; vacío para cuentas de servicio
+	}{
+		{nombre: "sin cabecera", preparar: func(m *almacenClavesAPIMemoria) string { return "" }, status: http.StatusUnauthorized},
+		{nombre: "access token JWT", preparar: func(m *almacenClavesAPIMemoria) string { return "Bearer eyJhbGciOi.x.y" }, status: http.StatusUnauthorized},
+		{nombre: "clave sin Bearer", preparar: func(m *almacenClavesAPIMemoria) string {
+			return clave(m, "sgk_servicio-0001", "", "importador", catalogo, time.Time{}, time.Time{})
+		}, status: http.StatusUnauthorized},
+		{nombre: "clave desconocida", preparar: func(m *almacenClavesAPIMemoria) string { return "Bearer sgk_desconocida" }, status: http.StatusUnauthorized},
+		{nombre: "error del almacén", preparar: func(m *almacenClavesAPIMemoria) string {
+			m.err = errors.New("sin conexión")
+			return "Bearer sgk_cualquiera"
+		}, status: http.StatusInternalServerError},
+		{nombre: "revocada", preparar: func(m *almacenClavesAPIMemoria) string {
+			return "Bearer " + clave(m, "sgk_servicio-0001", "", "importador", catalogo, time.Time{}, ahora.Add(-time.Minute))
+		}, status: http.StatusUnauthorized},
+		{nombre: "expirada", preparar: func(m *almacenClavesAPIMemoria) string {
+			return "Bearer " + clave(m, "sgk_servicio-0001", "", "importador", catalogo, ahora.Add(-time.Minute), time.Time{})
+		}, status: http.StatusUnauthorized},
+		{nombre: "sin el alcance de la ruta", preparar: func(m *almacenClavesAPIMemoria) string {
+			return "Bearer " + clave(m, "sgk_servicio-0001", "", "facturacion", []auth.AlcanceAPI{auth.AlcanceFacturacionLectura}, time.Time{}, time.Time{})
+		}, status: http.StatusForbidden},
+		{nombre: "cuenta de servicio", preparar: func(m *almacenClavesAPIMemoria) string {
+			return "Bearer " + clave(m, "sgk_servicio-0001", "", "importador", catalogo, ahora.Add(time.Hour), time.Time{})
+		}, status: http.StatusOK},
+		{nombre: "usuario con el permiso", preparar: func(m *almacenClavesAPIMemoria) string {
+			usuario(m, "1", auth.RolContentEditor)
+			return "Bearer " + clave(m, "sgk_usuario-00001", "1", "", catalogo, time.Time{}, time.Time{})
+		}, status: http.StatusOK, propietario: "1"},
+		{nombre: "usuario que perdió el rol", preparar: func(m *almacenClavesAPIMemoria) string {
+			usuario(m, "1", auth.RolViewer)
+			return "Bearer " + clave(m, "sgk_usuario-00001", "1", "", catalogo, time.Time{}, time.Time{})
+		}, status: http.StatusForbidden},
+		{nombre: "usuario con un rol que no concede el alcance", preparar: func(m *almacenClavesAPIMemoria) string {
+			usuario(m, "1", auth.RolBillingAdmin)
+			return "Bearer " + clave(m, "sgk_usuario-00001", "1", "", catalogo, time.Time{}, time.Time{})
+		}, status: http.StatusForbidden},
+		{nombre: "usuario eliminado", preparar: func(m *almacenClavesAPIMemoria) string {
+			return "Bearer " + clave(m, "sgk_usuario-00001", "1", "", catalogo, time.Time{}, time.Time{})
+		}, status: http.StatusUnauthorized},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			m := &almacenClavesAPIMemoria{claves: map[string]*auth.ClaveAPI{}, usuarios: map[string]*auth.Usuario{}}
+			r := httptest.NewRequest(http.MethodPost, "/api/catalog/contents", nil)
+			if cabecera := c.preparar(m); cabecera != "" {
+				r.Header.Set("Authorization", cabecera)
+			}
+			rec := httptest.NewRecorder()
+			var recibida *http.Request
+			verificarClaveAPI(m, auth.AlcanceCatalogoEscritura, func(w http.ResponseWriter, r *http.Request) { recibida = r })(rec, r)
+
+			if rec.Code != c.status {
+				t.Fatalf("status %d, se esperaba %d: %s", rec.Code, c.status, rec.Body)
+			}
+			if c.status != http.StatusOK {
+				if recibida != nil || m.usos != 0 {
+					t.Fatalf("la petición rechazada llegó al handler o registró %d usos", m.usos)
+				}
+				return
+			}
+			if claveAPIDe(recibida) == nil || m.usos != 1 {
+				t.Fatalf("clave en el contexto %v, %d usos registrados", claveAPIDe(recibida), m.usos)
+			}
+			u := usuarioDe(recibida)
+			if (u == nil) != (c.propietario == "") || (u != nil && u.GetID() != c.propietario) {
+				t.Fatalf("usuario en el contexto %v, se esperaba %q", u, c.propietario)
+			}
+		})
+	}
+}
+
+// TestVerificarClaveAPIRegistroDeUso comprueba que las peticiones seguidas
+// no escriben el último uso en cada una.
+func TestVerificarClaveAPIRegistroDeUso(t *testing.T) {
+	const plano = "sgk_servicio-0001"
+	m := &almacenClavesAPIMemoria{claves: map[string]*auth.ClaveAPI{
+		auth.HashToken(plano): auth.RecreateClaveAPIFromDB(1, "ci", plano[:12], auth.HashToken(plano), "", "informes",
+			[]auth.AlcanceAPI{auth.AlcanceReportes}, "", time.Now(), time.Time{}, time.Time{}, "", time.Time{}),
+	}}
+	h := verificarClaveAPI(m, auth.AlcanceReportes, func(w http.ResponseWriter, r *http.Request) {})
+	for range 5 {
+		r := httptest.NewRequest(http.MethodGet, "/api/reports/summary", nil)
+		r.Header.Set("Authorization", "Bearer "+plano)
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d", rec.Code)
+		}
+	}
+	if m.usos != 1 {
+		t.Fatalf("%d usos registrados, se esperaba 1", m.usos)
+	}
+}
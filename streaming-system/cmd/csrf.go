@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"streaming-system/pkg/auth"
+	"streaming-system/pkg/content"
+)
+
+const (
+	campoCSRF    = "csrf_token"    // campo oculto de los formularios
+	cabeceraCSRF = "X-CSRF-Token"  // para peticiones fetch con cuerpo JSON
+	cookieCSRF   = "streamgo_csrf" // semilla del token antes de iniciar sesión
+)
+
+// funcionesPlantilla se registran al parsear; renderizar sustituye las que
+// dependen de la petición (token CSRF y aviso de suplantación).
+var funcionesPlantilla = template.FuncMap{
+	"csrfToken":    func() string { return "" },
+	"suplantacion": func() *datosSuplantacion { return nil },
+	"tipo":         content.TipoDe,
+	"temporadas":   temporadasDe,
+	"add1":         func(n int) int { return n + 1 },
+}
+
+// renderizar ejecuta una plantilla con el token CSRF de la petición. Trabaja
+// sobre una copia de tmpl porque html/template no permite clonar un conjunto
+// que ya se ha ejecutado.
+func renderizar(w http.ResponseWriter, r *http.Request, nombre string, datos any) {
+	token := tokenCSRF(w, r)
+	t, err := tmpl.Clone()
+	if err == nil {
+		suplantacion := suplantacionDe(r)
+		t.Funcs(template.FuncMap{
+			"csrfToken":    func() string { return token },
+			"suplantacion": func() *datosSuplantacion { return suplantacion },
+		})
+		err = t.ExecuteTemplate(w, nombre, datos)
+	}
+	if err != nil {
+		fmt.Printf("❌ Error al mostrar %s: %v\n", nombre, err)
+	}
+}
+
+// tokenCSRF devuelve el token de la sesión o, sin sesión, el ligado a la
+// cookie de semilla, emitiéndola si todavía no existe.
+func tokenCSRF(w http.ResponseWriter, r *http.Request) string {
+	if s := sesionDe(r); s != nil {
+		return sesiones.TokenCSRF(s)
+	}
+	semilla := semillaCSRF(r)
+	if semilla == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		semilla = hex.EncodeToString(b)
+		http.SetCookie(w, &http.Cookie{Name: cookieCSRF, Value: semilla, Path: "/", HttpOnly: true,
+			Secure: r.TLS != nil, SameSite: http.SameSiteLaxMode})
+	}
+	return sesiones.TokenCSRFAnonimo(semilla)
+}
+
+func semillaCSRF(r *http.Request) string {
+	if c, err := r.Cookie(cookieCSRF); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// metodoSeguro indica si el método no debe cambiar estado y no necesita token.
+func metodoSeguro(m string) bool {
+	return m == http.MethodGet || m == http.MethodHead || m == http.MethodOptions
+}
+
+// soloPost responde 405 si la petición no es POST. Las rutas que cambian
+// estado lo exigen porque las peticiones GET no llevan token CSRF y la cookie
+// de sesión (SameSite=Lax) sí viaja al seguir un enlace desde otro sitio.
+func soloPost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodPost {
+		return true
+	}
+	w.Header().Set("Allow", http.MethodPost)
+	http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+	return false
+}
+
+// csrfValido comprueba el token del formulario o de la cabecera X-CSRF-Token.
+func csrfValido(r *http.Request, esperado string) bool {
+	recibido := r.Header.Get(cabeceraCSRF)
+	if recibido == "" {
+		recibido = r.PostFormValue(campoCSRF)
+	}
+	return auth.VerificarTokenCSRF(esperado, recibido)
+}
+
+func rechazarCSRF(w http.ResponseWriter, r *http.Request) {
+	fmt.Printf("🛡️ Petición %s %s rechazada por token CSRF inválido (%s)\n", r.Method, r.URL.Path, ipCliente(r))
+	http.Error(w, "La sesión del formulario caducó o la petición no viene de StreamGo. Recarga la página e inténtalo de nuevo.", http.StatusForbidden)
+}
+
+// conCSRFAnonimo protege los formularios que se envían sin sesión (login,
+// registro, recuperación de contraseña). Las rutas con sesión se comprueban
+// en resolverSesion.
+func conCSRFAnonimo(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !metodoSeguro(r.Method) {
+			semilla := semillaCSRF(r)
+			if semilla == "" || !csrfValido(r, sesiones.TokenCSRFAnonimo(semilla)) {
+				rechazarCSRF(w, r)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"streaming-system/pkg/auth"
+	"strings"
+	"testing"
+)
+
+// iniciarSesionPrueba crea una sesión en sesiones y devuelve su cookie.
+func iniciarSesionPrueba(t *testing.T, uid string) (*auth.Sesion, *http.Cookie) {
+	t.Helper()
+	u := auth.RecreateUsuarioFromDB(uid, "ana", "ana@example.com", "", nil)
+	u.IniciarSesionExterna()
+	rec := httptest.NewRecorder()
+	s, err := sesiones.Iniciar(rec, httptest.NewRequest(http.MethodPost, "/", nil), u, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, rec.Result().Cookies()[0]
+}
+
+// peticionCSRF construye un POST con el token en el formulario, en la cabecera o en ambos.
+func peticionCSRF(formulario, cabecera string, cookies ...*http.Cookie) *http.Request {
+	cuerpo := url.Values{}
+	if formulario != "" {
+		cuerpo.Set(campoCSRF, formulario)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/perfil", strings.NewReader(cuerpo.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cabecera != "" {
+		r.Header.Set(cabeceraCSRF, cabecera)
+	}
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestCSRFAnonimo(t *testing.T) {
+	sesiones = auth.NuevoGestorSesiones(auth.NuevoAlmacenSesionesMemoria(), []byte("clave"))
+	semilla := &http.Cookie{Name: cookieCSRF, Value: "semilla"}
+	token := sesiones.TokenCSRFAnonimo("semilla")
+	ajeno := sesiones.TokenCSRFAnonimo("otra semilla")
+	casos := []struct {
+		nombre string
+		r      *http.Request
+		status int
+	}{
+		{"GET sin token", httptest.NewRequest(http.MethodGet, "/perfil", nil), http.StatusOK},
+		{"sin cookie de semilla", peticionCSRF(token, ""), http.StatusForbidden},
+		{"sin token", peticionCSRF("", "", semilla), http.StatusForbidden},
+		{"token de otra semilla", peticionCSRF(ajeno, "", semilla), http.StatusForbidden},
+		{"token en el formulario", peticionCSRF(token, "", semilla), http.StatusOK},
+		{"token en la cabecera", peticionCSRF("", token, semilla), http.StatusOK},
+		{"la cabecera manda sobre el formulario", peticionCSRF(token, ajeno, semilla), http.StatusForbidden},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			conCSRFAnonimo(func(w http.ResponseWriter, r *http.Request) {})(rec, c.r)
+			if rec.Code != c.status {
+				t.Fatalf("status %d, se esperaba %d", rec.Code, c.status)
+			}
+		})
+	}
+}
+
+func TestCSRFSesion(t *testing.T) {
+	sesiones = auth.NuevoGestorSesiones(auth.NuevoAlmacenSesionesMemoria(), []byte("clave"))
+	s, cookie := iniciarSesionPrueba(t, "1")
+	otra, _ := iniciarSesionPrueba(t, "1")
+	token, ajeno := sesiones.TokenCSRF(s), sesiones.TokenCSRF(otra)
+
+	// Los rechazos ocurren antes de cargar el usuario, así que no hace falta dbStore.
+	rechazos := []struct {
+		nombre string
+		r      *http.Request
+	}{
+		{"sin token", peticionCSRF("", "", cookie)},
+		{"token de otra sesión", peticionCSRF(ajeno, "", cookie)},
+		{"token de otra sesión en la cabecera", peticionCSRF("", ajeno, cookie)},
+		{"token anónimo", peticionCSRF(sesiones.TokenCSRFAnonimo("semilla"), "", cookie, &http.Cookie{Name: cookieCSRF, Value: "semilla"})},
+	}
+	for _, c := range rechazos {
+		t.Run(c.nombre, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			resolverSesion(func(w http.ResponseWriter, r *http.Request) { t.Fatal("la petición llegó al handler") }, false)(rec, c.r)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("status %d, se esperaba %d", rec.Code, http.StatusForbidden)
+			}
+		})
+	}
+
+	validos := []struct {
+		nombre string
+		r      *http.Request
+		ok     bool
+	}{
+		{"token en el formulario", peticionCSRF(token, ""), true},
+		{"token en la cabecera", peticionCSRF("", token), true},
+		{"token de otra sesión", peticionCSRF(ajeno, ""), false},
+	}
+	for _, c := range validos {
+		if got := csrfValido(c.r, sesiones.TokenCSRF(s)); got != c.ok {
+			t.Errorf("%s: csrfValido = %v, se esperaba %v", c.nombre, got, c.ok)
+		}
+	}
+}
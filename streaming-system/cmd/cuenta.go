@@ -0,0 +1,199 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"streaming-system/pkg/auth"
+	"streaming-system/pkg/correo"
+	"time"
+)
+
+// periodoGraciaBaja es el tiempo durante el que una baja solicitada se puede cancelar.
+const periodoGraciaBaja = 14 * 24 * time.Hour
+
+// datosCuenta alimenta account.html.
+type datosCuenta struct {
+	Email          string
+	BajaProgramada time.Time // cero si no hay baja pendiente
+	DiasGracia     int
+	// CuentaExterna indica una identidad OIDC vinculada: si la cuenta se creó
+	// así, su contraseña es aleatoria y hay que restablecerla para darse de baja.
+	CuentaExterna bool
+	Error         string
+	Aviso         string
+}
+
+func renderCuenta(w http.ResponseWriter, r *http.Request, msgError string) {
+	u := usuarioDe(r)
+	baja, err := dbStore.LoadScheduledDeletion(u.GetID())
+	if err != nil {
+		fmt.Printf("❌ Error al cargar la baja programada: %v\n", err)
+	}
+	externa, err := dbStore.HasExternalIdentity(u.GetID())
+	if err != nil {
+		fmt.Printf("❌ Error al cargar las identidades externas: %v\n", err)
+	}
+	datos := datosCuenta{Email: u.GetCorreo(), BajaProgramada: baja, DiasGracia: int(periodoGraciaBaja.Hours() / 24),
+		CuentaExterna: externa, Error: msgError}
+	if r.URL.Query().Get("cancelada") == "1" {
+		datos.Aviso = "La eliminación de tu cuenta se canceló."
+	}
+	renderizar(w, r, "account.html", datos)
+}
+
+func handleAccount(w http.ResponseWriter, r *http.Request) {
+	renderCuenta(w, r, "")
+}
+
+// handleAccountExport descarga un ZIP con un JSON por cada grupo de datos del usuario.
+func handleAccountExport(w http.ResponseWriter, r *http.Request) {
+	u := usuarioDe(r)
+	datos, err := dbStore.ExportUserData(u.GetID())
+	if err != nil {
+		fmt.Printf("❌ Error al exportar datos: %v\n", err)
+		http.Error(w, "No se pudieron exportar tus datos", http.StatusInternalServerError)
+		return
+	}
+	auditarPeticion(r, auth.AccionDatosExportados, u.GetID(), "")
+	archivos := make([]string, 0, len(datos))
+	for nombre := range datos {
+		archivos = append(archivos, nombre)
+	}
+	sort.Strings(archivos)
+
+	ahora := time.Now().UTC()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="streamgo-datos-%s.zip"`, ahora.Format("20060102")))
+	w.Header().Set("Cache-Control", "no-store")
+	z := zip.NewWriter(w)
+	escribir := func(nombre string, v any) error {
+		f, err := z.CreateHeader(&zip.FileHeader{Name: nombre, Method: zip.Deflate, Modified: ahora})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	err = escribir("LEEME.json", map[string]any{
+		"usuario_id": u.GetID(),
+		"email":      u.GetCorreo(),
+		"generado":   ahora,
+		"archivos":   archivos,
+	})
+	for _, nombre := range archivos {
+		if err != nil {
+			break
+		}
+		err = escribir(nombre+".json", datos[nombre])
+	}
+	if err == nil {
+		err = z.Close()
+	}
+	if err != nil {
+		// Las cabeceras ya se enviaron: solo queda registrarlo.
+		fmt.Printf("❌ Error al escribir la exportación: %v\n", err)
+	}
+}
+
+// handleAccountDelete programa la baja tras confirmar la contraseña. Las
+// cuentas creadas con OIDC tienen una contraseña aleatoria: account.html les
+// indica que la restablezcan antes desde /password/forgot.
+func handleAccountDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+	u := usuarioDe(r)
+	if r.FormValue("confirmar") != "ELIMINAR" {
+		renderCuenta(w, r, "Escribe ELIMINAR para confirmar")
+		return
+	}
+	clave := auth.ClaveCuenta(u.GetCorreo())
+	err := verificarLimite(clave)
+	if err == nil {
+		if ok, _ := auth.VerificarContrasenia(u.GetContraseniaHash(), r.FormValue("password")); !ok {
+			limitador.RegistrarFallo(clave)
+			err = auth.ErrCredencialesInvalidas
+		}
+	}
+	if err != nil {
+		renderCuenta(w, r, mensajeLogin(err))
+		return
+	}
+
+	fecha := time.Now().Add(periodoGraciaBaja)
+	if err := dbStore.ScheduleAccountDeletion(u.GetID(), fecha); err != nil {
+		fmt.Printf("❌ Error al programar la baja: %v\n", err)
+		renderCuenta(w, r, "No se pudo programar la eliminación, inténtalo de nuevo")
+		return
+	}
+	auditarPeticion(r, auth.AccionBajaSolicitada, u.GetID(), "efectiva el "+fecha.UTC().Format(time.RFC3339))
+	// El resto de dispositivos pierde la sesión; este conserva la suya para poder cancelar.
+	if _, err := sesiones.CerrarOtras(u.GetID(), sesionDe(r)); err != nil {
+		fmt.Printf("❌ Error al cerrar sesiones: %v\n", err)
+	}
+	err = mailer.Enviar(correo.Mensaje{
+		Para:   u.GetCorreo(),
+		Asunto: "Tu cuenta de StreamGo se eliminará",
+		Cuerpo: fmt.Sprintf("Hola %s,\n\nRecibimos tu solicitud para eliminar tu cuenta. Se eliminará definitivamente el %s.\n\nSi cambias de opinión, inicia sesión antes de esa fecha y cancela la eliminación desde %s/account.\n\nSi no lo solicitaste, cambia tu contraseña y cancela la eliminación cuanto antes.",
+			u.GetNombre(), fecha.Format("02/01/2006 15:04"), urlBase()),
+	})
+	if err != nil {
+		fmt.Printf("❌ Error al enviar aviso de baja: %v\n", err)
+	}
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// handleAccountDeleteCancel anula una baja pendiente.
+func handleAccountDeleteCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+	u := usuarioDe(r)
+	if err := dbStore.CancelAccountDeletion(u.GetID()); err != nil {
+		fmt.Printf("❌ Error al cancelar la baja: %v\n", err)
+		renderCuenta(w, r, "No se pudo cancelar la eliminación, inténtalo de nuevo")
+		return
+	}
+	auditarPeticion(r, auth.AccionBajaCancelada, u.GetID(), "")
+	http.Redirect(w, r, "/account?cancelada=1", http.StatusSeeOther)
+}
+
+// finalizarBajas elimina las cuentas cuyo periodo de gracia terminó.
+func finalizarBajas() {
+	uids, err := dbStore.LoadDueAccountDeletions(time.Now())
+	if err != nil {
+		fmt.Printf("❌ Error al buscar bajas pendientes: %v\n", err)
+		return
+	}
+	for _, uid := range uids {
+		u, err := dbStore.LoadUserByID(uid)
+		if err != nil && !errors.Is(err, auth.ErrUsuarioNoEncontrado) {
+			fmt.Printf("❌ Error al cargar usuario %s: %v\n", uid, err)
+			continue
+		}
+		if err := dbStore.DeleteAccount(uid); err != nil {
+			fmt.Printf("❌ Error al eliminar la cuenta %s: %v\n", uid, err)
+			continue
+		}
+		if u != nil {
+			limitador.Reiniciar(auth.ClaveCuenta(u.GetCorreo()))
+		}
+		fmt.Printf("🗑️ Cuenta %s eliminada al terminar el periodo de gracia\n", uid)
+		auditar(actorSistema, auth.AccionCuentaEliminada, uid, "", "fin del periodo de gracia")
+	}
+}
+
+// finalizarBajasPeriodicamente revisa las bajas pendientes al arrancar y cada hora.
+func finalizarBajasPeriodicamente() {
+	for {
+		finalizarBajas()
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"streaming-system/pkg/auth"
+	"strings"
+	"time"
+)
+
+// dispositivoSesion es una fila de devices.html y de su respuesta JSON.
+type dispositivoSesion struct {
+	ID           string    `json:"id"`
+	Descripcion  string    `json:"description"`
+	UserAgent    string    `json:"user_agent"`
+	IP           string    `json:"ip"`
+	Creada       time.Time `json:"created_at"`
+	UltimoAcceso time.Time `json:"last_seen_at"`
+	Actual       bool      `json:"current"`
+}
+
+func listarDispositivos(r *http.Request) ([]dispositivoSesion, error) {
+	actual := sesionDe(r)
+	lista, err := sesiones.Listar(usuarioDe(r).GetID())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]dispositivoSesion, 0, len(lista))
+	for _, s := range lista {
+		d := s.GetDispositivo()
+		res = append(res, dispositivoSesion{
+			ID:           s.GetID(),
+			Descripcion:  d.Descripcion(),
+			UserAgent:    d.UserAgent,
+			IP:           d.IP,
+			Creada:       s.GetCreada(),
+			UltimoAcceso: s.GetUltimoAcceso(),
+			Actual:       s.GetToken() == actual.GetToken(),
+		})
+	}
+	return res, nil
+}
+
+// handleAccountDevices lista las sesiones abiertas de la cuenta. Con
+// "Accept: application/json" responde la misma lista en JSON.
+func handleAccountDevices(w http.ResponseWriter, r *http.Request) {
+	lista, err := listarDispositivos(r)
+	jsonPedido := strings.Contains(r.Header.Get("Accept"), "application/json")
+	if err != nil {
+		fmt.Printf("❌ Error al listar sesiones: %v\n", err)
+		if jsonPedido {
+			errorJSON(w, http.StatusInternalServerError, "no se pudieron cargar los dispositivos")
+		} else {
+			http.Error(w, "No se pudieron cargar los dispositivos", http.StatusInternalServerError)
+		}
+		return
+	}
+	if jsonPedido {
+		responderJSON(w, http.StatusOK, map[string]any{"sessions": lista})
+		return
+	}
+	renderizar(w, r, "devices.html", lista)
+}
+
+// handleRevokeDevice cierra una sesión concreta ({"id": "..."}) o todas
+// menos la actual ({"all_others": true}).
+func handleRevokeDevice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID        string `json:"id"`
+		AllOthers bool   `json:"all_others"`
+	}
+	if !leerJSON(w, r, &req) {
+		return
+	}
+	u, actual := usuarioDe(r), sesionDe(r)
+
+	if req.AllOthers {
+		n, err := sesiones.CerrarOtras(u.GetID(), actual)
+		if err != nil {
+			fmt.Printf("❌ Error al cerrar sesiones: %v\n", err)
+			errorJSON(w, http.StatusInternalServerError, "no se pudieron cerrar las sesiones")
+			return
+		}
+		auditarPeticion(r, auth.AccionSesionRevocada, u.GetID(), fmt.Sprintf("todas las demás (%d)", n))
+		responderJSON(w, http.StatusOK, map[string]int{"revoked": n})
+		return
+	}
+	if req.ID == "" {
+		errorJSON(w, http.StatusBadRequest, "falta el id de la sesión")
+		return
+	}
+	if req.ID == actual.GetID() {
+		errorJSON(w, http.StatusBadRequest, "para cerrar la sesión actual usa /logout")
+		return
+	}
+	if err := sesiones.CerrarPorID(u.GetID(), req.ID); err != nil {
+		if errors.Is(err, auth.ErrSesionInvalida) {
+			errorJSON(w, http.StatusNotFound, "sesión no encontrada")
+			return
+		}
+		fmt.Printf("❌ Error al cerrar la sesión: %v\n", err)
+		errorJSON(w, http.StatusInternalServerError, "no se pudo cerrar la sesión")
+		return
+	}
+	auditarPeticion(r, auth.AccionSesionRevocada, u.GetID(), "sesión "+req.ID)
+	responderJSON(w, http.StatusOK, map[string]int{"revoked": 1})
+}
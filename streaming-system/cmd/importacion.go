@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"streaming-system/pkg/auth"
+	"streaming-system/pkg/content"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tamanioImportacionMax limita el archivo de importación.
+const tamanioImportacionMax = 10 << 20
+
+// importando serializa las importaciones: cada una se planifica sobre la
+// instantánea actual y no debe pisar los cambios de otra.
+var importando sync.Mutex
+
+// tiposExportacion es el Content-Type de cada formato.
+var tiposExportacion = map[string]string{
+	content.FormatoCSV:    "text/csv; charset=utf-8",
+	content.FormatoJSON:   "application/json",
+	content.FormatoNDJSON: "application/x-ndjson",
+}
+
+// formatoDe devuelve el formato indicado o, si está vacío, el que corresponde
+// a la extensión del archivo (.csv, .json, .ndjson o .jsonl).
+func formatoDe(formato, nombreArchivo string) string {
+	if formato = strings.ToLower(strings.TrimSpace(formato)); formato != "" {
+		return formato
+	}
+	switch strings.ToLower(path.Ext(nombreArchivo)) {
+	case ".csv":
+		return content.FormatoCSV
+	case ".json":
+		return content.FormatoJSON
+	case ".ndjson", ".jsonl":
+		return content.FormatoNDJSON
+	}
+	return ""
+}
+
+// importarCatalogo lee el archivo y, salvo que sea una simulación o alguna
+// fila tenga errores, guarda todos los contenidos en una transacción y los
+// pasa al gestor.
+func importarCatalogo(r io.Reader, formato string, simular bool) (*content.ResultadoImportacion, error) {
+	filas, err := content.LeerCatalogo(r, formato)
+	if err != nil {
+		return nil, err
+	}
+	importando.Lock()
+	defer importando.Unlock()
+	res := content.PlanificarImportacion(gestor.Instantanea(), filas)
+	if simular || !res.Valida() || len(res.Contenidos) == 0 {
+		return res, nil
+	}
+	if err := dbStore.ImportContent(res.Contenidos); err != nil {
+		fmt.Printf("❌ Error al importar el catálogo: %v\n", err)
+		return nil, errors.New("no se pudo guardar la importación en la base de datos; no se aplicó ningún cambio")
+	}
+	for _, c := range res.Contenidos {
+		gestor.InsertarContenido(c)
+	}
+	return res, nil
+}
+
+// detalleImportacion resume el resultado para la auditoría.
+func detalleImportacion(formato string, res *content.ResultadoImportacion) string {
+	return fmt.Sprintf("%s: %d creados, %d actualizados, %d temporadas, %d episodios", formato, len(res.Creados), len(res.Actualizados),
+		res.Temporadas, res.Episodios)
+}
+
+// handleAdminImport muestra el formulario de importación (GET) y simula o
+// aplica un archivo (POST).
+func handleAdminImport(w http.ResponseWriter, r *http.Request) {
+	datos := struct {
+		Resultado *content.ResultadoImportacion
+		Simulado  bool
+		Aplicado  bool
+		Error     string
+	}{}
+	if r.Method == http.MethodPost {
+		datos.Simulado = r.FormValue("simular") == "1"
+		archivo, cabecera, err := r.FormFile("archivo")
+		formato := ""
+		switch {
+		case err != nil:
+			datos.Error = "Selecciona un archivo"
+		case cabecera.Size > tamanioImportacionMax:
+			datos.Error = "El archivo supera el tamaño máximo de 10 MB"
+		default:
+			defer archivo.Close()
+			formato = formatoDe(r.FormValue("formato"), cabecera.Filename)
+			datos.Resultado, err = importarCatalogo(archivo, formato, datos.Simulado)
+			if err != nil {
+				datos.Error = err.Error()
+			}
+		}
+		if res := datos.Resultado; res != nil {
+			datos.Aplicado = !datos.Simulado && res.Valida() && len(res.Contenidos) > 0
+			if datos.Aplicado {
+				auditarPeticion(r, auth.AccionCatalogoImportado, cabecera.Filename, detalleImportacion(formato, res))
+			}
+		}
+		if datos.Error != "" || datos.Resultado != nil && !datos.Resultado.Valida() {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+	}
+	renderizar(w, r, "admin_import.html", datos)
+}
+
+// exportarCatalogo escribe todo el catálogo como descarga.
+func exportarCatalogo(w http.ResponseWriter, formato string) error {
+	tipo, ok := tiposExportacion[formato]
+	if !ok {
+		return content.ErrFormatoDesconocido
+	}
+	w.Header().Set("Content-Type", tipo)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catalogo-%s.%s"`, time.Now().UTC().Format("20060102-150405"), formato))
+	if err := content.ExportarCatalogo(w, formato, gestor.ObtenerTodo()); err != nil {
+		fmt.Printf("❌ Error al exportar el catálogo: %v\n", err)
+	}
+	return nil
+}
+
+// handleAdminExport descarga el catálogo en ?formato= (csv por defecto).
+func handleAdminExport(w http.ResponseWriter, r *http.Request) {
+	formato := formatoDe(r.URL.Query().Get("formato"), ".csv")
+	if err := exportarCatalogo(w, formato); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	auditarPeticion(r, auth.AccionCatalogoExportado, "catalogo", formato)
+}
+
+// errorFilaAPI es la representación JSON de ErrorFila.
+type errorFilaAPI struct {
+	Fila    int      `json:"row"`
+	ID      string   `json:"id,omitempty"`
+	Errores []string `json:"errors"`
+}
+
+// handleAPICatalogoImport importa el cuerpo de la petición (alcance
+// catalog:write). ?format= es csv, json o ndjson; ?dry_run=1 solo valida.
+func handleAPICatalogoImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorJSON(w, http.StatusMethodNotAllowed, "método no permitido")
+		return
+	}
+	q := r.URL.Query()
+	formato, simular := formatoDe(q.Get("format"), ""), q.Get("dry_run") == "1" || q.Get("dry_run") == "true"
+	res, err := importarCatalogo(http.MaxBytesReader(w, r.Body, tamanioImportacionMax), formato, simular)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	errores := make([]errorFilaAPI, 0, len(res.Errores))
+	for _, e := range res.Errores {
+		errores = append(errores, errorFilaAPI{e.Fila, e.ID, e.Errores})
+	}
+	aplicado := !simular && res.Valida() && len(res.Contenidos) > 0
+	if aplicado {
+		auditarClaveAPI(r, auth.AccionCatalogoImportado, "catalogo", "api "+detalleImportacion(formato, res))
+	}
+	status := http.StatusOK
+	if !res.Valida() {
+		status = http.StatusUnprocessableEntity
+	}
+	responderJSON(w, status, map[string]any{
+		"dry_run": simular, "applied": aplicado, "seasons": res.Temporadas, "episodes": res.Episodios, "errors": errores,
+		"created": append([]string{}, res.Creados...), "updated": append([]string{}, res.Actualizados...),
+	})
+}
+
+// handleAPICatalogoExport devuelve el catálogo en ?format= (ndjson por defecto).
+func handleAPICatalogoExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorJSON(w, http.StatusMethodNotAllowed, "método no permitido")
+		return
+	}
+	formato := formatoDe(r.URL.Query().Get("format"), ".ndjson")
+	if err := exportarCatalogo(w, formato); err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	auditarClaveAPI(r, auth.AccionCatalogoExportado, "catalogo", "api "+formato)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"streaming-system/pkg/auth"
+	"strings"
+	"time"
+)
+
+// errorEspera informa al usuario cuánto falta para poder reintentar.
+type errorEspera struct {
+	espera time.Duration
+}
+
+func (e errorEspera) Error() string {
+	return fmt.Sprintf("Demasiados intentos fallidos. Inténtalo de nuevo en %s.", e.espera.Round(time.Second))
+}
+
+func (e errorEspera) Unwrap() error { return auth.ErrDemasiadosIntentos }
+
+// nuevoLimitador usa memoria por defecto; STREAMGO_ALMACEN_LIMITADOR=mysql
+// comparte los contadores entre varias instancias.
+func nuevoLimitador() *auth.LimitadorIntentos {
+	var almacen auth.AlmacenIntentos = auth.NuevoAlmacenIntentosMemoria()
+	if os.Getenv("STREAMGO_ALMACEN_LIMITADOR") == "mysql" {
+		almacen = dbStore
+	}
+	l := auth.NuevoLimitadorIntentos(almacen)
+	l.AlBloquear(func(e *auth.EventoBloqueo) {
+		fmt.Printf("🔒 Bloqueo de inicio de sesión: %s hasta %s (%d fallos)\n", e.GetClave(), e.GetHasta().Format(time.RFC3339), e.GetFallos())
+		auditar(actorSistema, auth.AccionCuentaBloqueada, e.GetClave(), "", fmt.Sprintf("hasta %s (%d fallos)", e.GetHasta().Format(time.RFC3339), e.GetFallos()))
+	})
+	return l
+}
+
+// verificarLimite devuelve errorEspera si alguna clave está bloqueada.
+func verificarLimite(claves ...string) error {
+	espera, err := limitador.Verificar(claves...)
+	if errors.Is(err, auth.ErrDemasiadosIntentos) {
+		return errorEspera{espera}
+	}
+	return err
+}
+
+// mensajeLogin traduce el error de autenticar a un texto para el formulario.
+func mensajeLogin(err error) string {
+	var e errorEspera
+	if errors.As(err, &e) {
+		return e.Error()
+	}
+	if !errors.Is(err, auth.ErrCredencialesInvalidas) {
+		fmt.Printf("❌ Error al iniciar sesión: %v\n", err)
+	}
+	return "Email o contraseña incorrectos"
+}
+
+// ipCliente devuelve la IP del cliente. X-Forwarded-For solo se tiene en
+// cuenta con STREAMGO_CONFIAR_PROXY=1, ya que el cliente puede falsificarlo.
+func ipCliente(r *http.Request) string {
+	if os.Getenv("STREAMGO_CONFIAR_PROXY") == "1" {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			ip, _, _ := strings.Cut(xff, ",")
+			return strings.TrimSpace(ip)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// handleAdminLockouts lista los bloqueos vigentes y permite desbloquear una clave.
+func handleAdminLockouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if clave := r.FormValue("clave"); clave != "" {
+			if err := limitador.Reiniciar(clave); err != nil {
+				fmt.Printf("❌ Error al desbloquear %s: %v\n", clave, err)
+			} else {
+				auditarPeticion(r, auth.AccionCuentaDesbloqueada, clave, "")
+			}
+		}
+		http.Redirect(w, r, "/admin/lockouts", http.StatusSeeOther)
+		return
+	}
+	activos, err := limitador.BloqueosActivos()
+	if err != nil {
+		fmt.Printf("❌ Error al cargar bloqueos: %v\n", err)
+	}
+	eventos, err := limitador.EventosRecientes(50)
+	if err != nil {
+		fmt.Printf("❌ Error al cargar eventos de bloqueo: %v\n", err)
+	}
+	renderizar(w, r, "admin_lockouts.html", struct {
+		Activos []*auth.EstadoIntentos
+		Eventos []*auth.EventoBloqueo
+	}{activos, eventos})
+}
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"streaming-system/pkg/auth"
 	"streaming-system/pkg/billing"
+	"streaming-system/pkg/busqueda"
 	"streaming-system/pkg/content"
+	"streaming-system/pkg/correo"
+	"streaming-system/pkg/playback"
 	"streaming-system/pkg/storage"
+	"strings"
 	"time"
 )
 
@@ -17,8 +23,13 @@ var (
 	gestor            *content.GestorDeContenido
 	dbStore           *storage.MySQLStorage
 	planesDisponibles map[string]*billing.Plan
-	usuarioActual     *auth.Usuario
-	perfilActual      *auth.Perfil
+	sesiones          *auth.GestorSesiones
+	tokens            *auth.EmisorTokens
+	mailer            correo.Mailer
+	limitador         *auth.LimitadorIntentos
+	verificador       *auth.FirmanteVerificacion
+	oidc              *auth.ClienteOIDC // nil si no hay proveedor configurado
+	auditoria         *auth.RegistroAuditoria
 	tmpl              *template.Template
 )
 
@@ -41,22 +52,80 @@ func main() {
 	defer s.DB.Close()
 
 	inicializarDatos()
-	tmpl = template.Must(template.ParseGlob("templates/*.html"))
+	sesiones = nuevoGestorSesiones()
+	tokens = nuevoEmisorTokens()
+	mailer = nuevoMailer()
+	limitador = nuevoLimitador()
+	verificador = nuevoFirmanteVerificacion()
+	oidc = nuevoClienteOIDC()
+	auditoria = nuevoRegistroAuditoria()
+	tmpl = template.Must(template.New("").Funcs(funcionesPlantilla).ParseGlob("templates/*.html"))
 
 	// RUTAS
-	http.HandleFunc("/", handleLogin)
-	http.HandleFunc("/register", handleRegister)
-	http.HandleFunc("/profiles", handleProfiles)
-	http.HandleFunc("/profiles/create", handleCreateProfile)
-	http.HandleFunc("/profiles/select", handleSelectProfile)
-	http.HandleFunc("/dashboard", handleDashboard)
-	http.HandleFunc("/checkout", handleCheckout)
-	http.HandleFunc("/checkout/buy", handleBuyPlan)
-	http.HandleFunc("/admin", handleAdmin)
-	http.HandleFunc("/admin/add", handleAdminAdd)
-	http.HandleFunc("/admin/update", handleAdminUpdate)
-	http.HandleFunc("/admin/delete", handleAdminDelete)
+	http.HandleFunc("/", conCSRFAnonimo(handleLogin))
+	http.HandleFunc("/register", conCSRFAnonimo(handleRegister))
+	http.HandleFunc("/password/forgot", conCSRFAnonimo(handlePasswordForgot))
+	http.HandleFunc("/password/reset", conCSRFAnonimo(handlePasswordReset))
+	http.HandleFunc("/profiles", conSesion(handleProfiles))
+	http.HandleFunc("/profiles/create", conSesion(handleCreateProfile))
+	http.HandleFunc("/profiles/select", conSesion(handleSelectProfile))
+	http.HandleFunc("/profiles/edit", conSesion(handleEditProfile))
+	http.HandleFunc("/profiles/delete", conSesion(handleDeleteProfile))
+	http.HandleFunc("/profiles/pin", conSesion(handleProfilePIN))
+	http.HandleFunc("/dashboard", conSesion(handleDashboard))
+	http.HandleFunc("/search", conSesion(handleSearch))
+	http.HandleFunc("/search.json", conSesion(handleSearchJSON))
+	http.HandleFunc("/search/suggest", conSesion(handleSearchSuggest))
+	http.HandleFunc("/watch", conSesion(handleWatch))
+	http.HandleFunc("/checkout", conSesion(handleCheckout))
+	http.HandleFunc("/checkout/buy", conSesion(sinSuplantacion(handleBuyPlan)))
+	http.HandleFunc("/admin", conPermiso(auth.PermisoAccederAdmin, handleAdmin))
+	http.HandleFunc("/admin/add", conPermiso(auth.PermisoGestionarCatalogo, handleAdminAdd))
+	http.HandleFunc("/admin/update", conPermiso(auth.PermisoGestionarCatalogo, handleAdminUpdate))
+	http.HandleFunc("/admin/delete", conPermiso(auth.PermisoGestionarCatalogo, handleAdminDelete))
+	http.HandleFunc("/admin/series", conPermiso(auth.PermisoGestionarCatalogo, handleAdminSeries))
+	http.HandleFunc("/admin/import", conPermiso(auth.PermisoGestionarCatalogo, handleAdminImport))
+	http.HandleFunc("/admin/export", conPermiso(auth.PermisoGestionarCatalogo, handleAdminExport))
+	http.HandleFunc("/admin/billing", conPermiso(auth.PermisoVerFacturacion, handleAdminBilling))
+	http.HandleFunc("/admin/roles", conPermiso(auth.PermisoGestionarRoles, handleAdminRoles))
 	http.HandleFunc("/logout", handleLogout)
+	http.HandleFunc("/verify-email", handleVerifyEmail)
+	http.HandleFunc("/verify-email/resend", conSesion(handleResendVerification))
+	http.HandleFunc("/login/mfa", conSesionMFAPendiente(handleLoginMFA))
+	http.HandleFunc("/login/oidc", handleLoginOIDC)
+	http.HandleFunc("/login/oidc/callback", handleOIDCCallback)
+	http.HandleFunc("/account/mfa", conSesion(sinSuplantacion(handleAccountMFA)))
+	http.HandleFunc("/account/mfa/qr.png", conSesionMFAPendiente(handleMFAQR))
+	http.HandleFunc("/account/devices", conSesion(handleAccountDevices))
+	http.HandleFunc("/account", conSesion(handleAccount))
+	http.HandleFunc("/account/export", conSesion(sinSuplantacion(handleAccountExport)))
+	http.HandleFunc("/account/delete", conSesion(sinSuplantacion(handleAccountDelete)))
+	http.HandleFunc("/account/delete/cancel", conSesion(sinSuplantacion(handleAccountDeleteCancel)))
+	http.HandleFunc("/account/devices/revoke", conSesion(sinSuplantacion(handleRevokeDevice)))
+	http.HandleFunc("/admin/mfa", conPermiso(auth.PermisoGestionarRoles, handleAdminMFA))
+	http.HandleFunc("/admin/lockouts", conPermiso(auth.PermisoGestionarCuentas, handleAdminLockouts))
+	http.HandleFunc("/admin/audit", conPermiso(auth.PermisoVerAuditoria, handleAdminAudit))
+	http.HandleFunc("/admin/audit.csv", conPermiso(auth.PermisoVerAuditoria, handleAdminAuditCSV))
+	http.HandleFunc("/admin/api-keys", conPermiso(auth.PermisoGestionarClaves, handleAdminAPIKeys))
+	http.HandleFunc("/admin/impersonate", conPermiso(auth.PermisoSuplantar, handleAdminImpersonate))
+	http.HandleFunc("/impersonate/stop", conSesion(handleImpersonateStop))
+
+	// API JSON (clientes móviles y TV)
+	http.HandleFunc("/api/auth/token", handleAPIToken)
+	http.HandleFunc("/api/auth/refresh", handleAPIRefresh)
+	http.HandleFunc("/api/auth/revoke", handleAPIRevoke)
+	http.HandleFunc("/api/me", conBearer(handleAPIMe))
+	http.HandleFunc("/api/mfa/enroll", conBearer(handleAPIMFAEnroll))
+	http.HandleFunc("/api/mfa/confirm", conBearer(handleAPIMFAConfirm))
+
+	// API de servicios (claves de API con alcance)
+	http.HandleFunc("/api/catalog/contents", conClaveAPI(auth.AlcanceCatalogoEscritura, handleAPICatalogo))
+	http.HandleFunc("/api/catalog/import", conClaveAPI(auth.AlcanceCatalogoEscritura, handleAPICatalogoImport))
+	http.HandleFunc("/api/catalog/export", conClaveAPI(auth.AlcanceCatalogoEscritura, handleAPICatalogoExport))
+	http.HandleFunc("/api/billing/subscriptions", conClaveAPI(auth.AlcanceFacturacionLectura, handleAPIFacturacion))
+	http.HandleFunc("/api/reports/summary", conClaveAPI(auth.AlcanceReportes, handleAPIReportes))
+
+	go finalizarBajasPeriodicamente()
 
 	port := ":8080"
 	fmt.Printf("🚀 Servidor corriendo en http://localhost%s\n", port)
@@ -70,156 +139,444 @@ func inicializarDatos() {
 	}
 	lista, _ := dbStore.LoadAllContent()
 	gestor = content.NuevoGestorDeContenido(lista)
+	buscador = busqueda.NuevoBuscador(gestor)
 }
 
 // --- MANEJADORES ---
 
+// datosLogin alimenta login.html con los avisos de los distintos flujos.
+type datosLogin struct {
+	Reset      bool
+	Registrado bool
+	Verificado bool
+	Error      string
+}
+
+// datosRegistro conserva lo escrito en register.html si hay errores.
+type datosRegistro struct {
+	Nombre string
+	Email  string
+	Error  string
+}
+
 func handleLogin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	datos := datosLogin{Reset: q.Get("reset") == "1", Registrado: q.Get("registrado") == "1", Verificado: q.Get("verificado") == "1"}
 	if r.Method == http.MethodPost {
-		email := r.FormValue("email")
-		pass := r.FormValue("password")
-		u, _ := dbStore.LoadUserByEmail(email)
-		if u != nil && u.IniciarSesion(email, pass) == nil {
-			usuarioActual = u
-			sub, _ := dbStore.LoadActiveSubscription(u.GetID())
-			if sub != nil {
-				usuarioActual.AsignarSuscripcion(sub)
-			}
-			if email == "admin@stream.com" {
-				http.Redirect(w, r, "/admin", http.StatusSeeOther)
-				return
-			}
-			http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		u, err := autenticar(r.FormValue("email"), r.FormValue("password"), ipCliente(r))
+		if err == nil {
+			iniciarSesionWeb(w, r, u, "contraseña")
 			return
 		}
+		datos.Error = mensajeLogin(err)
 	}
-	tmpl.ExecuteTemplate(w, "login.html", nil)
+	renderizar(w, r, "login.html", datos)
 }
 
-func handleRegister(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodPost {
-		u := auth.NuevoUsuario(fmt.Sprintf("%d", time.Now().Unix()), r.FormValue("nombre"), r.FormValue("email"), r.FormValue("password"))
-		dbStore.SaveUser(u)
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+// iniciarSesionWeb abre la sesión de un usuario ya autenticado (contraseña u
+// OIDC, según metodo) y lo envía al segundo factor si le corresponde.
+func iniciarSesionWeb(w http.ResponseWriter, r *http.Request, u *auth.Usuario, metodo string) {
+	pendiente, err := necesitaSegundoFactor(u)
+	if err == nil {
+		_, err = sesiones.Iniciar(w, r, u, pendiente)
+	}
+	if err != nil {
+		fmt.Printf("❌ Error al crear la sesión: %v\n", err)
+		http.Error(w, "No se pudo iniciar sesión", http.StatusInternalServerError)
 		return
 	}
-	tmpl.ExecuteTemplate(w, "register.html", nil)
-}
-
-func handleProfiles(w http.ResponseWriter, r *http.Request) {
-	if usuarioActual == nil {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+	if pendiente {
+		metodo += ", segundo factor pendiente"
+	}
+	auditar(u.GetCorreo(), auth.AccionLogin, u.GetID(), ipCliente(r), metodo)
+	if pendiente {
+		http.Redirect(w, r, "/login/mfa", http.StatusSeeOther)
 		return
 	}
-	p, _ := dbStore.LoadProfilesByUserID(usuarioActual.GetID())
-	tmpl.ExecuteTemplate(w, "profiles.html", struct{ Perfiles []*auth.Perfil }{p})
+	redirigirTrasLogin(w, r, u)
 }
 
-func handleCreateProfile(w http.ResponseWriter, r *http.Request) {
-	if usuarioActual == nil {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+// redirigirTrasLogin envía al panel a quien puede acceder a él y al resto a perfiles.
+func redirigirTrasLogin(w http.ResponseWriter, r *http.Request, u *auth.Usuario) {
+	if u.TienePermiso(auth.PermisoAccederAdmin) {
+		http.Redirect(w, r, "/admin", http.StatusSeeOther)
 		return
 	}
-	dbStore.SaveProfile(usuarioActual.GetID(), auth.NuevoPerfil(int(time.Now().Unix()%1000), r.FormValue("nombre")))
 	http.Redirect(w, r, "/profiles", http.StatusSeeOther)
 }
 
-func handleSelectProfile(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
-	perfiles, _ := dbStore.LoadProfilesByUserID(usuarioActual.GetID())
-	for _, p := range perfiles {
-		if p.GetID() == id {
-			perfilActual = p
-			break
+// autenticar valida las credenciales (formulario o API) pasando por el
+// limitador de intentos, y persiste el hash si IniciarSesion lo migró.
+func autenticar(email, pass, ip string) (*auth.Usuario, error) {
+	claves := []string{auth.ClaveCuenta(email), auth.ClaveIP(ip)}
+	if err := verificarLimite(claves...); err != nil {
+		return nil, err
+	}
+	u, err := dbStore.LoadUserByEmail(email)
+	if err == nil {
+		err = u.IniciarSesion(email, pass)
+	}
+	if err != nil {
+		auditar(email, auth.AccionLoginFallido, email, ip, "credenciales incorrectas")
+		if err := limitador.RegistrarFallo(claves...); err != nil {
+			fmt.Printf("❌ Error al registrar intento fallido: %v\n", err)
 		}
+		return nil, auth.ErrCredencialesInvalidas
 	}
-	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+	limitador.Reiniciar(claves[0])
+	if u.RequiereGuardarHash() {
+		if err := dbStore.UpdatePasswordHash(u.GetID(), u.GetContraseniaHash()); err != nil {
+			fmt.Printf("❌ Error al migrar el hash de contraseña: %v\n", err)
+		}
+	}
+	return u, nil
 }
 
-func handleDashboard(w http.ResponseWriter, r *http.Request) {
-	if usuarioActual == nil {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+func handleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		renderizar(w, r, "register.html", datosRegistro{})
+		return
+	}
+	datos := datosRegistro{Nombre: r.FormValue("nombre"), Email: strings.TrimSpace(r.FormValue("email"))}
+	var u *auth.Usuario
+	err := auth.ValidarContrasenia(r.FormValue("password"))
+	if err == nil {
+		u, err = auth.NuevoUsuario(auth.NuevoIDUsuario(), datos.Nombre, datos.Email, r.FormValue("password"))
+	}
+	if err == nil {
+		err = dbStore.SaveUser(u)
+	}
+	switch {
+	case errors.Is(err, auth.ErrContraseniaDebil) || errors.Is(err, auth.ErrEmailInvalido) || errors.Is(err, auth.ErrEmailEnUso):
+		datos.Error = err.Error()
+	case err != nil:
+		fmt.Printf("❌ Error al registrar usuario: %v\n", err)
+		datos.Error = "No se pudo crear la cuenta, inténtalo de nuevo"
+	}
+	if err != nil {
+		renderizar(w, r, "register.html", datos)
 		return
 	}
-	if usuarioActual.GetSuscripcion() == nil {
+	if err := enviarVerificacion(u); err != nil {
+		fmt.Printf("❌ Error al enviar correo de verificación: %v\n", err)
+	}
+	http.Redirect(w, r, "/?registrado=1", http.StatusSeeOther)
+}
+
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	usuario, perfil := usuarioDe(r), perfilDe(r)
+	if usuario.GetSuscripcion() == nil {
 		http.Redirect(w, r, "/checkout", http.StatusSeeOther)
 		return
 	}
-	if perfilActual == nil {
+	if perfil == nil {
 		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
 		return
 	}
 
-	mapa := gestor.ObtenerTodo()
-	var lista []content.Contenible
-	for _, c := range mapa {
-		lista = append(lista, c)
+	v := r.URL.Query()
+	consulta := consultaCatalogoDe(v)
+	consulta.Visible = func(c content.Contenible) bool { return content.ApropiadoPara(c, perfil.GetClasificacionMaxima()) }
+	pagina, err := gestor.Consultar(consulta)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	tmpl.ExecuteTemplate(w, "dashboard.html", struct {
+	tipo := ""
+	if len(consulta.Tipos) == 1 {
+		tipo = consulta.Tipos[0]
+	}
+	renderizar(w, r, "dashboard.html", struct {
 		Usuario    *auth.Usuario
 		Perfil     *auth.Perfil
+		Tipo       string // resalta Series o Películas en la navegación
 		Contenidos []content.Contenible
-	}{usuarioActual, perfilActual, lista})
+		Catalogo   vistaCatalogo
+	}{usuario, perfil, tipo, pagina.Contenidos, nuevaVistaCatalogo("/dashboard", v, pagina)})
 }
 
-func handleCheckout(w http.ResponseWriter, r *http.Request) {
-	if usuarioActual == nil {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+// handleWatch inicia la reproducción respetando el control parental del perfil.
+func handleWatch(w http.ResponseWriter, r *http.Request) {
+	usuario, perfil := usuarioDe(r), perfilDe(r)
+	if usuario.GetSuscripcion() == nil {
+		http.Redirect(w, r, "/checkout", http.StatusSeeOther)
+		return
+	}
+	if perfil == nil {
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+	c, err := gestor.ObtenerPorID(r.URL.Query().Get("id"))
+	if err != nil {
+		http.NotFound(w, r)
 		return
 	}
-	tmpl.ExecuteTemplate(w, "checkout.html", nil)
+	v, err := playback.IniciarReproduccion(fmt.Sprintf("V-%d-%d", perfil.GetID(), time.Now().UnixNano()), c, perfil.GetClasificacionMaxima())
+	if errors.Is(err, playback.ErrContenidoRestringido) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	renderizar(w, r, "watch.html", struct {
+		Perfil    *auth.Perfil
+		Contenido content.Contenible
+	}{perfil, v.GetContenido()})
+}
+
+func handleCheckout(w http.ResponseWriter, r *http.Request) {
+	renderCheckout(w, r, "")
+}
+
+func renderCheckout(w http.ResponseWriter, r *http.Request, msgError string) {
+	renderizar(w, r, "checkout.html", struct {
+		Verificado bool
+		Reenviado  bool
+		Error      string
+	}{usuarioDe(r).EmailVerificado(), r.URL.Query().Get("reenviado") == "1", msgError})
 }
 
 func handleBuyPlan(w http.ResponseWriter, r *http.Request) {
-	p := planesDisponibles[r.FormValue("plan_id")]
-	s := billing.NuevaSuscripcion("S-"+usuarioActual.GetID(), p)
-	dbStore.SaveSubscription(usuarioActual.GetID(), s)
-	usuarioActual.AsignarSuscripcion(s)
+	if !soloPost(w, r) {
+		return
+	}
+	usuario := usuarioDe(r)
+	// Solo las cuentas con el correo confirmado pueden contratar un plan.
+	if !usuario.EmailVerificado() {
+		http.Redirect(w, r, "/checkout", http.StatusSeeOther)
+		return
+	}
+	p, ok := planesDisponibles[r.PostFormValue("plan_id")]
+	if !ok {
+		http.Redirect(w, r, "/checkout", http.StatusSeeOther)
+		return
+	}
+	s := billing.NuevaSuscripcion("S-"+usuario.GetID(), p)
+	if err := dbStore.SaveSubscription(usuario.GetID(), s); err != nil {
+		fmt.Printf("❌ Error al guardar la suscripción: %v\n", err)
+		renderCheckout(w, r, "No se pudo contratar el plan, inténtalo de nuevo")
+		return
+	}
 	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 }
 
 func handleAdmin(w http.ResponseWriter, r *http.Request) {
-	if usuarioActual == nil || usuarioActual.GetCorreo() != "admin@stream.com" {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+	renderAdmin(w, r, nil)
+}
+
+// renderAdmin muestra el panel; errores son los problemas del último
+// formulario del catálogo, si no se pudo aplicar.
+func renderAdmin(w http.ResponseWriter, r *http.Request, errores []string) {
+	usuario := usuarioDe(r)
+	// Tras un formulario fallido la URL es la del POST, sin filtros: se
+	// muestra la primera página.
+	v := r.URL.Query()
+	pagina, err := gestor.Consultar(consultaCatalogoDe(v))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	mapa := gestor.ObtenerTodo()
-	var lista []content.Contenible
-	for _, c := range mapa {
-		lista = append(lista, c)
+	obligatorios, _ := dbStore.LoadMFARequiredRoles()
+	var politicaMFA []politicaMFARol
+	for _, rol := range auth.RolesDisponibles() {
+		politicaMFA = append(politicaMFA, politicaMFARol{rol, slices.Contains(obligatorios, rol)})
 	}
-	tmpl.ExecuteTemplate(w, "admin.html", lista)
+	if errores != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	renderizar(w, r, "admin.html", struct {
+		Contenidos            []content.Contenible
+		Catalogo              vistaCatalogo
+		Errores               []string
+		PuedeEditar           bool
+		PuedeVerFacturacion   bool
+		PuedeGestionarRoles   bool
+		PuedeGestionarCuentas bool
+		PuedeVerAuditoria     bool
+		PuedeGestionarClaves  bool
+		PuedeSuplantar        bool
+		Roles                 []auth.Rol
+		PoliticaMFA           []politicaMFARol
+		Clasificaciones       []content.Clasificacion
+	}{
+		pagina.Contenidos,
+		nuevaVistaCatalogo("/admin", v, pagina),
+		errores,
+		usuario.TienePermiso(auth.PermisoGestionarCatalogo),
+		usuario.TienePermiso(auth.PermisoVerFacturacion),
+		usuario.TienePermiso(auth.PermisoGestionarRoles),
+		usuario.TienePermiso(auth.PermisoGestionarCuentas),
+		usuario.TienePermiso(auth.PermisoVerAuditoria),
+		usuario.TienePermiso(auth.PermisoGestionarClaves),
+		usuario.TienePermiso(auth.PermisoSuplantar),
+		auth.RolesDisponibles(),
+		politicaMFA,
+		content.ClasificacionesDisponibles(),
+	})
 }
 
-func handleAdminAdd(w http.ResponseWriter, r *http.Request) {
-	n := content.NuevaPelicula(r.FormValue("id"), r.FormValue("titulo"), r.FormValue("descripcion"), "N/A", "N/A", "N/A", 0)
-	gestor.InsertarContenido(n)
-	err := dbStore.SaveContent(n)
+// clasificacionDeFormulario lee el campo "clasificacion" del panel admin; -1
+// si falta o no es un número, para que content.Validar lo rechace.
+func clasificacionDeFormulario(r *http.Request) int {
+	edad, err := strconv.Atoi(r.PostFormValue("clasificacion"))
 	if err != nil {
+		return -1
+	}
+	return edad
+}
+
+// contenidoDeFormulario construye el contenido del tipo indicado con los
+// campos del formulario del panel. No valida: ver content.Validar.
+func contenidoDeFormulario(r *http.Request, tipo string) content.Contenible {
+	id, titulo, descripcion := strings.TrimSpace(r.PostFormValue("id")), r.PostFormValue("titulo"), r.PostFormValue("descripcion")
+	edad := clasificacionDeFormulario(r)
+	duracion, _ := strconv.ParseFloat(r.PostFormValue("duracion"), 32)
+	var c content.Contenible
+	switch tipo {
+	case content.TipoDocumental:
+		c = content.NuevoDocumental(id, strings.TrimSpace(titulo), strings.TrimSpace(descripcion), strings.TrimSpace(r.PostFormValue("generos")),
+			strings.TrimSpace(r.PostFormValue("tema")), strings.TrimSpace(r.PostFormValue("narrador")), float32(duracion), edad)
+	case content.TipoSerie:
+		temporadas, _ := strconv.Atoi(r.PostFormValue("temporadas"))
+		c = content.NuevaSerie(id, strings.TrimSpace(titulo), strings.TrimSpace(descripcion), strings.TrimSpace(r.PostFormValue("generos")),
+			min(max(temporadas, 1), 50), edad)
+	default:
+		anio, _ := strconv.Atoi(r.PostFormValue("anio"))
+		c = content.NuevaPelicula(id, titulo, descripcion, content.SepararGeneros(r.PostFormValue("generos")), r.PostFormValue("director"),
+			r.PostFormValue("trailer"), float32(duracion), anio, r.PostFormValue("idioma"), edad)
+	}
+	content.MarcarEstreno(c, r.PostFormValue("estreno") == "1")
+	return c
+}
+
+// mensajesError separa los errores unidos con errors.Join para listarlos en el panel.
+func mensajesError(err error) []string {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		var res []string
+		for _, e := range j.Unwrap() {
+			res = append(res, mensajesError(e)...)
+		}
+		return res
+	}
+	return []string{err.Error()}
+}
+
+func handleAdminAdd(w http.ResponseWriter, r *http.Request) {
+	if !soloPost(w, r) {
+		return
+	}
+	n := contenidoDeFormulario(r, r.PostFormValue("tipo"))
+	if _, err := gestor.ObtenerPorID(n.GetID()); err == nil {
+		renderAdmin(w, r, []string{"Ya existe un contenido con el ID " + n.GetID()})
+		return
+	}
+	if err := content.Validar(n); err != nil {
+		renderAdmin(w, r, mensajesError(err))
+		return
+	}
+	if err := dbStore.SaveContent(n); err != nil {
 		fmt.Printf("❌ Error al guardar en DB: %v\n", err)
+		renderAdmin(w, r, []string{"No se pudo guardar el contenido en la base de datos"})
+		return
 	}
+	gestor.InsertarContenido(n)
+	auditarPeticion(r, auth.AccionContenidoCreado, n.GetID(), n.GetTitulo())
 	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
 
+// handleAdminUpdate reemplaza los metadatos del contenido. Las temporadas de
+// una serie y los episodios de un documental se conservan.
 func handleAdminUpdate(w http.ResponseWriter, r *http.Request) {
-	id := r.FormValue("id")
-	t := r.FormValue("titulo")
-	d := r.FormValue("descripcion")
-	if err := gestor.ActualizarContenidoMetadata(id, t); err == nil {
-		dbStore.UpdateContentFull(id, t, d)
+	if !soloPost(w, r) {
+		return
+	}
+	actual, err := gestor.ObtenerPorID(r.PostFormValue("id"))
+	if err != nil {
+		renderAdmin(w, r, []string{err.Error()})
+		return
 	}
+	id, edad := actual.GetID(), clasificacionDeFormulario(r)
+	t, d := strings.TrimSpace(r.PostFormValue("titulo")), strings.TrimSpace(r.PostFormValue("descripcion"))
+
+	if _, ok := actual.(*content.Serie); ok {
+		if err := content.ValidarComunes(id, t, d, edad); err != nil {
+			renderAdmin(w, r, mensajesError(err))
+			return
+		}
+		// La copia solo se publica si se guardó en la base de datos.
+		estreno := r.PostFormValue("estreno") == "1"
+		err = gestor.Actualizar(id, func(serie content.Contenible) error {
+			content.EditarComunes(serie, t, d, edad)
+			content.MarcarEstreno(serie, estreno)
+			return dbStore.UpdateContentFull(serie)
+		})
+	} else {
+		nuevo := contenidoDeFormulario(r, content.TipoDe(actual))
+		if err := content.Validar(nuevo); err != nil {
+			renderAdmin(w, r, mensajesError(err))
+			return
+		}
+		// Los episodios del documental se toman de la versión vigente al
+		// guardar, no de la leída antes, para no perder otra escritura.
+		err = gestor.Actualizar(id, func(c content.Contenible) error {
+			doc, esDoc := c.(*content.Documental)
+			nuevoDoc, nuevoEsDoc := nuevo.(*content.Documental)
+			if esDoc && nuevoEsDoc {
+				for _, ep := range doc.ObtenerEpisodios() {
+					nuevoDoc.AgregarEpisodio(ep.GetTitulo(), ep.GetDuracion())
+				}
+			}
+			if err := content.ReemplazarDatos(c, nuevo); err != nil {
+				return err
+			}
+			return dbStore.UpdateContentFull(c)
+		})
+	}
+	if errors.Is(err, content.ErrContenidoNoEncontrado) || errors.Is(err, content.ErrCambioDeTipo) {
+		renderAdmin(w, r, []string{err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error al actualizar el contenido %s: %v\n", id, err)
+		renderAdmin(w, r, []string{"No se pudo guardar el contenido en la base de datos"})
+		return
+	}
+	auditarPeticion(r, auth.AccionContenidoActualizado, id, fmt.Sprintf("título %q, clasificación %d", t, edad))
 	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
 
 func handleAdminDelete(w http.ResponseWriter, r *http.Request) {
-	id := r.FormValue("id")
+	if !soloPost(w, r) {
+		return
+	}
+	id := r.PostFormValue("id")
+	if _, err := gestor.ObtenerPorID(id); err != nil {
+		renderAdmin(w, r, []string{err.Error()})
+		return
+	}
+	if err := dbStore.DeleteContent(id); err != nil {
+		fmt.Printf("❌ Error al borrar el contenido %s: %v\n", id, err)
+		renderAdmin(w, r, []string{"No se pudo borrar el contenido de la base de datos"})
+		return
+	}
 	gestor.BorrarContenido(id)
-	dbStore.DeleteContent(id)
+	auditarPeticion(r, auth.AccionContenidoEliminado, id, "")
 	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
 
+// handleLogout cierra la sesión. Es POST con el token de la sesión para que
+// un enlace desde otro sitio no pueda cerrarla.
 func handleLogout(w http.ResponseWriter, r *http.Request) {
-	usuarioActual = nil
-	perfilActual = nil
+	if !soloPost(w, r) {
+		return
+	}
+	if s, err := sesiones.Resolver(r); err == nil {
+		if !csrfValido(r, sesiones.TokenCSRF(s)) {
+			rechazarCSRF(w, r)
+			return
+		}
+		if u, err := dbStore.LoadUserByID(s.GetUsuarioID()); err == nil {
+			auditar(u.GetCorreo(), auth.AccionLogout, u.GetID(), ipCliente(r), "")
+		}
+	}
+	sesiones.Cerrar(w, r)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"streaming-system/pkg/auth"
+	"time"
+)
+
+// errMFAYaActivo impide generar un secreto nuevo sobre uno ya confirmado.
+var errMFAYaActivo = errors.New("la verificación en dos pasos ya está activa")
+
+// datosMFA alimenta mfa.html en sus distintos modos:
+// "verificar", "inscribir", "codigos" y "estado".
+type datosMFA struct {
+	Modo        string
+	Accion      string
+	URI         string
+	Error       string
+	Codigos     []string
+	Activo      bool
+	Obligatorio bool
+}
+
+// politicaMFARol es una fila de la política MFA del panel admin.
+type politicaMFARol struct {
+	Rol         auth.Rol
+	Obligatorio bool
+}
+
+// necesitaSegundoFactor indica si el inicio de sesión debe pasar por /login/mfa:
+// el usuario tiene TOTP activo o alguno de sus roles lo exige.
+func necesitaSegundoFactor(u *auth.Usuario) (bool, error) {
+	cfg, err := dbStore.LoadMFA(u.GetID())
+	if err != nil {
+		return false, err
+	}
+	if cfg.EstaActivo() {
+		return true, nil
+	}
+	return mfaObligatorioPara(u)
+}
+
+func mfaObligatorioPara(u *auth.Usuario) (bool, error) {
+	roles, err := dbStore.LoadMFARequiredRoles()
+	if err != nil {
+		return false, err
+	}
+	return auth.MFAObligatorio(u, roles), nil
+}
+
+// verificarSegundoFactor acepta un código TOTP o un código de recuperación.
+// Los fallos cuentan en el limitador bajo la clave MFA del usuario.
+func verificarSegundoFactor(u *auth.Usuario, codigo string) error {
+	clave := auth.ClaveMFA(u.GetID())
+	if err := verificarLimite(clave); err != nil {
+		return err
+	}
+	cfg, err := dbStore.LoadMFA(u.GetID())
+	if err != nil {
+		return err
+	}
+	if !cfg.EstaActivo() {
+		return auth.ErrCodigoMFAInvalido
+	}
+	if paso, err := cfg.VerificarCodigo(codigo, time.Now()); err == nil {
+		err = dbStore.UpdateMFALastStep(u.GetID(), paso)
+		if err == nil {
+			limitador.Reiniciar(clave)
+		}
+		return err
+	}
+	err = dbStore.ConsumeRecoveryCode(u.GetID(), auth.HashCodigoRecuperacion(codigo))
+	if errors.Is(err, auth.ErrCodigoMFAInvalido) {
+		limitador.RegistrarFallo(clave)
+	} else if err == nil {
+		limitador.Reiniciar(clave)
+	}
+	return err
+}
+
+// configPendiente devuelve el secreto aún sin confirmar del usuario o genera uno.
+func configPendiente(u *auth.Usuario) (*auth.ConfigMFA, error) {
+	cfg, err := dbStore.LoadMFA(u.GetID())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.EstaActivo() {
+		return nil, errMFAYaActivo
+	}
+	if cfg != nil {
+		return cfg, nil
+	}
+	cfg = auth.NuevaConfigMFA(u.GetID())
+	return cfg, dbStore.SaveMFA(cfg)
+}
+
+// confirmarInscripcion activa el secreto pendiente con el primer código válido
+// y devuelve los códigos de recuperación, que solo se muestran esta vez.
+func confirmarInscripcion(u *auth.Usuario, codigo string) ([]string, error) {
+	cfg, err := configPendiente(u)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cfg.VerificarCodigo(codigo, time.Now()); err != nil {
+		return nil, err
+	}
+	cfg.Activar()
+	if err := dbStore.SaveMFA(cfg); err != nil {
+		return nil, err
+	}
+	return regenerarCodigosRecuperacion(u)
+}
+
+func regenerarCodigosRecuperacion(u *auth.Usuario) ([]string, error) {
+	planos, hashes := auth.GenerarCodigosRecuperacion(10)
+	return planos, dbStore.SaveRecoveryCodes(u.GetID(), hashes)
+}
+
+// mensajeMFA traduce errores internos a un mensaje apto para el usuario.
+func mensajeMFA(err error) string {
+	if errors.Is(err, auth.ErrCodigoMFAInvalido) || errors.Is(err, errMFAYaActivo) || errors.Is(err, auth.ErrDemasiadosIntentos) {
+		return err.Error()
+	}
+	fmt.Printf("❌ Error de MFA: %v\n", err)
+	return "No se pudo completar la verificación, inténtalo de nuevo"
+}
+
+// handleLoginMFA completa el inicio de sesión pidiendo el segundo factor, o
+// fuerza la inscripción si el rol del usuario lo exige y aún no lo tiene.
+func handleLoginMFA(w http.ResponseWriter, r *http.Request) {
+	u, s := usuarioDe(r), sesionDe(r)
+	if !s.MFAPendiente() {
+		redirigirTrasLogin(w, r, u)
+		return
+	}
+	completar := func() {
+		s.CompletarMFA()
+		if err := sesiones.Guardar(s); err != nil {
+			fmt.Printf("❌ Error al guardar la sesión: %v\n", err)
+		}
+	}
+
+	cfg, err := dbStore.LoadMFA(u.GetID())
+	if err != nil {
+		http.Error(w, mensajeMFA(err), http.StatusInternalServerError)
+		return
+	}
+	if cfg.EstaActivo() {
+		datos := datosMFA{Modo: "verificar", Accion: "/login/mfa"}
+		if r.Method == http.MethodPost {
+			if err := verificarSegundoFactor(u, r.FormValue("codigo")); err != nil {
+				if errors.Is(err, auth.ErrCodigoMFAInvalido) {
+					auditarPeticion(r, auth.AccionLoginFallido, u.GetID(), "código de verificación incorrecto")
+				}
+				datos.Error = mensajeMFA(err)
+			} else {
+				completar()
+				auditarPeticion(r, auth.AccionMFAVerificado, u.GetID(), "")
+				redirigirTrasLogin(w, r, u)
+				return
+			}
+		}
+		renderizar(w, r, "mfa.html", datos)
+		return
+	}
+
+	// Inscripción obligatoria por rol.
+	datos := datosMFA{Modo: "inscribir", Accion: "/login/mfa", Obligatorio: true}
+	if r.Method == http.MethodPost {
+		codigos, err := confirmarInscripcion(u, r.FormValue("codigo"))
+		if err == nil {
+			completar()
+			auditarPeticion(r, auth.AccionMFAActivado, u.GetID(), "inscripción exigida por el rol")
+			renderizar(w, r, "mfa.html", datosMFA{Modo: "codigos", Codigos: codigos, Activo: true})
+			return
+		}
+		datos.Error = mensajeMFA(err)
+	}
+	if cfg, err = configPendiente(u); err != nil {
+		http.Error(w, mensajeMFA(err), http.StatusInternalServerError)
+		return
+	}
+	datos.URI = cfg.URI(u.GetCorreo())
+	renderizar(w, r, "mfa.html", datos)
+}
+
+// handleAccountMFA permite activar, desactivar y regenerar códigos de recuperación.
+func handleAccountMFA(w http.ResponseWriter, r *http.Request) {
+	u := usuarioDe(r)
+	obligatorio, err := mfaObligatorioPara(u)
+	if err != nil {
+		http.Error(w, mensajeMFA(err), http.StatusInternalServerError)
+		return
+	}
+	cfg, err := dbStore.LoadMFA(u.GetID())
+	if err != nil {
+		http.Error(w, mensajeMFA(err), http.StatusInternalServerError)
+		return
+	}
+	datos := datosMFA{Modo: "estado", Accion: "/account/mfa", Activo: cfg.EstaActivo(), Obligatorio: obligatorio}
+
+	if r.Method == http.MethodPost {
+		switch r.FormValue("accion") {
+		case "iniciar":
+			cfg, err := configPendiente(u)
+			if err != nil {
+				datos.Error = mensajeMFA(err)
+				break
+			}
+			datos.Modo, datos.URI = "inscribir", cfg.URI(u.GetCorreo())
+		case "confirmar":
+			codigos, err := confirmarInscripcion(u, r.FormValue("codigo"))
+			if err != nil {
+				datos.Modo, datos.Error = "inscribir", mensajeMFA(err)
+				if cfg, err := configPendiente(u); err == nil {
+					datos.URI = cfg.URI(u.GetCorreo())
+				}
+				break
+			}
+			datos.Modo, datos.Codigos, datos.Activo = "codigos", codigos, true
+			auditarPeticion(r, auth.AccionMFAActivado, u.GetID(), "")
+		case "codigos":
+			if err := verificarSegundoFactor(u, r.FormValue("codigo")); err != nil {
+				datos.Error = mensajeMFA(err)
+				break
+			}
+			codigos, err := regenerarCodigosRecuperacion(u)
+			if err != nil {
+				datos.Error = mensajeMFA(err)
+				break
+			}
+			datos.Modo, datos.Codigos = "codigos", codigos
+			auditarPeticion(r, auth.AccionCodigosRecuperacion, u.GetID(), "")
+		case "desactivar":
+			if ok, _ := auth.VerificarContrasenia(u.GetContraseniaHash(), r.FormValue("password")); !ok {
+				datos.Error = auth.ErrCredencialesInvalidas.Error()
+				break
+			}
+			if obligatorio {
+				datos.Error = "Tu rol exige la verificación en dos pasos"
+				break
+			}
+			if err := dbStore.DeleteMFA(u.GetID()); err != nil {
+				datos.Error = mensajeMFA(err)
+				break
+			}
+			datos.Activo = false
+			auditarPeticion(r, auth.AccionMFADesactivado, u.GetID(), "")
+		}
+	}
+	renderizar(w, r, "mfa.html", datos)
+}
+
+// handleMFAQR sirve el QR del secreto pendiente de confirmar. Un secreto ya
+// activo no se vuelve a exponer.
+func handleMFAQR(w http.ResponseWriter, r *http.Request) {
+	u := usuarioDe(r)
+	cfg, err := dbStore.LoadMFA(u.GetID())
+	if err != nil || cfg == nil || cfg.EstaActivo() {
+		http.NotFound(w, r)
+		return
+	}
+	png, err := cfg.QRPNG(u.GetCorreo(), 256)
+	if err != nil {
+		http.Error(w, "No se pudo generar el código QR", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
+
+// handleAdminMFA activa o desactiva la exigencia de MFA para un rol.
+func handleAdminMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if rol := auth.Rol(r.FormValue("rol")); auth.EsRolValido(rol) {
+			obligatorio := r.FormValue("obligatorio") == "1"
+			if err := dbStore.SetMFARequiredForRole(rol, obligatorio); err != nil {
+				fmt.Printf("❌ Error al guardar la política MFA: %v\n", err)
+			} else {
+				auditarPeticion(r, auth.AccionPoliticaMFACambiada, string(rol), fmt.Sprintf("obligatorio=%t", obligatorio))
+			}
+		}
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// --- API JSON ---
+
+func handleAPIMFAEnroll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorJSON(w, http.StatusMethodNotAllowed, "método no permitido")
+		return
+	}
+	u := usuarioDe(r)
+	cfg, err := configPendiente(u)
+	if errors.Is(err, errMFAYaActivo) {
+		errorJSON(w, http.StatusConflict, err.Error())
+		return
+	}
+	var png []byte
+	if err == nil {
+		png, err = cfg.QRPNG(u.GetCorreo(), 256)
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, mensajeMFA(err))
+		return
+	}
+	responderJSON(w, http.StatusOK, map[string]string{
+		"otpauth_uri": cfg.URI(u.GetCorreo()),
+		"qr_png":      base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+func handleAPIMFAConfirm(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !leerJSON(w, r, &req) {
+		return
+	}
+	u := usuarioDe(r)
+	codigos, err := confirmarInscripcion(u, req.Code)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrCodigoMFAInvalido) || errors.Is(err, errMFAYaActivo) {
+			status = http.StatusBadRequest
+		}
+		errorJSON(w, status, mensajeMFA(err))
+		return
+	}
+	auditarPeticion(r, auth.AccionMFAActivado, u.GetID(), "api")
+	responderJSON(w, http.StatusOK, map[string][]string{"recovery_codes": codigos})
+}
@@ -0,0 +1,220 @@
+// Command oidc-mock es un proveedor OpenID Connect mínimo para desarrollo:
+// sirve descubrimiento, JWKS, autorización con PKCE y token endpoint, y emite
+// id_tokens RS256 para el correo que se escriba en su formulario.
+//
+//	go run ./cmd/oidc-mock -addr :9000
+//	STREAMGO_OIDC_ISSUER=http://localhost:9000 STREAMGO_OIDC_CLIENT_ID=streamgo go run ./cmd
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// codigoEmitido es lo que el token endpoint necesita para canjear un código.
+type codigoEmitido struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	desafio     string
+	email       string
+	verificado  bool
+	nombre      string
+	subject     string
+	expira      time.Time
+}
+
+type proveedor struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	clave        *rsa.PrivateKey
+	kid          string
+
+	mu      sync.Mutex
+	codigos map[string]codigoEmitido
+}
+
+var formulario = template.Must(template.New("autorizar").Parse(`<!DOCTYPE html>
+<html lang="es"><head><meta charset="UTF-8"><title>Proveedor OIDC de prueba</title></head>
+<body style="font-family:sans-serif;max-width:28rem;margin:4rem auto">
+<h1>Proveedor OIDC de prueba</h1>
+<p>Cliente: <code>{{.ClientID}}</code></p>
+<form method="POST">
+  {{range $k, $v := .Params}}{{range $v}}<input type="hidden" name="{{$k}}" value="{{.}}">{{end}}{{end}}
+  <p><label>Correo <input type="email" name="email" required value="{{.Email}}"></label></p>
+  <p><label>Nombre <input type="text" name="name" value="Prueba"></label></p>
+  <p><label><input type="checkbox" name="email_verified" value="1" checked> Correo verificado</label></p>
+  <p><button name="accion" value="aceptar">Iniciar sesión</button> <button name="accion" value="denegar">Denegar</button></p>
+</form>
+</body></html>`))
+
+func main() {
+	addr := flag.String("addr", ":9000", "dirección de escucha")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer anunciado (debe coincidir con STREAMGO_OIDC_ISSUER)")
+	clientID := flag.String("client-id", "streamgo", "client_id aceptado")
+	clientSecret := flag.String("client-secret", "", "client_secret exigido; vacío para un cliente público")
+	flag.Parse()
+
+	clave, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	p := &proveedor{issuer: *issuer, clientID: *clientID, clientSecret: *clientSecret, clave: clave,
+		kid: fmt.Sprintf("mock-%d", time.Now().Unix()), codigos: make(map[string]codigoEmitido)}
+
+	http.HandleFunc("/.well-known/openid-configuration", p.handleDescubrimiento)
+	http.HandleFunc("/jwks", p.handleJWKS)
+	http.HandleFunc("/authorize", p.handleAutorizar)
+	http.HandleFunc("/token", p.handleToken)
+
+	fmt.Printf("🧪 Proveedor OIDC de prueba en %s (issuer %s, client_id %s)\n", *addr, *issuer, *clientID)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+}
+
+func (p *proveedor) handleDescubrimiento(w http.ResponseWriter, r *http.Request) {
+	responder(w, http.StatusOK, map[string]any{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	})
+}
+
+func (p *proveedor) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := p.clave.PublicKey
+	responder(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA", "use": "sig", "alg": "RS256", "kid": p.kid,
+		"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+// handleAutorizar muestra el formulario (GET) y emite el código (POST).
+func (p *proveedor) handleAutorizar(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	q := r.Form
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" || q.Get("client_id") != p.clientID {
+		http.Error(w, "client_id o redirect_uri inválidos", http.StatusBadRequest)
+		return
+	}
+	volver := func(params url.Values) {
+		params.Set("state", q.Get("state"))
+		redirect.RawQuery = params.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		volver(url.Values{"error": {"invalid_request"}, "error_description": {"se requiere code + PKCE S256"}})
+		return
+	}
+	if r.Method != http.MethodPost {
+		params := url.Values{}
+		for _, k := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			params.Set(k, q.Get(k))
+		}
+		formulario.Execute(w, map[string]any{"ClientID": p.clientID, "Params": params, "Email": "usuario@example.com"})
+		return
+	}
+	if q.Get("accion") == "denegar" {
+		volver(url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	// El sub se deriva del correo para que repetir el login dé la misma identidad.
+	h := sha256.Sum256([]byte(q.Get("email")))
+	codigo := aleatorio()
+	p.mu.Lock()
+	p.codigos[codigo] = codigoEmitido{
+		clientID: p.clientID, redirectURI: q.Get("redirect_uri"), nonce: q.Get("nonce"), desafio: q.Get("code_challenge"),
+		email: q.Get("email"), verificado: q.Get("email_verified") == "1", nombre: q.Get("name"),
+		subject: hex.EncodeToString(h[:12]), expira: time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+	volver(url.Values{"code": {codigo}})
+}
+
+// handleToken canjea un código de un solo uso comprobando cliente, redirect_uri y PKCE.
+func (p *proveedor) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		errorOAuth(w, "invalid_request")
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.clientID || subtle.ConstantTimeCompare([]byte(secret), []byte(p.clientSecret)) != 1 {
+		responder(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	c, existe := p.codigos[r.PostForm.Get("code")]
+	delete(p.codigos, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	h := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !existe || time.Now().After(c.expira) || c.clientID != clientID || c.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(h[:]) != c.desafio {
+		errorOAuth(w, "invalid_grant")
+		return
+	}
+
+	ahora := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": p.issuer, "sub": c.subject, "aud": clientID,
+		"iat": ahora.Unix(), "exp": ahora.Add(5 * time.Minute).Unix(),
+		"nonce": c.nonce, "email": c.email, "email_verified": c.verificado, "name": c.nombre,
+	})
+	t.Header["kid"] = p.kid
+	idToken, err := t.SignedString(p.clave)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	responder(w, http.StatusOK, map[string]any{
+		"access_token": aleatorio(), "token_type": "Bearer", "expires_in": 300, "id_token": idToken,
+	})
+}
+
+func aleatorio() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func errorOAuth(w http.ResponseWriter, codigo string) {
+	responder(w, http.StatusBadRequest, map[string]string{"error": codigo})
+}
+
+func responder(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"streaming-system/pkg/auth"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// cookieOIDC guarda la solicitud OIDC en curso (state, nonce y verificador PKCE).
+const cookieOIDC = "streamgo_oidc"
+
+// errCuentaLocalSinVerificar impide vincular una identidad externa a una cuenta
+// cuyo correo nadie confirmó: podría haberla registrado un tercero.
+var errCuentaLocalSinVerificar = errors.New("ya existe una cuenta con ese correo sin confirmar; confírmalo o entra con tu contraseña")
+
+// ProveedorOIDC es el nombre del proveedor para el botón de login.html, o ""
+// si no hay proveedor configurado.
+func (datosLogin) ProveedorOIDC() string {
+	if oidc == nil {
+		return ""
+	}
+	return oidc.GetNombre()
+}
+
+// nuevoClienteOIDC configura el relying party a partir del entorno. Sin
+// STREAMGO_OIDC_ISSUER el inicio de sesión externo queda desactivado.
+func nuevoClienteOIDC() *auth.ClienteOIDC {
+	issuer := os.Getenv("STREAMGO_OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+	cfg := auth.ConfigOIDC{
+		Nombre:       os.Getenv("STREAMGO_OIDC_NOMBRE"),
+		Issuer:       issuer,
+		ClientID:     os.Getenv("STREAMGO_OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("STREAMGO_OIDC_CLIENT_SECRET"),
+		RedirectURL:  urlBase() + "/login/oidc/callback",
+	}
+	if cfg.Nombre == "" {
+		cfg.Nombre = "tu empresa"
+	}
+	if s := os.Getenv("STREAMGO_OIDC_SCOPES"); s != "" {
+		cfg.Scopes = strings.Fields(s)
+	}
+	// La clave solo firma la cookie de una solicitud de 10 minutos: si se
+	// pierde en un reinicio basta con volver a pulsar el botón.
+	clave := []byte(os.Getenv("STREAMGO_CLAVE_SESION"))
+	if len(clave) == 0 {
+		clave = make([]byte, 32)
+		rand.Read(clave)
+	}
+	fmt.Printf("🔑 Inicio de sesión OIDC habilitado con %s\n", cfg.Issuer)
+	return auth.NuevoClienteOIDC(cfg, clave)
+}
+
+// mensajeOIDC traduce errores del flujo externo para login.html.
+func mensajeOIDC(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrSolicitudOIDCInvalida), errors.Is(err, auth.ErrEmailExternoNoVerificado),
+		errors.Is(err, auth.ErrEmailInvalido), errors.Is(err, errCuentaLocalSinVerificar):
+		return err.Error()
+	}
+	fmt.Printf("❌ Error de inicio de sesión OIDC: %v\n", err)
+	return "No se pudo iniciar sesión con el proveedor externo, inténtalo de nuevo"
+}
+
+// handleLoginOIDC redirige al proveedor con una solicitud nueva.
+func handleLoginOIDC(w http.ResponseWriter, r *http.Request) {
+	if oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+	sol := auth.NuevaSolicitudOIDC(time.Now())
+	destino, err := oidc.URLAutorizacion(r.Context(), sol)
+	if err != nil {
+		renderizar(w, r, "login.html", datosLogin{Error: mensajeOIDC(err)})
+		return
+	}
+	// SameSite=Lax: la cookie tiene que viajar en la redirección de vuelta desde el proveedor.
+	http.SetCookie(w, &http.Cookie{
+		Name: cookieOIDC, Value: oidc.SellarSolicitud(sol), Path: "/login/oidc",
+		MaxAge: int(auth.VigenciaSolicitudOIDC.Seconds()), HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, destino, http.StatusFound)
+}
+
+// handleOIDCCallback valida la vuelta del proveedor e inicia la sesión local.
+func handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: cookieOIDC, Path: "/login/oidc", MaxAge: -1, HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteLaxMode})
+
+	q := r.URL.Query()
+	u, err := func() (*auth.Usuario, error) {
+		c, err := r.Cookie(cookieOIDC)
+		if err != nil {
+			return nil, auth.ErrSolicitudOIDCInvalida
+		}
+		sol, err := oidc.AbrirSolicitud(c.Value, q.Get("state"), time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if e := q.Get("error"); e != "" {
+			return nil, fmt.Errorf("%w: %s %s", auth.ErrProveedorOIDC, e, q.Get("error_description"))
+		}
+		id, err := oidc.Canjear(r.Context(), q.Get("code"), sol)
+		if err != nil {
+			return nil, err
+		}
+		return usuarioParaIdentidad(dbStore, id, ipCliente(r))
+	}()
+	if err != nil {
+		renderizar(w, r, "login.html", datosLogin{Error: mensajeOIDC(err)})
+		return
+	}
+	u.IniciarSesionExterna()
+	iniciarSesionWeb(w, r, u, "oidc")
+}
+
+// almacenIdentidades es lo que usuarioParaIdentidad necesita de dbStore.
+type almacenIdentidades interface {
+	LoadUserByExternalIdentity(issuer, subject string) (*auth.Usuario, error)
+	LoadUserByEmail(email string) (*auth.Usuario, error)
+	SaveUser(u *auth.Usuario) error
+	LinkExternalIdentity(uid string, id *auth.IdentidadOIDC) error
+}
+
+// usuarioParaIdentidad resuelve el usuario local de una identidad externa:
+// primero por el vínculo (issuer, sub); si no existe, por correo verificado en
+// ambos lados, creando la cuenta si no hay ninguna con ese correo.
+func usuarioParaIdentidad(almacen almacenIdentidades, id *auth.IdentidadOIDC, ip string) (*auth.Usuario, error) {
+	u, err := almacen.LoadUserByExternalIdentity(id.Issuer, id.Subject)
+	if err != nil || u != nil {
+		return u, err
+	}
+	if !id.EmailVerificado {
+		return nil, auth.ErrEmailExternoNoVerificado
+	}
+	if err := auth.ValidarEmail(id.Email); err != nil {
+		return nil, err
+	}
+
+	u, err = almacen.LoadUserByEmail(id.Email)
+	switch {
+	case err == nil:
+		if !u.EmailVerificado() {
+			return nil, errCuentaLocalSinVerificar
+		}
+	case errors.Is(err, auth.ErrUsuarioNoEncontrado):
+		if u, err = crearUsuarioExterno(almacen, id); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+	if err := almacen.LinkExternalIdentity(u.GetID(), id); err != nil {
+		return nil, err
+	}
+	fmt.Printf("🔗 Identidad %s de %s vinculada al usuario %s\n", id.Subject, id.Issuer, u.GetID())
+	auditar(u.GetCorreo(), auth.AccionIdentidadVinculada, u.GetID(), ip, id.Issuer+" "+id.Subject)
+	return u, nil
+}
+
+// crearUsuarioExterno da de alta una cuenta para una identidad externa nueva.
+// Lleva una contraseña aleatoria: para usarla, el usuario tendría que
+// restablecerla desde /password/forgot.
+func crearUsuarioExterno(almacen almacenIdentidades, id *auth.IdentidadOIDC) (*auth.Usuario, error) {
+	pass := make([]byte, 24)
+	rand.Read(pass)
+	u, err := auth.NuevoUsuario(auth.NuevoIDUsuario(), nombreCuenta(id), id.Email, hex.EncodeToString(pass))
+	if err != nil {
+		return nil, err
+	}
+	u.MarcarEmailVerificado()
+	return u, almacen.SaveUser(u)
+}
+
+// nombreCuenta elige el nombre visible ajustado a NOMBRE_USUARIO (10 caracteres).
+func nombreCuenta(id *auth.IdentidadOIDC) string {
+	nombre := strings.TrimSpace(id.Nombre)
+	if nombre == "" {
+		nombre, _, _ = strings.Cut(id.Email, "@")
+	}
+	for utf8.RuneCountInString(nombre) > 10 {
+		_, n := utf8.DecodeLastRuneInString(nombre)
+		nombre = nombre[:len(nombre)-n]
+	}
+	return nombre
+}
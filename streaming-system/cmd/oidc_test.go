@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"streaming-system/pkg/auth"
+	"testing"
+)
+
+// almacenIdentidadesMemoria sustituye a dbStore en las pruebas de usuarioParaIdentidad.
+type almacenIdentidadesMemoria struct {
+	porCorreo map[string]*auth.Usuario
+	vinculos  map[string]string // issuer + " " + sub -> USUARIO_ID
+	err       error             // si no es nil, lo devuelven todas las lecturas
+}
+
+func (m *almacenIdentidadesMemoria) LoadUserByExternalIdentity(issuer, subject string) (*auth.Usuario, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	uid, ok := m.vinculos[issuer+" "+subject]
+	if !ok {
+		return nil, nil
+	}
+	for _, u := range m.porCorreo {
+		if u.GetID() == uid {
+			return u, nil
+		}
+	}
+	return nil, auth.ErrUsuarioNoEncontrado
+}
+
+func (m *almacenIdentidadesMemoria) LoadUserByEmail(email string) (*auth.Usuario, error) {
+	if u, ok := m.porCorreo[email]; ok {
+		return u, nil
+	}
+	return nil, auth.ErrUsuarioNoEncontrado
+}
+
+func (m *almacenIdentidadesMemoria) SaveUser(u *auth.Usuario) error {
+	m.porCorreo[u.GetCorreo()] = u
+	return nil
+}
+
+func (m *almacenIdentidadesMemoria) LinkExternalIdentity(uid string, id *auth.IdentidadOIDC) error {
+	m.vinculos[id.Issuer+" "+id.Subject] = uid
+	return nil
+}
+
+func TestUsuarioParaIdentidad(t *testing.T) {
+	const issuer = "https://idp.example.com"
+	identidad := func(email string, verificado bool) *auth.IdentidadOIDC {
+		return &auth.IdentidadOIDC{Issuer: issuer, Subject: "sub-1", Email: email, EmailVerificado: verificado, Nombre: "Ana María López"}
+	}
+	casos := []struct {
+		nombre    string
+		id        *auth.IdentidadOIDC
+		vinculado bool // la identidad ya estaba vinculada al usuario 1
+		lectura   error
+		err       error
+		usuario   string // ID esperado; "nuevo" si se crea la cuenta
+		vincula   bool   // se registra un vínculo nuevo
+	}{
+		{nombre: "vínculo existente aunque el correo no esté verificado", id: identidad("otra@example.com", false), vinculado: true, usuario: "1"},
+		{nombre: "cuenta local verificada con el mismo correo", id: identidad("ana@example.com", true), usuario: "1", vincula: true},
+		{nombre: "cuenta local sin verificar", id: identidad("beto@example.com", true), err: errCuentaLocalSinVerificar},
+		{nombre: "correo externo sin verificar", id: identidad("ana@example.com", false), err: auth.ErrEmailExternoNoVerificado},
+		{nombre: "correo externo inválido", id: identidad("no-es-correo", true), err: auth.ErrEmailInvalido},
+		{nombre: "sin cuenta local", id: identidad("nueva@example.com", true), usuario: "nuevo", vincula: true},
+		{nombre: "error del almacén", id: identidad("ana@example.com", true), lectura: errors.New("sin conexión")},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			auditoria = auth.NuevoRegistroAuditoria(auth.NuevoAlmacenAuditoriaMemoria())
+			ana := auth.RecreateUsuarioFromDB("1", "ana", "ana@example.com", "", nil)
+			ana.MarcarEmailVerificado()
+			almacen := &almacenIdentidadesMemoria{
+				porCorreo: map[string]*auth.Usuario{
+					"ana@example.com":  ana,
+					"beto@example.com": auth.RecreateUsuarioFromDB("2", "beto", "beto@example.com", "", nil),
+				},
+				vinculos: map[string]string{},
+				err:      c.lectura,
+			}
+			if c.vinculado {
+				almacen.vinculos[issuer+" sub-1"] = "1"
+			}
+			antes := len(almacen.vinculos)
+			esperado := c.err
+			if c.lectura != nil {
+				esperado = c.lectura
+			}
+
+			u, err := usuarioParaIdentidad(almacen, c.id, "203.0.113.7")
+			if !errors.Is(err, esperado) {
+				t.Fatalf("error = %v, se esperaba %v", err, esperado)
+			}
+			eventos, _ := auditoria.Buscar(auth.FiltroAuditoria{Accion: auth.AccionIdentidadVinculada})
+			if c.vincula != (len(eventos) == 1) || c.vincula != (len(almacen.vinculos) == antes+1) {
+				t.Fatalf("vínculos %v, %d eventos de auditoría; se esperaba vincular: %v", almacen.vinculos, len(eventos), c.vincula)
+			}
+			if err != nil {
+				return
+			}
+			switch c.usuario {
+			case "nuevo":
+				if u.GetID() == "1" || u.GetID() == "2" || !u.EmailVerificado() || u.GetNombre() != "Ana María " || almacen.porCorreo[c.id.Email] != u {
+					t.Fatalf("cuenta creada %q %q, verificada %v", u.GetID(), u.GetNombre(), u.EmailVerificado())
+				}
+			default:
+				if u.GetID() != c.usuario {
+					t.Fatalf("usuario %s, se esperaba %s", u.GetID(), c.usuario)
+				}
+			}
+			if c.vincula && almacen.vinculos[issuer+" sub-1"] != u.GetID() {
+				t.Fatalf("vinculado a %q, se esperaba %s", almacen.vinculos[issuer+" sub-1"], u.GetID())
+			}
+		})
+	}
+}
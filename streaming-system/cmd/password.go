@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"streaming-system/pkg/auth"
+	"streaming-system/pkg/correo"
+)
+
+// nuevoMailer usa SMTP si STREAMGO_SMTP_HOST está definido; si no, escribe
+// los correos en la carpeta ./outbox para desarrollo local.
+func nuevoMailer() correo.Mailer {
+	remitente := os.Getenv("STREAMGO_SMTP_REMITENTE")
+	if remitente == "" {
+		remitente = "StreamGo <no-reply@streamgo.local>"
+	}
+	host := os.Getenv("STREAMGO_SMTP_HOST")
+	if host == "" {
+		fmt.Println("📭 STREAMGO_SMTP_HOST no definido: los correos se guardan en ./outbox")
+		return correo.NuevoMailerArchivo("outbox", remitente)
+	}
+	puerto := os.Getenv("STREAMGO_SMTP_PUERTO")
+	if puerto == "" {
+		puerto = "587"
+	}
+	return correo.NuevoMailerSMTP(host, puerto, os.Getenv("STREAMGO_SMTP_USUARIO"), os.Getenv("STREAMGO_SMTP_PASSWORD"), remitente)
+}
+
+// urlBase es la URL pública usada en los enlaces enviados por correo.
+func urlBase() string {
+	if u := os.Getenv("STREAMGO_URL_BASE"); u != "" {
+		return u
+	}
+	return "http://localhost:8080"
+}
+
+func handlePasswordForgot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		renderizar(w, r, "password_forgot.html", nil)
+		return
+	}
+	// La respuesta es la misma exista o no el correo, para no revelar cuentas.
+	if u, err := dbStore.LoadUserByEmail(r.FormValue("email")); err == nil {
+		auditar(u.GetCorreo(), auth.AccionRecuperacionPedida, u.GetID(), ipCliente(r), "")
+		plano, t := auth.NuevoTokenRecuperacion(u.GetID())
+		if err := dbStore.SavePasswordResetToken(t); err != nil {
+			fmt.Printf("❌ Error al guardar token de recuperación: %v\n", err)
+		} else {
+			enlace := urlBase() + "/password/reset?token=" + url.QueryEscape(plano)
+			err := mailer.Enviar(correo.Mensaje{
+				Para:   u.GetCorreo(),
+				Asunto: "Restablece tu contraseña de StreamGo",
+				Cuerpo: fmt.Sprintf("Hola %s,\n\nPara crear una contraseña nueva abre este enlace (válido %d minutos):\n\n%s\n\nSi no lo solicitaste, ignora este correo.",
+					u.GetNombre(), int(auth.VigenciaTokenRecuperacion.Minutes()), enlace),
+			})
+			if err != nil {
+				fmt.Printf("❌ Error al enviar correo de recuperación: %v\n", err)
+			}
+		}
+	}
+	renderizar(w, r, "password_forgot.html", struct{ Enviado bool }{true})
+}
+
+func handlePasswordReset(w http.ResponseWriter, r *http.Request) {
+	type datos struct {
+		Token string
+		Error string
+	}
+	token := r.FormValue("token")
+	hash := auth.HashToken(token)
+
+	if r.Method != http.MethodPost {
+		if err := dbStore.PeekPasswordResetToken(hash); err != nil {
+			renderizar(w, r, "password_reset.html", datos{Error: auth.ErrTokenRecuperacionInvalido.Error()})
+			return
+		}
+		renderizar(w, r, "password_reset.html", datos{Token: token})
+		return
+	}
+
+	nueva := r.FormValue("password")
+	if nueva != r.FormValue("confirmacion") {
+		renderizar(w, r, "password_reset.html", datos{Token: token, Error: "Las contraseñas no coinciden"})
+		return
+	}
+	if err := auth.ValidarContrasenia(nueva); err != nil {
+		renderizar(w, r, "password_reset.html", datos{Token: token, Error: err.Error()})
+		return
+	}
+	uid, err := dbStore.ConsumePasswordResetToken(hash)
+	if err != nil {
+		if !errors.Is(err, auth.ErrTokenRecuperacionInvalido) {
+			fmt.Printf("❌ Error al consumir token de recuperación: %v\n", err)
+		}
+		renderizar(w, r, "password_reset.html", datos{Error: auth.ErrTokenRecuperacionInvalido.Error()})
+		return
+	}
+	u, err := dbStore.LoadUserByID(uid)
+	if err == nil {
+		err = u.CambiarContrasenia(nueva)
+	}
+	if err == nil {
+		err = dbStore.UpdatePasswordHash(u.GetID(), u.GetContraseniaHash())
+	}
+	if err != nil {
+		fmt.Printf("❌ Error al restablecer contraseña: %v\n", err)
+		http.Error(w, "No se pudo restablecer la contraseña", http.StatusInternalServerError)
+		return
+	}
+
+	auditar(u.GetCorreo(), auth.AccionContraseniaCambiada, u.GetID(), ipCliente(r), "enlace de recuperación")
+	// Cualquier sesión abierta con la contraseña anterior deja de ser válida.
+	sesiones.CerrarTodas(uid)
+	tokens.RevocarTodos(uid)
+	http.Redirect(w, r, "/?reset=1", http.StatusSeeOther)
+}
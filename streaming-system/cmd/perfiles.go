@@ -0,0 +1,300 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"streaming-system/pkg/auth"
+	"strings"
+	"time"
+)
+
+// datosPerfiles alimenta profiles.html.
+type datosPerfiles struct {
+	Perfiles  []*auth.Perfil
+	Avatares  []auth.Avatar
+	Gestionar bool // muestra los accesos a editar cada perfil
+	Infantil  bool // el perfil activo es infantil y no puede administrar perfiles
+	Error     string
+	// BajaProgramada avisa de una eliminación de cuenta pendiente; cero si no hay.
+	BajaProgramada time.Time
+}
+
+// datosEditarPerfil alimenta profile_edit.html.
+type datosEditarPerfil struct {
+	Perfil   *auth.Perfil
+	Avatares []auth.Avatar
+	Idiomas  []auth.Idioma
+	Niveles  []auth.NivelParental
+	Error    string
+}
+
+// datosPINPerfil alimenta profile_pin.html en modo "desbloquear" (pedir el
+// PIN al seleccionar) o "configurar" (fijar o quitar el PIN con la contraseña).
+type datosPINPerfil struct {
+	Modo   string
+	Perfil *auth.Perfil
+	Error  string
+}
+
+// mensajePerfil traduce errores de validación para el usuario.
+func mensajePerfil(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrNombrePerfilInvalido), errors.Is(err, auth.ErrAvatarInvalido),
+		errors.Is(err, auth.ErrIdiomaInvalido), errors.Is(err, auth.ErrClasificacionInvalida),
+		errors.Is(err, auth.ErrPerfilNoEncontrado), errors.Is(err, auth.ErrPINInvalido),
+		errors.Is(err, auth.ErrPINIncorrecto), errors.Is(err, auth.ErrDemasiadosIntentos),
+		errors.Is(err, auth.ErrCredencialesInvalidas):
+		return err.Error()
+	}
+	fmt.Printf("❌ Error de perfil: %v\n", err)
+	return "No se pudo guardar el perfil, inténtalo de nuevo"
+}
+
+func renderPerfiles(w http.ResponseWriter, r *http.Request, msgError string) {
+	p, err := dbStore.LoadProfilesByUserID(usuarioDe(r).GetID())
+	if err != nil {
+		fmt.Printf("❌ Error al cargar perfiles: %v\n", err)
+	}
+	baja, err := dbStore.LoadScheduledDeletion(usuarioDe(r).GetID())
+	if err != nil {
+		fmt.Printf("❌ Error al cargar la baja programada: %v\n", err)
+	}
+	infantil := perfilInfantilActivo(r)
+	renderizar(w, r, "profiles.html", datosPerfiles{
+		Perfiles:       p,
+		Avatares:       auth.AvataresDisponibles(),
+		Gestionar:      r.URL.Query().Get("gestionar") == "1" && !infantil,
+		Infantil:       infantil,
+		Error:          msgError,
+		BajaProgramada: baja,
+	})
+}
+
+// perfilInfantilActivo impide que desde un perfil infantil se creen, editen o
+// borren perfiles (por ejemplo, para subir su propia clasificación máxima).
+func perfilInfantilActivo(r *http.Request) bool {
+	p := perfilDe(r)
+	return p != nil && p.EsInfantil()
+}
+
+const msgGestionInfantil = "Un perfil infantil no puede administrar perfiles"
+
+// perfilBloqueado indica que el perfil tiene PIN y no es el perfil activo de
+// la sesión, así que no se puede editar ni borrar sin desbloquearlo antes.
+func perfilBloqueado(r *http.Request, p *auth.Perfil) bool {
+	return p.TienePIN() && sesionDe(r).GetPerfilID() != p.GetID()
+}
+
+const msgPerfilBloqueado = "Entra en el perfil con su PIN para administrarlo"
+
+// verificarPINPerfil comprueba el PIN pasando por el limitador de intentos.
+func verificarPINPerfil(p *auth.Perfil, pin string) error {
+	clave := auth.ClavePIN(p.GetID())
+	if err := verificarLimite(clave); err != nil {
+		return err
+	}
+	if err := p.VerificarPIN(pin); err != nil {
+		if err := limitador.RegistrarFallo(clave); err != nil {
+			fmt.Printf("❌ Error al registrar intento fallido: %v\n", err)
+		}
+		return err
+	}
+	limitador.Reiniciar(clave)
+	return nil
+}
+
+func handleProfiles(w http.ResponseWriter, r *http.Request) {
+	renderPerfiles(w, r, "")
+}
+
+func handleCreateProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+	if perfilInfantilActivo(r) {
+		renderPerfiles(w, r, msgGestionInfantil)
+		return
+	}
+	p := auth.NuevoPerfil(auth.NuevoIDPerfil(), "")
+	err := p.EditarNombre(r.FormValue("nombre"))
+	if err == nil && r.FormValue("avatar") != "" {
+		err = p.CambiarAvatar(r.FormValue("avatar"))
+	}
+	if err == nil && r.FormValue("infantil") == "1" {
+		err = p.CambiarClasificacionMaxima(auth.EdadMaximaInfantil)
+	}
+	if err == nil {
+		err = dbStore.SaveProfile(usuarioDe(r).GetID(), p)
+	}
+	if err != nil {
+		renderPerfiles(w, r, mensajePerfil(err))
+		return
+	}
+	http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+}
+
+// handleSelectProfile activa el perfil en la sesión; si tiene PIN, primero lo pide.
+func handleSelectProfile(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	p, err := dbStore.LoadProfile(usuarioDe(r).GetID(), id)
+	if err != nil {
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+	if p.TienePIN() {
+		if r.Method != http.MethodPost {
+			renderizar(w, r, "profile_pin.html", datosPINPerfil{Modo: "desbloquear", Perfil: p})
+			return
+		}
+		if err := verificarPINPerfil(p, r.FormValue("pin")); err != nil {
+			renderizar(w, r, "profile_pin.html", datosPINPerfil{Modo: "desbloquear", Perfil: p, Error: mensajePerfil(err)})
+			return
+		}
+	}
+	s := sesionDe(r)
+	s.SeleccionarPerfil(id)
+	sesiones.Guardar(s)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// handleProfilePIN fija, cambia o quita el PIN de un perfil. Exige la
+// contraseña de la cuenta, así que sirve también para restablecer un PIN olvidado.
+func handleProfilePIN(w http.ResponseWriter, r *http.Request) {
+	u := usuarioDe(r)
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	p, err := dbStore.LoadProfile(u.GetID(), id)
+	if err != nil {
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+	datos := datosPINPerfil{Modo: "configurar", Perfil: p}
+	if r.Method != http.MethodPost {
+		renderizar(w, r, "profile_pin.html", datos)
+		return
+	}
+
+	clave := auth.ClaveCuenta(u.GetCorreo())
+	err = verificarLimite(clave)
+	if err == nil {
+		if ok, _ := auth.VerificarContrasenia(u.GetContraseniaHash(), r.FormValue("password")); !ok {
+			limitador.RegistrarFallo(clave)
+			err = auth.ErrCredencialesInvalidas
+		}
+	}
+	if err == nil {
+		if pin := r.FormValue("pin"); pin == "" {
+			p.QuitarPIN()
+		} else {
+			err = p.EstablecerPIN(pin)
+		}
+	}
+	if err == nil {
+		err = dbStore.UpdateProfilePIN(u.GetID(), p.GetID(), p.GetPINHash())
+	}
+	if err != nil {
+		datos.Error = mensajePerfil(err)
+		renderizar(w, r, "profile_pin.html", datos)
+		return
+	}
+	// Un PIN nuevo desbloquea el perfil si estaba bloqueado por intentos fallidos.
+	limitador.Reiniciar(auth.ClavePIN(p.GetID()))
+	http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+}
+
+// handleEditProfile muestra y guarda nombre, avatar y preferencias de un perfil.
+func handleEditProfile(w http.ResponseWriter, r *http.Request) {
+	if perfilInfantilActivo(r) {
+		renderPerfiles(w, r, msgGestionInfantil)
+		return
+	}
+	uid := usuarioDe(r).GetID()
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	p, err := dbStore.LoadProfile(uid, id)
+	if err != nil {
+		if !errors.Is(err, auth.ErrPerfilNoEncontrado) {
+			fmt.Printf("❌ Error al cargar perfil: %v\n", err)
+		}
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+	if perfilBloqueado(r, p) {
+		renderPerfiles(w, r, msgPerfilBloqueado)
+		return
+	}
+	datos := datosEditarPerfil{Perfil: p, Avatares: auth.AvataresDisponibles(), Idiomas: auth.IdiomasDisponibles(), Niveles: auth.NivelesParentales()}
+	if r.Method == http.MethodPost {
+		err := p.EditarNombre(r.FormValue("nombre"))
+		if err == nil {
+			err = p.CambiarAvatar(r.FormValue("avatar"))
+		}
+		if err == nil {
+			err = cambiarClasificacionPerfil(r, p)
+		}
+		if err == nil {
+			err = p.ActualizarPreferencias(auth.PreferenciasPerfil{
+				IdiomaInterfaz:   r.FormValue("idioma_interfaz"),
+				IdiomaSubtitulos: r.FormValue("idioma_subtitulos"),
+				Autoplay:         r.FormValue("autoplay") == "1",
+			})
+		}
+		if err == nil {
+			err = dbStore.UpdateProfile(uid, p)
+		}
+		if err == nil {
+			http.Redirect(w, r, "/profiles?gestionar=1", http.StatusSeeOther)
+			return
+		}
+		datos.Error = mensajePerfil(err)
+	}
+	renderizar(w, r, "profile_edit.html", datos)
+}
+
+// cambiarClasificacionPerfil aplica el nivel parental del formulario. Sin el
+// campo, o con el valor ya guardado, el perfil no cambia: los perfiles
+// anteriores a los niveles pueden tener edades que no son ninguno (99, 14...).
+func cambiarClasificacionPerfil(r *http.Request, p *auth.Perfil) error {
+	valor := strings.TrimSpace(r.FormValue("clasificacion_maxima"))
+	if valor == "" {
+		return nil
+	}
+	edad, err := strconv.Atoi(valor)
+	if err != nil {
+		return auth.ErrClasificacionInvalida
+	}
+	if edad == p.GetClasificacionMaxima() {
+		return nil
+	}
+	return p.CambiarClasificacionMaxima(edad)
+}
+
+// handleDeleteProfile elimina el perfil y su historial. Si era el perfil
+// activo de la sesión, la sesión vuelve a la selección de perfil.
+func handleDeleteProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+		return
+	}
+	if perfilInfantilActivo(r) {
+		renderPerfiles(w, r, msgGestionInfantil)
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	if p, err := dbStore.LoadProfile(usuarioDe(r).GetID(), id); err == nil && perfilBloqueado(r, p) {
+		renderPerfiles(w, r, msgPerfilBloqueado)
+		return
+	}
+	if err := dbStore.DeleteProfile(usuarioDe(r).GetID(), id); err != nil {
+		renderPerfiles(w, r, mensajePerfil(err))
+		return
+	}
+	if s := sesionDe(r); s.GetPerfilID() == id {
+		s.SeleccionarPerfil(0)
+		if err := sesiones.Guardar(s); err != nil {
+			fmt.Printf("❌ Error al guardar la sesión: %v\n", err)
+		}
+	}
+	http.Redirect(w, r, "/profiles?gestionar=1", http.StatusSeeOther)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"streaming-system/pkg/auth"
+	"streaming-system/pkg/content"
+	"strings"
+	"time"
+)
+
+// temporadasDe devuelve las temporadas si el contenido es una serie, para
+// que las plantillas muestren la lista de episodios.
+func temporadasDe(c content.Contenible) []*content.Temporada {
+	if s, ok := c.(*content.Serie); ok {
+		return s.ObtenerTemporadas()
+	}
+	return nil
+}
+
+type datosEditorSerie struct {
+	Serie *content.Serie
+	Error string
+}
+
+// handleAdminSeries muestra el editor de temporadas y episodios de una serie
+// y aplica sus formularios (campo accion).
+func handleAdminSeries(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	c, err := gestor.ObtenerPorID(id)
+	serie, ok := c.(*content.Serie)
+	if err != nil || !ok {
+		http.NotFound(w, r)
+		return
+	}
+	datos := datosEditorSerie{Serie: serie}
+	if r.Method == http.MethodPost {
+		detalle, err := editarSerie(r, id)
+		if err == nil {
+			auditarPeticion(r, auth.AccionContenidoActualizado, id, detalle)
+			http.Redirect(w, r, "/admin/series?"+url.Values{"id": {id}}.Encode(), http.StatusSeeOther)
+			return
+		}
+		datos.Error = err.Error()
+	}
+	renderizar(w, r, "admin_series.html", datos)
+}
+
+// errGuardarSerie sustituye en el panel los errores de la base de datos.
+var errGuardarSerie = errors.New("no se pudo guardar el cambio en la base de datos; no se aplicó")
+
+// editarSerie aplica una acción del editor y devuelve el detalle para la
+// auditoría. Cada cambio se guarda en la base de datos antes de publicarse en
+// el catálogo; si falla, el catálogo no cambia.
+func editarSerie(r *http.Request, id string) (string, error) {
+	temporada, _ := strconv.Atoi(r.FormValue("temporada"))
+	numero, _ := strconv.Atoi(r.FormValue("numero"))
+	switch r.FormValue("accion") {
+	case "temporada":
+		titulo := strings.TrimSpace(r.FormValue("titulo"))
+		return fmt.Sprintf("temporada %d guardada", temporada), gestor.ActualizarSerie(id, func(s *content.Serie) error {
+			if err := s.GuardarTemporada(temporada, titulo); err != nil {
+				return err
+			}
+			return enBaseDeDatos(dbStore.SaveSeason(id, temporada, titulo))
+		})
+	case "borrar_temporada":
+		return fmt.Sprintf("temporada %d borrada", temporada), gestor.ActualizarSerie(id, func(s *content.Serie) error {
+			if err := s.BorrarTemporada(temporada); err != nil {
+				return err
+			}
+			return enBaseDeDatos(dbStore.DeleteSeason(id, temporada))
+		})
+	case "episodio":
+		ep, err := episodioDeFormulario(r, numero)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("T%dE%d %q guardado", temporada, numero, ep.GetTitulo()), gestor.ActualizarSerie(id, func(s *content.Serie) error {
+			if err := s.GuardarEpisodio(temporada, ep); err != nil {
+				return err
+			}
+			return enBaseDeDatos(dbStore.SaveEpisode(id, temporada, ep))
+		})
+	case "borrar_episodio":
+		return fmt.Sprintf("T%dE%d borrado", temporada, numero), gestor.ActualizarSerie(id, func(s *content.Serie) error {
+			if err := s.BorrarEpisodio(temporada, numero); err != nil {
+				return err
+			}
+			return enBaseDeDatos(dbStore.DeleteEpisode(id, temporada, numero))
+		})
+	}
+	return "", errors.New("acción desconocida")
+}
+
+// enBaseDeDatos registra el error de la base de datos y lo cambia por
+// errGuardarSerie; los de validación del catálogo llegan tal cual al panel.
+func enBaseDeDatos(err error) error {
+	if err == nil {
+		return nil
+	}
+	fmt.Printf("❌ Error al guardar la serie: %v\n", err)
+	return errGuardarSerie
+}
+
+// episodioDeFormulario lee el episodio del editor; la fecha de emisión es opcional.
+func episodioDeFormulario(r *http.Request, numero int) (*content.Episodio, error) {
+	duracion, _ := strconv.ParseFloat(r.FormValue("duracion"), 32)
+	var emision time.Time
+	if f := r.FormValue("emision"); f != "" {
+		var err error
+		if emision, err = time.Parse(time.DateOnly, f); err != nil {
+			return nil, errors.New("la fecha de emisión no es válida")
+		}
+	}
+	return content.NuevoEpisodio(numero, r.FormValue("titulo"), r.FormValue("descripcion"), float32(duracion), emision)
+}
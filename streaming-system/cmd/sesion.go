@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"os"
+	"streaming-system/pkg/auth"
+)
+
+// claveContexto evita colisiones con otras claves del contexto de la petición.
+type claveContexto int
+
+const (
+	claveSesion claveContexto = iota
+	claveUsuario
+	clavePerfil
+	claveCredencialAPI
+	claveSuplantador
+)
+
+// nuevoGestorSesiones configura el almacén y la clave de firma a partir del entorno.
+// STREAMGO_ALMACEN_SESIONES=memoria usa el almacén en memoria (una sola instancia).
+func nuevoGestorSesiones() *auth.GestorSesiones {
+	var almacen auth.AlmacenSesiones = dbStore
+	if os.Getenv("STREAMGO_ALMACEN_SESIONES") == "memoria" {
+		almacen = auth.NuevoAlmacenSesionesMemoria()
+	}
+
+	clave := []byte(os.Getenv("STREAMGO_CLAVE_SESION"))
+	if len(clave) == 0 {
+		fmt.Println("⚠️ STREAMGO_CLAVE_SESION no definida: se usa una clave aleatoria (las sesiones no sobreviven a un reinicio)")
+		clave = make([]byte, 32)
+		rand.Read(clave)
+	}
+	g := auth.NuevoGestorSesiones(almacen, clave)
+	g.UsarIPCliente(ipCliente)
+	return g
+}
+
+// conSesion exige una sesión válida y resuelve el usuario y el perfil de la petición.
+func conSesion(next http.HandlerFunc) http.HandlerFunc {
+	return resolverSesion(next, false)
+}
+
+// conSesionMFAPendiente admite además sesiones que aún no verificaron el
+// segundo factor; solo debe usarse en las rutas de /login/mfa.
+func conSesionMFAPendiente(next http.HandlerFunc) http.HandlerFunc {
+	return resolverSesion(next, true)
+}
+
+func resolverSesion(next http.HandlerFunc, permitirMFAPendiente bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, err := sesiones.Resolver(r)
+		if err != nil {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		if s.MFAPendiente() && !permitirMFAPendiente {
+			http.Redirect(w, r, "/login/mfa", http.StatusSeeOther)
+			return
+		}
+		// Toda petición que cambia estado con la cookie de sesión debe traer
+		// el token de esa misma sesión.
+		if !metodoSeguro(r.Method) && !csrfValido(r, sesiones.TokenCSRF(s)) {
+			rechazarCSRF(w, r)
+			return
+		}
+		u, err := dbStore.LoadUserByID(s.GetUsuarioID())
+		if err != nil {
+			sesiones.Cerrar(w, r)
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		ctx := context.WithValue(r.Context(), claveSesion, s)
+		if s.EstaSuplantando() {
+			if objetivo := objetivoSuplantacion(s, u); objetivo != nil {
+				ctx = context.WithValue(ctx, claveSuplantador, u)
+				u = objetivo
+			}
+		}
+		if sub, _ := dbStore.LoadActiveSubscription(u.GetID()); sub != nil {
+			u.AsignarSuscripcion(sub)
+		}
+		ctx = context.WithValue(ctx, claveUsuario, u)
+		if s.GetPerfilID() != 0 {
+			if p, err := dbStore.LoadProfile(u.GetID(), s.GetPerfilID()); err == nil {
+				ctx = context.WithValue(ctx, clavePerfil, p)
+			}
+		}
+		r = r.WithContext(ctx)
+		if agente := suplantadorDe(r); agente != nil {
+			auditarPeticion(r, auth.AccionSuplantacionPeticion, u.GetID(), r.Method+" "+r.URL.RequestURI())
+		}
+		next(w, r)
+	}
+}
+
+func sesionDe(r *http.Request) *auth.Sesion {
+	s, _ := r.Context().Value(claveSesion).(*auth.Sesion)
+	return s
+}
+
+func usuarioDe(r *http.Request) *auth.Usuario {
+	u, _ := r.Context().Value(claveUsuario).(*auth.Usuario)
+	return u
+}
+
+func perfilDe(r *http.Request) *auth.Perfil {
+	p, _ := r.Context().Value(clavePerfil).(*auth.Perfil)
+	return p
+}
+
+// conPermiso exige sesión y que el usuario tenga el permiso indicado.
+func conPermiso(p auth.Permiso, next http.HandlerFunc) http.HandlerFunc {
+	return conSesion(func(w http.ResponseWriter, r *http.Request) {
+		if !usuarioDe(r).TienePermiso(p) {
+			http.Error(w, "No tienes permiso para acceder a esta sección", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"streaming-system/pkg/auth"
+	"strings"
+	"unicode/utf8"
+)
+
+// objetivoSuplantacion carga el usuario que suplanta la sesión del agente.
+// Si ya no se permite (el agente perdió el rol, el objetivo pasó a ser
+// administrador o se eliminó), termina la suplantación y devuelve nil para que
+// la petición siga como el propio agente.
+func objetivoSuplantacion(s *auth.Sesion, agente *auth.Usuario) *auth.Usuario {
+	uid := s.GetSuplantadoID()
+	objetivo, err := dbStore.LoadUserByID(uid)
+	if err == nil && objetivo != nil && auth.ValidarSuplantacion(agente, objetivo) == nil {
+		return objetivo
+	}
+	s.TerminarSuplantacion()
+	if err := sesiones.Guardar(s); err != nil {
+		fmt.Printf("❌ Error al terminar la suplantación: %v\n", err)
+	}
+	auditar(agente.GetCorreo(), auth.AccionSuplantacionFin, uid, "", "ya no está permitida")
+	return nil
+}
+
+// suplantadorDe devuelve el agente de soporte cuando la petición se hace
+// suplantando a otro usuario, o nil.
+func suplantadorDe(r *http.Request) *auth.Usuario {
+	u, _ := r.Context().Value(claveSuplantador).(*auth.Usuario)
+	return u
+}
+
+// actorDe identifica a quien hace la petición en la auditoría; durante una
+// suplantación figuran ambas identidades.
+func actorDe(r *http.Request) string {
+	if agente := suplantadorDe(r); agente != nil {
+		return agente.GetCorreo() + " como " + usuarioDe(r).GetCorreo()
+	}
+	return usuarioDe(r).GetCorreo()
+}
+
+// sinSuplantacion veta la ruta durante una suplantación: el soporte no puede
+// mover dinero ni tocar la seguridad o la existencia de la cuenta del cliente.
+func sinSuplantacion(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if suplantadorDe(r) != nil {
+			auditarPeticion(r, auth.AccionSuplantacionBloqueo, usuarioDe(r).GetID(), r.Method+" "+r.URL.Path)
+			http.Error(w, auth.ErrBloqueadoEnSuplantacion.Error(), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleAdminImpersonate empieza a suplantar al usuario del correo indicado.
+// El motivo es obligatorio y queda en la auditoría.
+func handleAdminImpersonate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/admin", http.StatusSeeOther)
+		return
+	}
+	agente, s := usuarioDe(r), sesionDe(r)
+	motivo := strings.TrimSpace(r.FormValue("motivo"))
+	objetivo, err := dbStore.LoadUserByEmail(strings.TrimSpace(r.FormValue("email")))
+	if err != nil || objetivo == nil || motivo == "" || utf8.RuneCountInString(motivo) > 300 {
+		http.Error(w, "Indica el correo de un usuario existente y el motivo de la suplantación", http.StatusBadRequest)
+		return
+	}
+	if err := auth.ValidarSuplantacion(agente, objetivo); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	s.IniciarSuplantacion(objetivo.GetID())
+	if err := sesiones.Guardar(s); err != nil {
+		fmt.Printf("❌ Error al iniciar la suplantación: %v\n", err)
+		http.Error(w, "No se pudo iniciar la suplantación", http.StatusInternalServerError)
+		return
+	}
+	auditarPeticion(r, auth.AccionSuplantacionIniciada, objetivo.GetID(), objetivo.GetCorreo()+": "+motivo)
+	http.Redirect(w, r, "/profiles", http.StatusSeeOther)
+}
+
+// handleImpersonateStop devuelve la sesión al agente.
+func handleImpersonateStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+	s := sesionDe(r)
+	if suplantadorDe(r) == nil {
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+	auditarPeticion(r, auth.AccionSuplantacionFin, usuarioDe(r).GetID(), "")
+	s.TerminarSuplantacion()
+	if err := sesiones.Guardar(s); err != nil {
+		fmt.Printf("❌ Error al terminar la suplantación: %v\n", err)
+	}
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// datosSuplantacion es lo que muestra el aviso fijo de las páginas del cliente.
+type datosSuplantacion struct {
+	Agente  string
+	Usuario string
+}
+
+func suplantacionDe(r *http.Request) *datosSuplantacion {
+	agente := suplantadorDe(r)
+	if agente == nil {
+		return nil
+	}
+	return &datosSuplantacion{Agente: agente.GetCorreo(), Usuario: usuarioDe(r).GetCorreo()}
+}
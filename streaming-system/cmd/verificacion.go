@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"streaming-system/pkg/auth"
+	"streaming-system/pkg/correo"
+	"time"
+)
+
+// nuevoFirmanteVerificacion usa STREAMGO_CLAVE_VERIFICACION o, si no está
+// definida, la clave de sesión, para que los enlaces sobrevivan a un reinicio.
+func nuevoFirmanteVerificacion() *auth.FirmanteVerificacion {
+	clave := []byte(os.Getenv("STREAMGO_CLAVE_VERIFICACION"))
+	if len(clave) == 0 {
+		clave = []byte(os.Getenv("STREAMGO_CLAVE_SESION"))
+	}
+	if len(clave) == 0 {
+		fmt.Println("⚠️ STREAMGO_CLAVE_VERIFICACION no definida: los enlaces de verificación caducan al reiniciar")
+		clave = make([]byte, 32)
+		rand.Read(clave)
+	}
+	return auth.NuevoFirmanteVerificacion(clave)
+}
+
+// enviarVerificacion manda al usuario el enlace para confirmar su correo.
+func enviarVerificacion(u *auth.Usuario) error {
+	enlace := urlBase() + "/verify-email?token=" + url.QueryEscape(verificador.Firmar(u, time.Now()))
+	return mailer.Enviar(correo.Mensaje{
+		Para:   u.GetCorreo(),
+		Asunto: "Confirma tu correo de StreamGo",
+		Cuerpo: fmt.Sprintf("Hola %s,\n\nPara confirmar tu correo abre este enlace (válido %d horas):\n\n%s\n\nSi no creaste una cuenta en StreamGo, ignora este correo.",
+			u.GetNombre(), int(auth.VigenciaEnlaceVerificacion.Hours()), enlace),
+	})
+}
+
+func handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	uid, email, err := verificador.Verificar(r.URL.Query().Get("token"), time.Now())
+	if err == nil {
+		var u *auth.Usuario
+		if u, err = dbStore.LoadUserByID(uid); err == nil && u.GetCorreo() != email {
+			err = auth.ErrEnlaceVerificacionInvalido
+		}
+	}
+	if err == nil {
+		err = dbStore.MarkEmailVerified(uid, email)
+	}
+	if err != nil {
+		if !errors.Is(err, auth.ErrEnlaceVerificacionInvalido) {
+			fmt.Printf("❌ Error al verificar correo: %v\n", err)
+		}
+		renderizar(w, r, "login.html", datosLogin{Error: auth.ErrEnlaceVerificacionInvalido.Error()})
+		return
+	}
+	http.Redirect(w, r, "/?verificado=1", http.StatusSeeOther)
+}
+
+// handleResendVerification reenvía el enlace al usuario con sesión iniciada.
+func handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/checkout", http.StatusSeeOther)
+		return
+	}
+	if u := usuarioDe(r); !u.EmailVerificado() {
+		if err := enviarVerificacion(u); err != nil {
+			fmt.Printf("❌ Error al enviar correo de verificación: %v\n", err)
+		}
+	}
+	http.Redirect(w, r, "/checkout?reenviado=1", http.StatusSeeOther)
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AccionAuditoria identifica el tipo de evento registrado en la auditoría.
+type AccionAuditoria string
+
+const (
+	AccionLogin                AccionAuditoria = "login"
+	AccionLoginFallido         AccionAuditoria = "login_fallido"
+	AccionLogout               AccionAuditoria = "logout"
+	AccionMFAVerificado        AccionAuditoria = "mfa_verificado"
+	AccionMFAActivado          AccionAuditoria = "mfa_activado"
+	AccionMFADesactivado       AccionAuditoria = "mfa_desactivado"
+	AccionCodigosRecuperacion  AccionAuditoria = "mfa_codigos_regenerados"
+	AccionContraseniaCambiada  AccionAuditoria = "contrasenia_cambiada"
+	AccionRecuperacionPedida   AccionAuditoria = "recuperacion_solicitada"
+	AccionIdentidadVinculada   AccionAuditoria = "identidad_externa_vinculada"
+	AccionSesionRevocada       AccionAuditoria = "sesion_revocada"
+	AccionCuentaBloqueada      AccionAuditoria = "cuenta_bloqueada"
+	AccionCuentaDesbloqueada   AccionAuditoria = "cuenta_desbloqueada"
+	AccionDatosExportados      AccionAuditoria = "datos_exportados"
+	AccionBajaSolicitada       AccionAuditoria = "baja_solicitada"
+	AccionBajaCancelada        AccionAuditoria = "baja_cancelada"
+	AccionCuentaEliminada      AccionAuditoria = "cuenta_eliminada"
+	AccionContenidoCreado      AccionAuditoria = "contenido_creado"
+	AccionContenidoActualizado AccionAuditoria = "contenido_actualizado"
+	AccionContenidoEliminado   AccionAuditoria = "contenido_eliminado"
+	AccionRolesCambiados       AccionAuditoria = "roles_cambiados"
+	AccionPoliticaMFACambiada  AccionAuditoria = "politica_mfa_cambiada"
+	AccionClaveAPICreada       AccionAuditoria = "clave_api_creada"
+	AccionClaveAPIRevocada     AccionAuditoria = "clave_api_revocada"
+	AccionSuplantacionIniciada AccionAuditoria = "suplantacion_iniciada"
+	AccionSuplantacionFin      AccionAuditoria = "suplantacion_terminada"
+	AccionSuplantacionPeticion AccionAuditoria = "suplantacion_peticion"
+	AccionSuplantacionBloqueo  AccionAuditoria = "suplantacion_accion_bloqueada"
+	AccionCatalogoImportado    AccionAuditoria = "catalogo_importado"
+	AccionCatalogoExportado    AccionAuditoria = "catalogo_exportado"
+)
+
+// AccionesAuditoria devuelve todas las acciones, para los filtros del panel.
+func AccionesAuditoria() []AccionAuditoria {
+	return []AccionAuditoria{
+		AccionLogin, AccionLoginFallido, AccionLogout, AccionMFAVerificado, AccionMFAActivado, AccionMFADesactivado,
+		AccionCodigosRecuperacion, AccionContraseniaCambiada, AccionRecuperacionPedida, AccionIdentidadVinculada,
+		AccionSesionRevocada, AccionCuentaBloqueada, AccionCuentaDesbloqueada, AccionDatosExportados,
+		AccionBajaSolicitada, AccionBajaCancelada, AccionCuentaEliminada, AccionContenidoCreado,
+		AccionContenidoActualizado, AccionContenidoEliminado, AccionRolesCambiados, AccionPoliticaMFACambiada,
+		AccionClaveAPICreada, AccionClaveAPIRevocada, AccionSuplantacionIniciada, AccionSuplantacionFin,
+		AccionSuplantacionPeticion, AccionSuplantacionBloqueo, AccionCatalogoImportado, AccionCatalogoExportado,
+	}
+}
+
+// EventoAuditoria es una entrada del registro de auditoría. Cada evento
+// guarda el hash del anterior, así que modificar o borrar una fila rompe la
+// cadena a partir de ese punto.
+type EventoAuditoria struct {
+	id           int64
+	fecha        time.Time
+	actor        string // correo del usuario, o "sistema"
+	accion       AccionAuditoria
+	objetivo     string // sobre qué se actuó (usuario, contenido, clave del limitador...)
+	ip           string
+	detalle      string
+	hashAnterior string
+	hash         string
+}
+
+// NuevoEventoAuditoria crea un evento aún sin encadenar.
+func NuevoEventoAuditoria(actor string, accion AccionAuditoria, objetivo, ip, detalle string) *EventoAuditoria {
+	// Se recorta antes de calcular el hash para que coincida con lo que se persiste.
+	return &EventoAuditoria{
+		fecha: time.Now().UTC().Truncate(time.Microsecond), actor: recortar(actor, 254), accion: accion,
+		objetivo: recortar(objetivo, 255), ip: recortar(ip, 45), detalle: recortar(detalle, 500),
+	}
+}
+
+// recortar limita s a n caracteres (no bytes), como VARCHAR(n).
+func recortar(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// RecreateEventoAuditoriaFromDB reconstruye un evento persistido.
+func RecreateEventoAuditoriaFromDB(id int64, fecha time.Time, actor string, accion AccionAuditoria, objetivo, ip, detalle, hashAnterior, hash string) *EventoAuditoria {
+	return &EventoAuditoria{id: id, fecha: fecha, actor: actor, accion: accion, objetivo: objetivo, ip: ip, detalle: detalle, hashAnterior: hashAnterior, hash: hash}
+}
+
+func (e *EventoAuditoria) GetID() int64               { return e.id }
+func (e *EventoAuditoria) GetFecha() time.Time        { return e.fecha }
+func (e *EventoAuditoria) GetActor() string           { return e.actor }
+func (e *EventoAuditoria) GetAccion() AccionAuditoria { return e.accion }
+func (e *EventoAuditoria) GetObjetivo() string        { return e.objetivo }
+func (e *EventoAuditoria) GetIP() string              { return e.ip }
+func (e *EventoAuditoria) GetDetalle() string         { return e.detalle }
+func (e *EventoAuditoria) GetHashAnterior() string    { return e.hashAnterior }
+func (e *EventoAuditoria) GetHash() string            { return e.hash }
+
+// Encadenar fija el hash del evento anterior y calcula el propio. Lo llama
+// el almacén mientras tiene bloqueada la cabeza de la cadena.
+func (e *EventoAuditoria) Encadenar(hashAnterior string) {
+	e.hashAnterior = hashAnterior
+	e.hash = e.calcularHash()
+}
+
+func (e *EventoAuditoria) calcularHash() string {
+	campos := []string{e.hashAnterior, e.fecha.UTC().Format(time.RFC3339Nano), e.actor, string(e.accion), e.objetivo, e.ip, e.detalle}
+	// Cada campo va precedido de su longitud en bytes: así ningún contenido,
+	// tenga los separadores que tenga, produce la misma entrada que otro.
+	h := sha256.New()
+	for _, c := range campos {
+		fmt.Fprintf(h, "%d:%s", len(c), c)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FiltroAuditoria restringe la consulta del registro; los campos vacíos no filtran.
+type FiltroAuditoria struct {
+	Actor    string
+	Accion   AccionAuditoria
+	Objetivo string
+	IP       string
+	Desde    time.Time
+	Hasta    time.Time
+	Limite   int // 0 = sin límite
+}
+
+// Coincide indica si el evento pasa el filtro (Actor y Objetivo por subcadena).
+func (f FiltroAuditoria) Coincide(e *EventoAuditoria) bool {
+	contiene := func(s, sub string) bool { return strings.Contains(strings.ToLower(s), strings.ToLower(sub)) }
+	return (f.Actor == "" || contiene(e.actor, f.Actor)) &&
+		(f.Accion == "" || e.accion == f.Accion) &&
+		(f.Objetivo == "" || contiene(e.objetivo, f.Objetivo)) &&
+		(f.IP == "" || e.ip == f.IP) &&
+		(f.Desde.IsZero() || !e.fecha.Before(f.Desde)) &&
+		(f.Hasta.IsZero() || e.fecha.Before(f.Hasta))
+}
+
+// AlmacenAuditoria es el backend del registro. Solo permite añadir eventos.
+type AlmacenAuditoria interface {
+	// AppendAuditEvent encadena el evento con el último guardado (llamando a
+	// Encadenar) y lo inserta, de forma atómica frente a otras escrituras.
+	AppendAuditEvent(e *EventoAuditoria) error
+	// LoadAuditEvents devuelve los eventos que cumplen el filtro, el más reciente primero.
+	LoadAuditEvents(f FiltroAuditoria) ([]*EventoAuditoria, error)
+	// LoadAuditChain devuelve todos los eventos en orden de inserción.
+	LoadAuditChain() ([]*EventoAuditoria, error)
+}
+
+// RegistroAuditoria añade eventos al almacén y comprueba la integridad de la cadena.
+type RegistroAuditoria struct {
+	almacen AlmacenAuditoria
+}
+
+// NuevoRegistroAuditoria crea un registro sobre el almacén indicado.
+func NuevoRegistroAuditoria(almacen AlmacenAuditoria) *RegistroAuditoria {
+	return &RegistroAuditoria{almacen: almacen}
+}
+
+// Registrar añade un evento al final de la cadena.
+func (r *RegistroAuditoria) Registrar(actor string, accion AccionAuditoria, objetivo, ip, detalle string) error {
+	return r.almacen.AppendAuditEvent(NuevoEventoAuditoria(actor, accion, objetivo, ip, detalle))
+}
+
+// Buscar devuelve los eventos que cumplen el filtro, el más reciente primero.
+func (r *RegistroAuditoria) Buscar(f FiltroAuditoria) ([]*EventoAuditoria, error) {
+	return r.almacen.LoadAuditEvents(f)
+}
+
+// Verificar recorre la cadena completa y devuelve el ID del primer evento
+// alterado (hash que no corresponde a su contenido o que no enlaza con el
+// anterior), o 0 si la cadena está íntegra.
+func (r *RegistroAuditoria) Verificar() (int64, error) {
+	cadena, err := r.almacen.LoadAuditChain()
+	if err != nil {
+		return 0, err
+	}
+	anterior := ""
+	for _, e := range cadena {
+		if e.hashAnterior != anterior || e.calcularHash() != e.hash {
+			return e.id, nil
+		}
+		anterior = e.hash
+	}
+	return 0, nil
+}
+
+// AlmacenAuditoriaMemoria es un backend para una sola instancia sin base de datos.
+type AlmacenAuditoriaMemoria struct {
+	mu      sync.Mutex
+	eventos []*EventoAuditoria
+}
+
+// NuevoAlmacenAuditoriaMemoria crea un backend en memoria vacío.
+func NuevoAlmacenAuditoriaMemoria() *AlmacenAuditoriaMemoria {
+	return &AlmacenAuditoriaMemoria{}
+}
+
+func (m *AlmacenAuditoriaMemoria) AppendAuditEvent(e *EventoAuditoria) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	anterior := ""
+	if n := len(m.eventos); n > 0 {
+		anterior = m.eventos[n-1].hash
+	}
+	e.id = int64(len(m.eventos) + 1)
+	e.Encadenar(anterior)
+	m.eventos = append(m.eventos, e)
+	return nil
+}
+
+func (m *AlmacenAuditoriaMemoria) LoadAuditEvents(f FiltroAuditoria) ([]*EventoAuditoria, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*EventoAuditoria
+	for i := len(m.eventos) - 1; i >= 0 && (f.Limite == 0 || len(res) < f.Limite); i-- {
+		if f.Coincide(m.eventos[i]) {
+			res = append(res, m.eventos[i])
+		}
+	}
+	return res, nil
+}
+
+func (m *AlmacenAuditoriaMemoria) LoadAuditChain() ([]*EventoAuditoria, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*EventoAuditoria(nil), m.eventos...), nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// registroPrueba devuelve un registro en memoria con cuatro eventos (IDs 1 a 4).
+func registroPrueba(t *testing.T) (*RegistroAuditoria, *AlmacenAuditoriaMemoria) {
+	t.Helper()
+	almacen := NuevoAlmacenAuditoriaMemoria()
+	r := NuevoRegistroAuditoria(almacen)
+	for _, detalle := range []string{"uno", "dos", "tres", "cuatro"} {
+		if err := r.Registrar("ana@example.com", AccionLogin, "42", "203.0.113.7", detalle); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r, almacen
+}
+
+func TestVerificarAuditoria(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		alterar  func(m *AlmacenAuditoriaMemoria)
+		alterado int64
+	}{
+		{"cadena íntegra", func(*AlmacenAuditoriaMemoria) {}, 0},
+		{"campo modificado", func(m *AlmacenAuditoriaMemoria) { m.eventos[1].detalle = "otro" }, 2},
+		{"fecha modificada", func(m *AlmacenAuditoriaMemoria) { m.eventos[2].fecha = m.eventos[2].fecha.Add(time.Second) }, 3},
+		{"fila intermedia borrada", func(m *AlmacenAuditoriaMemoria) { m.eventos = slices.Delete(m.eventos, 1, 2) }, 3},
+		{"primera fila borrada", func(m *AlmacenAuditoriaMemoria) { m.eventos = m.eventos[1:] }, 2},
+		{"enlace roto con hash recalculado", func(m *AlmacenAuditoriaMemoria) {
+			// Quien reescribe una fila puede recalcular su hash, pero no el de la siguiente.
+			m.eventos[1].detalle = "otro"
+			m.eventos[1].Encadenar(m.eventos[1].hashAnterior)
+		}, 3},
+		{"hash anterior cambiado", func(m *AlmacenAuditoriaMemoria) { m.eventos[3].Encadenar(m.eventos[1].hash) }, 4},
+		{"última fila borrada", func(m *AlmacenAuditoriaMemoria) { m.eventos = m.eventos[:3] }, 0}, // indetectable sin un ancla externa
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			r, almacen := registroPrueba(t)
+			c.alterar(almacen)
+			alterado, err := r.Verificar()
+			if err != nil || alterado != c.alterado {
+				t.Fatalf("Verificar = %d, %v; se esperaba %d", alterado, err, c.alterado)
+			}
+		})
+	}
+}
+
+// TestHashAuditoriaInyectivo comprueba que contenidos distintos con
+// separadores o secuencias de escape no producen el mismo hash.
+func TestHashAuditoriaInyectivo(t *testing.T) {
+	fecha := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	evento := func(objetivo, ip, detalle string) *EventoAuditoria {
+		e := &EventoAuditoria{fecha: fecha, actor: "ana@example.com", accion: AccionLogin, objetivo: objetivo, ip: ip, detalle: detalle}
+		e.Encadenar("")
+		return e
+	}
+	pares := []struct {
+		nombre string
+		a, b   *EventoAuditoria
+	}{
+		{"salto de línea y barra invertida", evento("a\nb", "", ""), evento(`a\nb`, "", "")},
+		{"texto movido entre campos", evento("a\nb", "", ""), evento("a", "b", "")},
+		{"separador al final de un campo", evento("a\n", "b", ""), evento("a", "\nb", "")},
+		{"longitud dentro del contenido", evento("1:a", "", ""), evento("", "1:a", "")},
+	}
+	for _, p := range pares {
+		if p.a.GetHash() == p.b.GetHash() {
+			t.Errorf("%s: mismo hash para %q/%q/%q y %q/%q/%q", p.nombre,
+				p.a.objetivo, p.a.ip, p.a.detalle, p.b.objetivo, p.b.ip, p.b.detalle)
+		}
+	}
+}
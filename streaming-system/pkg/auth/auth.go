@@ -1,7 +1,11 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"math/big"
+	"strconv"
 	"time"
 )
 
@@ -9,31 +13,40 @@ import (
 // ¡Declaración única para todo el paquete auth!
 var ErrCredencialesInvalidas = errors.New("credenciales inválidas")
 
+// ErrUsuarioNoEncontrado indica que no existe un usuario con ese ID o correo.
+var ErrUsuarioNoEncontrado = errors.New("usuario no encontrado")
+
 // Autenticacion representa la sesión de un usuario.
 type Autenticacion struct {
 	token      string    // token de sesión
+	usuarioID  string    // usuario dueño del token
 	expireDate time.Time // fecha de expiración del token
 }
 
-// GenerarToken simula la generación de un nuevo token de sesión.
+// RecreateAutenticacionFromDB reconstruye una autenticación persistida.
+func RecreateAutenticacionFromDB(token, usuarioID string, expira time.Time) *Autenticacion {
+	return &Autenticacion{token: token, usuarioID: usuarioID, expireDate: expira}
+}
+
+// GenerarToken genera un nuevo token de sesión aleatorio para el usuario.
 func (a *Autenticacion) GenerarToken(usuarioID string) string {
-	// Simulación simple: un token es el ID del usuario más un timestamp
-	a.token = usuarioID + "_" + time.Now().Format("20060102150405")
+	a.token = generarTokenAleatorio(32)
+	a.usuarioID = usuarioID
 	a.expireDate = time.Now().Add(24 * time.Hour)
 	return a.token
 }
 
-// VerificarToken simula la verificación de la validez de un token.
+// VerificarToken comprueba que el token exista y no haya expirado.
 func (a *Autenticacion) VerificarToken() bool {
 	return time.Now().Before(a.expireDate) && a.token != ""
 }
 
-// RenovarToken simula la extensión de la vida del token.
+// RenovarToken extiende la vida del token.
 func (a *Autenticacion) RenovarToken() {
 	a.expireDate = time.Now().Add(48 * time.Hour)
 }
 
-// RevocarToken simula el cierre de sesión, invalidando el token.
+// RevocarToken cierra la sesión, invalidando el token.
 func (a *Autenticacion) RevocarToken() {
 	a.token = ""
 	a.expireDate = time.Time{}
@@ -43,3 +56,48 @@ func (a *Autenticacion) RevocarToken() {
 func (a *Autenticacion) GetToken() string {
 	return a.token
 }
+
+// GetUsuarioID devuelve el ID del usuario dueño del token.
+func (a *Autenticacion) GetUsuarioID() string {
+	return a.usuarioID
+}
+
+// GetExpireDate devuelve la fecha de expiración del token.
+func (a *Autenticacion) GetExpireDate() time.Time {
+	return a.expireDate
+}
+
+// generarTokenAleatorio devuelve n bytes aleatorios codificados en hexadecimal.
+func generarTokenAleatorio(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("auth: no se pudo leer de crypto/rand: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// maxID mantiene los IDs generados por debajo de 2^53 para que los clientes
+// JavaScript de la API los lean sin perder precisión.
+var maxID = big.NewInt(1<<53 - 1)
+
+// idAleatorio devuelve un entero aleatorio en [1, 2^53).
+func idAleatorio() int64 {
+	n, err := rand.Int(rand.Reader, maxID)
+	if err != nil {
+		panic("auth: no se pudo leer de crypto/rand: " + err.Error())
+	}
+	return n.Int64() + 1
+}
+
+// NuevoIDUsuario genera el ID de una cuenta nueva: un entero positivo
+// aleatorio, porque USUARIO_ID es BIGINT y dos altas en el mismo instante no
+// deben chocar.
+func NuevoIDUsuario() string {
+	return strconv.FormatInt(idAleatorio(), 10)
+}
+
+// NuevoIDPerfil genera el ID de un perfil nuevo con el mismo criterio que
+// NuevoIDUsuario: PERFIL_ID es la clave de toda la tabla, no de cada cuenta.
+func NuevoIDPerfil() int {
+	return int(idAleatorio())
+}
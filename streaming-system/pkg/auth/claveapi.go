@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrClaveAPIInvalida cubre claves inexistentes, revocadas o expiradas.
+	ErrClaveAPIInvalida = errors.New("la clave de API no es válida, fue revocada o expiró")
+	// ErrAlcanceInsuficiente indica que la clave no concede el alcance que exige la ruta.
+	ErrAlcanceInsuficiente = errors.New("la clave de API no tiene el alcance necesario")
+	// ErrDatosClaveAPI indica un nombre, propietario, alcance o expiración no válidos al crearla.
+	ErrDatosClaveAPI = errors.New("la clave necesita un nombre, un único propietario (usuario o cuenta de servicio), al menos un alcance y una expiración futura")
+)
+
+// PrefijoClaveAPI distingue las claves de API de los access tokens JWT.
+const PrefijoClaveAPI = "sgk_"
+
+// intervaloUsoClaveAPI limita cuántas veces se persiste el último uso de una
+// clave: los trabajos por lotes pueden hacer muchas peticiones por segundo.
+const intervaloUsoClaveAPI = time.Minute
+
+// AlcanceAPI es una familia de operaciones que una clave de API puede realizar.
+type AlcanceAPI string
+
+const (
+	AlcanceCatalogoEscritura  AlcanceAPI = "catalog:write"
+	AlcanceFacturacionLectura AlcanceAPI = "billing:read"
+	AlcanceReportes           AlcanceAPI = "reporting"
+)
+
+// permisoPorAlcance es el permiso que debe conservar el usuario propietario
+// de una clave para que esta siga funcionando con ese alcance.
+var permisoPorAlcance = map[AlcanceAPI]Permiso{
+	AlcanceCatalogoEscritura:  PermisoGestionarCatalogo,
+	AlcanceFacturacionLectura: PermisoVerFacturacion,
+	AlcanceReportes:           PermisoVerReportes,
+}
+
+// AlcancesAPI devuelve todos los alcances, para el formulario del panel.
+func AlcancesAPI() []AlcanceAPI {
+	return []AlcanceAPI{AlcanceCatalogoEscritura, AlcanceFacturacionLectura, AlcanceReportes}
+}
+
+// EsAlcanceValido indica si a es uno de los alcances definidos.
+func EsAlcanceValido(a AlcanceAPI) bool {
+	_, ok := permisoPorAlcance[a]
+	return ok
+}
+
+// Permiso devuelve el permiso equivalente al alcance.
+func (a AlcanceAPI) Permiso() Permiso { return permisoPorAlcance[a] }
+
+// ClaveAPI es una credencial para trabajos automáticos. Pertenece a un
+// usuario o a una cuenta de servicio con nombre, y solo se persiste su hash.
+type ClaveAPI struct {
+	id             int64
+	nombre         string
+	prefijo        string // primeros caracteres de la clave, para reconocerla en el panel
+	hash           string
+	usuarioID      string // vacío si pertenece a una cuenta de servicio
+	cuentaServicio string
+	alcances       []AlcanceAPI
+	creadaPor      string
+	creada         time.Time
+	expira         time.Time // cero = sin expiración
+	ultimoUso      time.Time
+	ultimaIP       string
+	revocada       time.Time
+}
+
+// NuevaClaveAPI genera una clave y devuelve también su valor en claro, que
+// solo se muestra una vez. Debe indicarse usuarioID o cuentaServicio, no ambos.
+func NuevaClaveAPI(nombre, usuarioID, cuentaServicio string, alcances []AlcanceAPI, expira time.Time, creadaPor string) (string, *ClaveAPI, error) {
+	nombre, cuentaServicio = strings.TrimSpace(nombre), strings.TrimSpace(cuentaServicio)
+	ahora := time.Now().UTC().Truncate(time.Second)
+	if nombre == "" || utf8.RuneCountInString(nombre) > 100 || utf8.RuneCountInString(cuentaServicio) > 100 ||
+		(usuarioID == "") == (cuentaServicio == "") || len(alcances) == 0 ||
+		(!expira.IsZero() && !expira.After(ahora)) {
+		return "", nil, ErrDatosClaveAPI
+	}
+	for _, a := range alcances {
+		if !EsAlcanceValido(a) {
+			return "", nil, ErrDatosClaveAPI
+		}
+	}
+	plano := PrefijoClaveAPI + generarTokenAleatorio(32)
+	return plano, &ClaveAPI{
+		nombre: nombre, prefijo: plano[:len(PrefijoClaveAPI)+8], hash: HashToken(plano),
+		usuarioID: usuarioID, cuentaServicio: cuentaServicio, alcances: alcances,
+		creadaPor: creadaPor, creada: ahora, expira: expira,
+	}, nil
+}
+
+// RecreateClaveAPIFromDB reconstruye una clave persistida.
+func RecreateClaveAPIFromDB(id int64, nombre, prefijo, hash, usuarioID, cuentaServicio string, alcances []AlcanceAPI, creadaPor string, creada, expira, ultimoUso time.Time, ultimaIP string, revocada time.Time) *ClaveAPI {
+	return &ClaveAPI{
+		id: id, nombre: nombre, prefijo: prefijo, hash: hash, usuarioID: usuarioID, cuentaServicio: cuentaServicio,
+		alcances: alcances, creadaPor: creadaPor, creada: creada, expira: expira,
+		ultimoUso: ultimoUso, ultimaIP: ultimaIP, revocada: revocada,
+	}
+}
+
+func (c *ClaveAPI) GetID() int64                { return c.id }
+func (c *ClaveAPI) GetNombre() string           { return c.nombre }
+func (c *ClaveAPI) GetPrefijo() string          { return c.prefijo }
+func (c *ClaveAPI) GetHash() string             { return c.hash }
+func (c *ClaveAPI) GetUsuarioID() string        { return c.usuarioID }
+func (c *ClaveAPI) GetCuentaServicio() string   { return c.cuentaServicio }
+func (c *ClaveAPI) GetAlcances() []AlcanceAPI   { return c.alcances }
+func (c *ClaveAPI) GetCreadaPor() string        { return c.creadaPor }
+func (c *ClaveAPI) GetCreada() time.Time        { return c.creada }
+func (c *ClaveAPI) GetExpira() time.Time        { return c.expira }
+func (c *ClaveAPI) GetUltimoUso() time.Time     { return c.ultimoUso }
+func (c *ClaveAPI) GetUltimaIP() string         { return c.ultimaIP }
+func (c *ClaveAPI) GetRevocada() time.Time      { return c.revocada }
+func (c *ClaveAPI) EsDeCuentaServicio() bool    { return c.usuarioID == "" }
+func (c *ClaveAPI) EstaRevocada() bool          { return !c.revocada.IsZero() }
+func (c *ClaveAPI) Expiro(ahora time.Time) bool { return !c.expira.IsZero() && !ahora.Before(c.expira) }
+
+// EstaVigente indica si la clave puede usarse ahora.
+func (c *ClaveAPI) EstaVigente(ahora time.Time) bool { return !c.EstaRevocada() && !c.Expiro(ahora) }
+
+// Concede indica si la clave incluye el alcance.
+func (c *ClaveAPI) Concede(a AlcanceAPI) bool {
+	for _, propio := range c.alcances {
+		if propio == a {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistrarUso anota el uso y devuelve true si ha pasado suficiente tiempo
+// desde el último registrado como para persistirlo.
+func (c *ClaveAPI) RegistrarUso(ahora time.Time, ip string) bool {
+	if !c.ultimoUso.IsZero() && ahora.Sub(c.ultimoUso) < intervaloUsoClaveAPI {
+		return false
+	}
+	c.ultimoUso, c.ultimaIP = ahora, ip
+	return true
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNuevaClaveAPI(t *testing.T) {
+	manana := time.Now().Add(24 * time.Hour)
+	todos := AlcancesAPI()
+	casos := []struct {
+		nombre         string
+		clave          string
+		usuarioID      string
+		cuentaServicio string
+		alcances       []AlcanceAPI
+		expira         time.Time
+		ok             bool
+	}{
+		{"de usuario", "ci", "1", "", todos, manana, true},
+		{"de cuenta de servicio", "ci", "", "importador", todos, manana, true},
+		{"sin expiración", "ci", "1", "", todos, time.Time{}, true},
+		{"usuario y cuenta de servicio", "ci", "1", "importador", todos, manana, false},
+		{"sin propietario", "ci", "", "", todos, manana, false},
+		{"cuenta de servicio en blanco", "ci", "", "   ", todos, manana, false},
+		{"sin nombre", "  ", "1", "", todos, manana, false},
+		{"nombre demasiado largo", strings.Repeat("ñ", 101), "1", "", todos, manana, false},
+		{"cuenta de servicio demasiado larga", "ci", "", strings.Repeat("s", 101), todos, manana, false},
+		{"sin alcances", "ci", "1", "", nil, manana, false},
+		{"alcance desconocido", "ci", "1", "", []AlcanceAPI{AlcanceReportes, "admin"}, manana, false},
+		{"expiración pasada", "ci", "1", "", todos, time.Now().Add(-time.Hour), false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			plano, clave, err := NuevaClaveAPI(c.clave, c.usuarioID, c.cuentaServicio, c.alcances, c.expira, "admin@example.com")
+			if !c.ok {
+				if err != ErrDatosClaveAPI || clave != nil || plano != "" {
+					t.Fatalf("error = %v, se esperaba %v", err, ErrDatosClaveAPI)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(plano, PrefijoClaveAPI) || !strings.HasPrefix(plano, clave.GetPrefijo()) || clave.GetHash() != HashToken(plano) {
+				t.Fatalf("clave %q con prefijo %q y hash %q", plano, clave.GetPrefijo(), clave.GetHash())
+			}
+			if clave.EsDeCuentaServicio() != (c.usuarioID == "") || !clave.EstaVigente(time.Now()) {
+				t.Fatalf("cuenta de servicio %v, vigente %v", clave.EsDeCuentaServicio(), clave.EstaVigente(time.Now()))
+			}
+		})
+	}
+
+	otro, _, _ := NuevaClaveAPI("ci", "1", "", todos, manana, "")
+	if uno, _, _ := NuevaClaveAPI("ci", "1", "", todos, manana, ""); uno == otro {
+		t.Fatal("dos claves con el mismo valor")
+	}
+}
+
+func TestClaveAPIConcede(t *testing.T) {
+	_, c, err := NuevaClaveAPI("ci", "", "facturacion", []AlcanceAPI{AlcanceFacturacionLectura}, time.Time{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	casos := []struct {
+		alcance AlcanceAPI
+		ok      bool
+	}{
+		{AlcanceFacturacionLectura, true},
+		{AlcanceCatalogoEscritura, false},
+		{AlcanceReportes, false},
+		{"billing", false},
+	}
+	for _, cs := range casos {
+		if got := c.Concede(cs.alcance); got != cs.ok {
+			t.Errorf("Concede(%q) = %v, se esperaba %v", cs.alcance, got, cs.ok)
+		}
+	}
+}
+
+func TestClaveAPIEstaVigente(t *testing.T) {
+	ahora := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clave := func(expira, revocada time.Time) *ClaveAPI {
+		return RecreateClaveAPIFromDB(1, "ci", "sgk_abcd1234", "hash", "1", "", AlcancesAPI(), "", ahora.Add(-time.Hour), expira, time.Time{}, "", revocada)
+	}
+	casos := []struct {
+		nombre string
+		c      *ClaveAPI
+		ok     bool
+	}{
+		{"sin expiración", clave(time.Time{}, time.Time{}), true},
+		{"expira después", clave(ahora.Add(time.Second), time.Time{}), true},
+		{"expira justo ahora", clave(ahora, time.Time{}), false},
+		{"expirada", clave(ahora.Add(-time.Second), time.Time{}), false},
+		{"revocada", clave(time.Time{}, ahora.Add(-time.Minute)), false},
+		{"revocada y expirada", clave(ahora.Add(-time.Second), ahora.Add(-time.Minute)), false},
+	}
+	for _, c := range casos {
+		if got := c.c.EstaVigente(ahora); got != c.ok {
+			t.Errorf("%s: EstaVigente = %v, se esperaba %v", c.nombre, got, c.ok)
+		}
+	}
+}
+
+func TestClaveAPIRegistrarUso(t *testing.T) {
+	_, c, _ := NuevaClaveAPI("ci", "1", "", AlcancesAPI(), time.Time{}, "")
+	ahora := time.Now()
+	pasos := []struct {
+		cuando time.Time
+		ok     bool
+	}{
+		{ahora, true},
+		{ahora.Add(intervaloUsoClaveAPI - time.Second), false},
+		{ahora.Add(intervaloUsoClaveAPI), true},
+	}
+	for i, p := range pasos {
+		if got := c.RegistrarUso(p.cuando, "203.0.113.7"); got != p.ok {
+			t.Fatalf("uso %d: RegistrarUso = %v, se esperaba %v", i, got, p.ok)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package auth
+
+import "crypto/hmac"
+
+// Los tokens CSRF se derivan con la clave de las sesiones. Cada uso lleva su
+// propio prefijo para que un token CSRF, que viaja en el HTML, nunca coincida
+// con la firma de la cookie de sesión.
+
+// TokenCSRF devuelve el token sincronizador de la sesión. Es estable durante
+// toda la sesión y cambia al volver a iniciarla, porque depende de su token.
+func (g *GestorSesiones) TokenCSRF(s *Sesion) string {
+	return g.firmar("csrf:" + s.GetToken())
+}
+
+// TokenCSRFAnonimo devuelve el token de los formularios previos al login
+// (login, registro, recuperación), ligado a una semilla aleatoria que el
+// cliente guarda en una cookie propia.
+func (g *GestorSesiones) TokenCSRFAnonimo(semilla string) string {
+	return g.firmar("csrf-anonimo:" + semilla)
+}
+
+// VerificarTokenCSRF compara en tiempo constante el token recibido con el esperado.
+func VerificarTokenCSRF(esperado, recibido string) bool {
+	return recibido != "" && hmac.Equal([]byte(esperado), []byte(recibido))
+}
@@ -0,0 +1,54 @@
+package auth
+
+import "testing"
+
+func sesionPrueba(t *testing.T, uid string) *Sesion {
+	t.Helper()
+	a := &Autenticacion{}
+	a.GenerarToken(uid)
+	return NuevaSesion(a, Dispositivo{})
+}
+
+func TestTokenCSRF(t *testing.T) {
+	g := NuevoGestorSesiones(NuevoAlmacenSesionesMemoria(), []byte("clave"))
+	otraClave := NuevoGestorSesiones(NuevoAlmacenSesionesMemoria(), []byte("otra clave"))
+	s1, s2 := sesionPrueba(t, "1"), sesionPrueba(t, "1")
+
+	if g.TokenCSRF(s1) != g.TokenCSRF(s1) {
+		t.Fatal("el token de una sesión debe ser estable")
+	}
+	// Pares que no deben coincidir nunca.
+	distintos := []struct {
+		nombre string
+		a, b   string
+	}{
+		{"otra sesión del mismo usuario", g.TokenCSRF(s1), g.TokenCSRF(s2)},
+		{"otra clave", g.TokenCSRF(s1), otraClave.TokenCSRF(s1)},
+		{"firma de la cookie de sesión", g.TokenCSRF(s1), g.firmar(s1.GetToken())},
+		{"anónimo con el token como semilla", g.TokenCSRF(s1), g.TokenCSRFAnonimo(s1.GetToken())},
+		{"anónimo con otra semilla", g.TokenCSRFAnonimo("a"), g.TokenCSRFAnonimo("b")},
+	}
+	for _, d := range distintos {
+		if d.a == d.b || VerificarTokenCSRF(d.a, d.b) {
+			t.Errorf("%s: el token coincide", d.nombre)
+		}
+	}
+}
+
+func TestVerificarTokenCSRF(t *testing.T) {
+	casos := []struct {
+		esperado, recibido string
+		ok                 bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"abc", "", false},
+		{"", "", false},
+		{"abc", "abcd", false},
+	}
+	for _, c := range casos {
+		if got := VerificarTokenCSRF(c.esperado, c.recibido); got != c.ok {
+			t.Errorf("VerificarTokenCSRF(%q, %q) = %v, se esperaba %v", c.esperado, c.recibido, got, c.ok)
+		}
+	}
+}
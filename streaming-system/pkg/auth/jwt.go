@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrTokenInvalido cubre firmas incorrectas, claves desconocidas y tokens expirados.
+	ErrTokenInvalido = errors.New("token inválido o expirado")
+	// ErrReutilizacionRefresco indica que se presentó un refresh token ya rotado;
+	// toda su familia queda revocada.
+	ErrReutilizacionRefresco = errors.New("refresh token reutilizado: sesión revocada")
+)
+
+// ClaveFirma es una clave identificada por `kid` con su algoritmo de firma.
+type ClaveFirma struct {
+	kid         string
+	metodo      jwt.SigningMethod
+	firmar      any // []byte o ed25519.PrivateKey
+	verificador any // []byte o ed25519.PublicKey
+}
+
+// NuevaClaveHS256 crea una clave simétrica HMAC-SHA256.
+func NuevaClaveHS256(kid string, secreto []byte) *ClaveFirma {
+	return &ClaveFirma{kid: kid, metodo: jwt.SigningMethodHS256, firmar: secreto, verificador: secreto}
+}
+
+// NuevaClaveEd25519 crea una clave asimétrica EdDSA a partir de la privada.
+func NuevaClaveEd25519(kid string, privada ed25519.PrivateKey) *ClaveFirma {
+	return &ClaveFirma{kid: kid, metodo: jwt.SigningMethodEdDSA, firmar: privada,
+		verificador: privada.Public().(ed25519.PublicKey)}
+}
+
+func (c *ClaveFirma) GetKid() string { return c.kid }
+
+// ClaimsAcceso son los claims del access token.
+type ClaimsAcceso struct {
+	Roles []Rol `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ParTokens es la respuesta de emisión y rotación de tokens.
+type ParTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// TokenRefresco es el registro persistido de un refresh token (solo su hash).
+// Todos los tokens rotados desde un mismo inicio de sesión comparten familia.
+type TokenRefresco struct {
+	hash      string
+	usuarioID string
+	familia   string
+	expira    time.Time
+	usado     bool // ya se cambió por un token nuevo
+	revocado  bool
+}
+
+// RecreateTokenRefrescoFromDB reconstruye un refresh token persistido.
+func RecreateTokenRefrescoFromDB(hash, usuarioID, familia string, expira time.Time, usado, revocado bool) *TokenRefresco {
+	return &TokenRefresco{hash: hash, usuarioID: usuarioID, familia: familia, expira: expira, usado: usado, revocado: revocado}
+}
+
+func (t *TokenRefresco) GetHash() string      { return t.hash }
+func (t *TokenRefresco) GetUsuarioID() string { return t.usuarioID }
+func (t *TokenRefresco) GetFamilia() string   { return t.familia }
+func (t *TokenRefresco) GetExpira() time.Time { return t.expira }
+func (t *TokenRefresco) EstaUsado() bool      { return t.usado }
+func (t *TokenRefresco) EstaRevocado() bool   { return t.revocado }
+
+// AlmacenRefresco persiste los refresh tokens y su lista de revocación.
+type AlmacenRefresco interface {
+	SaveRefreshToken(t *TokenRefresco) error
+	LoadRefreshToken(hash string) (*TokenRefresco, error)
+	// MarkRefreshTokenUsed debe ser atómico: si el token ya estaba usado
+	// devuelve ErrReutilizacionRefresco.
+	MarkRefreshTokenUsed(hash string) error
+	RevokeRefreshFamily(familia string) error
+	RevokeRefreshTokensByUserID(uid string) error
+}
+
+// EmisorTokens firma access tokens JWT y rota refresh tokens opacos.
+type EmisorTokens struct {
+	mu          sync.RWMutex
+	emisor      string
+	claves      map[string]*ClaveFirma
+	activa      *ClaveFirma
+	almacen     AlmacenRefresco
+	ttlAcceso   time.Duration
+	ttlRefresco time.Duration
+}
+
+// NuevoEmisorTokens crea un emisor que firma con `activa` y acepta además
+// tokens firmados con las claves `anteriores` (rotación por `kid`).
+func NuevoEmisorTokens(emisor string, almacen AlmacenRefresco, activa *ClaveFirma, anteriores ...*ClaveFirma) *EmisorTokens {
+	e := &EmisorTokens{
+		emisor:      emisor,
+		claves:      make(map[string]*ClaveFirma),
+		almacen:     almacen,
+		ttlAcceso:   15 * time.Minute,
+		ttlRefresco: 30 * 24 * time.Hour,
+	}
+	for _, c := range anteriores {
+		e.claves[c.kid] = c
+	}
+	e.RotarClave(activa)
+	return e
+}
+
+// RotarClave firma los tokens nuevos con `nueva`; las claves previas siguen
+// verificando hasta que se retiren.
+func (e *EmisorTokens) RotarClave(nueva *ClaveFirma) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.claves[nueva.kid] = nueva
+	e.activa = nueva
+}
+
+// RetirarClave deja de aceptar tokens firmados con el `kid` indicado.
+func (e *EmisorTokens) RetirarClave(kid string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.activa == nil || e.activa.kid != kid {
+		delete(e.claves, kid)
+	}
+}
+
+// EmitirPar emite un access token y abre una nueva familia de refresh tokens.
+func (e *EmisorTokens) EmitirPar(u *Usuario) (*ParTokens, error) {
+	return e.emitir(u.GetID(), u.GetRoles(), generarTokenAleatorio(16))
+}
+
+// VerificarAcceso valida firma, `kid`, emisor y expiración de un access token.
+func (e *EmisorTokens) VerificarAcceso(token string) (*ClaimsAcceso, error) {
+	claims := &ClaimsAcceso{}
+	_, err := jwt.ParseWithClaims(token, claims, e.claveVerificacion,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(e.emisor),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrTokenInvalido
+	}
+	return claims, nil
+}
+
+// Refrescar cambia un refresh token válido por un par nuevo. Presentar un
+// token ya usado o revocado se trata como robo y revoca toda la familia.
+func (e *EmisorTokens) Refrescar(refresh string, roles func(uid string) ([]Rol, error)) (*ParTokens, error) {
+	t, err := e.almacen.LoadRefreshToken(hashRefresco(refresh))
+	if err != nil {
+		return nil, ErrTokenInvalido
+	}
+	if t.usado || t.revocado {
+		if err := e.almacen.RevokeRefreshFamily(t.familia); err != nil {
+			return nil, err
+		}
+		return nil, ErrReutilizacionRefresco
+	}
+	if time.Now().After(t.expira) {
+		return nil, ErrTokenInvalido
+	}
+	if err := e.almacen.MarkRefreshTokenUsed(t.hash); err != nil {
+		if errors.Is(err, ErrReutilizacionRefresco) {
+			e.almacen.RevokeRefreshFamily(t.familia)
+		}
+		return nil, err
+	}
+	r, err := roles(t.usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	return e.emitir(t.usuarioID, r, t.familia)
+}
+
+// RevocarRefresco revoca la familia completa del refresh token (cierre de sesión).
+func (e *EmisorTokens) RevocarRefresco(refresh string) error {
+	t, err := e.almacen.LoadRefreshToken(hashRefresco(refresh))
+	if err != nil {
+		return ErrTokenInvalido
+	}
+	return e.almacen.RevokeRefreshFamily(t.familia)
+}
+
+// RevocarTodos revoca todos los refresh tokens del usuario.
+func (e *EmisorTokens) RevocarTodos(uid string) error {
+	return e.almacen.RevokeRefreshTokensByUserID(uid)
+}
+
+func (e *EmisorTokens) emitir(uid string, roles []Rol, familia string) (*ParTokens, error) {
+	e.mu.RLock()
+	clave := e.activa
+	e.mu.RUnlock()
+
+	ahora := time.Now()
+	claims := &ClaimsAcceso{
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    e.emisor,
+			Subject:   uid,
+			IssuedAt:  jwt.NewNumericDate(ahora),
+			ExpiresAt: jwt.NewNumericDate(ahora.Add(e.ttlAcceso)),
+			ID:        generarTokenAleatorio(8),
+		},
+	}
+	tok := jwt.NewWithClaims(clave.metodo, claims)
+	tok.Header["kid"] = clave.kid
+	acceso, err := tok.SignedString(clave.firmar)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := generarTokenAleatorio(32)
+	registro := &TokenRefresco{hash: hashRefresco(refresh), usuarioID: uid, familia: familia, expira: ahora.Add(e.ttlRefresco)}
+	if err := e.almacen.SaveRefreshToken(registro); err != nil {
+		return nil, err
+	}
+	return &ParTokens{AccessToken: acceso, RefreshToken: refresh, TokenType: "Bearer", ExpiresIn: int(e.ttlAcceso.Seconds())}, nil
+}
+
+func (e *EmisorTokens) claveVerificacion(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	e.mu.RLock()
+	c, ok := e.claves[kid]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("kid desconocido %q", kid)
+	}
+	// El algoritmo lo fija la clave, nunca la cabecera del token.
+	if t.Method.Alg() != c.metodo.Alg() {
+		return nil, fmt.Errorf("algoritmo %s no corresponde al kid %q", t.Method.Alg(), kid)
+	}
+	return c.verificador, nil
+}
+
+func hashRefresco(refresh string) string {
+	h := sha256.Sum256([]byte(strings.TrimSpace(refresh)))
+	return hex.EncodeToString(h[:])
+}
+
+// AlmacenRefrescoMemoria guarda los refresh tokens en memoria.
+type AlmacenRefrescoMemoria struct {
+	mu     sync.Mutex
+	tokens map[string]*TokenRefresco // Clave: hash
+}
+
+// NuevoAlmacenRefrescoMemoria crea un almacén de refresh tokens vacío.
+func NuevoAlmacenRefrescoMemoria() *AlmacenRefrescoMemoria {
+	return &AlmacenRefrescoMemoria{tokens: make(map[string]*TokenRefresco)}
+}
+
+func (m *AlmacenRefrescoMemoria) SaveRefreshToken(t *TokenRefresco) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copia := *t
+	m.tokens[t.hash] = &copia
+	return nil
+}
+
+func (m *AlmacenRefrescoMemoria) LoadRefreshToken(hash string) (*TokenRefresco, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[hash]
+	if !ok {
+		return nil, ErrTokenInvalido
+	}
+	copia := *t
+	return &copia, nil
+}
+
+func (m *AlmacenRefrescoMemoria) MarkRefreshTokenUsed(hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[hash]
+	if !ok {
+		return ErrTokenInvalido
+	}
+	if t.usado {
+		return ErrReutilizacionRefresco
+	}
+	t.usado = true
+	return nil
+}
+
+func (m *AlmacenRefrescoMemoria) RevokeRefreshFamily(familia string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tokens {
+		if t.familia == familia {
+			t.revocado = true
+		}
+	}
+	return nil
+}
+
+func (m *AlmacenRefrescoMemoria) RevokeRefreshTokensByUserID(uid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tokens {
+		if t.usuarioID == uid {
+			t.revocado = true
+		}
+	}
+	return nil
+}
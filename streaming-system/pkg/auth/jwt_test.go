@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func usuarioDePrueba(t *testing.T) *Usuario {
+	t.Helper()
+	u, err := NuevoUsuario("42", "ana", "ana@example.com", "contraseña-segura")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.AsignarRoles([]Rol{RolContentEditor})
+	return u
+}
+
+func rolesFijos(roles ...Rol) func(string) ([]Rol, error) {
+	return func(string) ([]Rol, error) { return roles, nil }
+}
+
+func TestVerificarAcceso(t *testing.T) {
+	_, privada, _ := ed25519.GenerateKey(nil)
+	hs := NuevaClaveHS256("hs-1", []byte("secreto-de-prueba"))
+	ed := NuevaClaveEd25519("ed-1", privada)
+	u := usuarioDePrueba(t)
+
+	casos := []struct {
+		nombre string
+		token  func(t *testing.T) string
+		valido bool
+	}{
+		{"firmado con la clave activa", func(t *testing.T) string {
+			return emitirAcceso(t, NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), ed), u)
+		}, true},
+		{"firmado con una clave anterior", func(t *testing.T) string {
+			return emitirAcceso(t, NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), hs), u)
+		}, true},
+		{"otro emisor", func(t *testing.T) string {
+			return emitirAcceso(t, NuevoEmisorTokens("otro", NuevoAlmacenRefrescoMemoria(), ed), u)
+		}, false},
+		{"expirado", func(t *testing.T) string {
+			e := NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), ed)
+			e.ttlAcceso = -time.Minute
+			return emitirAcceso(t, e, u)
+		}, false},
+		{"kid desconocido", func(t *testing.T) string {
+			return emitirAcceso(t, NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), NuevaClaveHS256("hs-2", []byte("x"))), u)
+		}, false},
+		{"HS256 con el kid de la clave Ed25519", func(t *testing.T) string {
+			// Confusión de algoritmo: firmar HMAC con la clave pública como secreto.
+			tok := jwt.NewWithClaims(jwt.SigningMethodHS256, &ClaimsAcceso{RegisteredClaims: jwt.RegisteredClaims{
+				Issuer: "streamgo", Subject: "42", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			}})
+			tok.Header["kid"] = "ed-1"
+			s, err := tok.SignedString([]byte(privada.Public().(ed25519.PublicKey)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}, false},
+		{"payload alterado", func(t *testing.T) string {
+			partes := strings.Split(emitirAcceso(t, NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), ed), u), ".")
+			claims, _ := base64.RawURLEncoding.DecodeString(partes[1])
+			partes[1] = base64.RawURLEncoding.EncodeToString(bytes.Replace(claims, []byte(`"sub":"42"`), []byte(`"sub":"1"`), 1))
+			return strings.Join(partes, ".")
+		}, false},
+	}
+
+	verificador := NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), ed, hs)
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			claims, err := verificador.VerificarAcceso(c.token(t))
+			if c.valido {
+				if err != nil {
+					t.Fatalf("VerificarAcceso: %v", err)
+				}
+				if claims.Subject != "42" || len(claims.Roles) != 1 || claims.Roles[0] != RolContentEditor {
+					t.Fatalf("claims = %+v", claims)
+				}
+				return
+			}
+			if !errors.Is(err, ErrTokenInvalido) {
+				t.Fatalf("error = %v, se esperaba ErrTokenInvalido", err)
+			}
+		})
+	}
+}
+
+func TestRetirarClave(t *testing.T) {
+	anterior := NuevaClaveHS256("v1", []byte("uno"))
+	e := NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), anterior)
+	u := usuarioDePrueba(t)
+	token := emitirAcceso(t, e, u)
+
+	e.RotarClave(NuevaClaveHS256("v2", []byte("dos")))
+	if _, err := e.VerificarAcceso(token); err != nil {
+		t.Fatalf("tras rotar, la clave anterior debe seguir verificando: %v", err)
+	}
+	e.RetirarClave("v1")
+	if _, err := e.VerificarAcceso(token); !errors.Is(err, ErrTokenInvalido) {
+		t.Fatalf("tras retirar v1: error = %v", err)
+	}
+	e.RetirarClave("v2")
+	if _, err := e.VerificarAcceso(emitirAcceso(t, e, u)); err != nil {
+		t.Fatalf("la clave activa no se puede retirar: %v", err)
+	}
+}
+
+func TestRefrescar(t *testing.T) {
+	// Cada paso presenta uno de los refresh tokens emitidos hasta entonces
+	// (0 = el del inicio de sesión) y espera un resultado.
+	type paso struct {
+		presentar int
+		err       error
+	}
+	casos := []struct {
+		nombre string
+		pasos  []paso
+	}{
+		{"rotación normal", []paso{{0, nil}, {1, nil}, {2, nil}}},
+		{"reutilizar el token ya rotado revoca la familia", []paso{{0, nil}, {0, ErrReutilizacionRefresco}, {1, ErrReutilizacionRefresco}}},
+		{"reutilización tardía también revoca el último", []paso{{0, nil}, {1, nil}, {0, ErrReutilizacionRefresco}, {2, ErrReutilizacionRefresco}}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			e := NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), NuevaClaveHS256("k", []byte("s")))
+			par, err := e.EmitirPar(usuarioDePrueba(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			emitidos := []string{par.RefreshToken}
+			for i, p := range c.pasos {
+				nuevo, err := e.Refrescar(emitidos[p.presentar], rolesFijos(RolContentEditor))
+				if !errors.Is(err, p.err) {
+					t.Fatalf("paso %d: error = %v, se esperaba %v", i, err, p.err)
+				}
+				if err == nil {
+					if _, err := e.VerificarAcceso(nuevo.AccessToken); err != nil {
+						t.Fatalf("paso %d: access token rotado inválido: %v", i, err)
+					}
+					emitidos = append(emitidos, nuevo.RefreshToken)
+				}
+			}
+		})
+	}
+}
+
+func TestRefrescarRevocadoOExpirado(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		preparar func(t *testing.T, e *EmisorTokens, par *ParTokens)
+		err      error
+	}{
+		{"desconocido", func(t *testing.T, e *EmisorTokens, par *ParTokens) { par.RefreshToken = "no-existe" }, ErrTokenInvalido},
+		{"expirado", func(t *testing.T, e *EmisorTokens, par *ParTokens) {
+			e.ttlRefresco = -time.Minute
+			nuevo, _ := e.EmitirPar(usuarioDePrueba(t))
+			*par = *nuevo
+		}, ErrTokenInvalido},
+		{"familia revocada al cerrar sesión", func(t *testing.T, e *EmisorTokens, par *ParTokens) { e.RevocarRefresco(par.RefreshToken) }, ErrReutilizacionRefresco},
+		{"todos los del usuario revocados", func(t *testing.T, e *EmisorTokens, par *ParTokens) { e.RevocarTodos("42") }, ErrReutilizacionRefresco},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			e := NuevoEmisorTokens("streamgo", NuevoAlmacenRefrescoMemoria(), NuevaClaveHS256("k", []byte("s")))
+			par, err := e.EmitirPar(usuarioDePrueba(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.preparar(t, e, par)
+			if _, err := e.Refrescar(par.RefreshToken, rolesFijos()); !errors.Is(err, c.err) {
+				t.Fatalf("error = %v, se esperaba %v", err, c.err)
+			}
+		})
+	}
+}
+
+func emitirAcceso(t *testing.T, e *EmisorTokens, u *Usuario) string {
+	t.Helper()
+	par, err := e.EmitirPar(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return par.AccessToken
+}
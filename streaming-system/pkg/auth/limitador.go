@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDemasiadosIntentos indica que la cuenta o la IP está en espera o bloqueada.
+var ErrDemasiadosIntentos = errors.New("demasiados intentos fallidos")
+
+// EstadoIntentos es el contador de fallos de una clave (cuenta o IP).
+type EstadoIntentos struct {
+	clave          string
+	fallos         int
+	ultimoFallo    time.Time
+	bloqueadoHasta time.Time
+}
+
+// RecreateEstadoIntentosFromDB reconstruye un contador persistido.
+func RecreateEstadoIntentosFromDB(clave string, fallos int, ultimoFallo, bloqueadoHasta time.Time) *EstadoIntentos {
+	return &EstadoIntentos{clave: clave, fallos: fallos, ultimoFallo: ultimoFallo, bloqueadoHasta: bloqueadoHasta}
+}
+
+func (e *EstadoIntentos) GetClave() string             { return e.clave }
+func (e *EstadoIntentos) GetFallos() int               { return e.fallos }
+func (e *EstadoIntentos) GetUltimoFallo() time.Time    { return e.ultimoFallo }
+func (e *EstadoIntentos) GetBloqueadoHasta() time.Time { return e.bloqueadoHasta }
+
+// EventoBloqueo registra el momento en que una clave quedó bloqueada.
+type EventoBloqueo struct {
+	clave  string
+	fallos int
+	fecha  time.Time
+	hasta  time.Time
+}
+
+// RecreateEventoBloqueoFromDB reconstruye un evento persistido.
+func RecreateEventoBloqueoFromDB(clave string, fallos int, fecha, hasta time.Time) *EventoBloqueo {
+	return &EventoBloqueo{clave: clave, fallos: fallos, fecha: fecha, hasta: hasta}
+}
+
+func (e *EventoBloqueo) GetClave() string    { return e.clave }
+func (e *EventoBloqueo) GetFallos() int      { return e.fallos }
+func (e *EventoBloqueo) GetFecha() time.Time { return e.fecha }
+func (e *EventoBloqueo) GetHasta() time.Time { return e.hasta }
+
+// AlmacenIntentos es el backend del limitador (memoria o MySQL).
+type AlmacenIntentos interface {
+	// LoadLoginAttempts devuelve el estado de la clave o nil si no tiene fallos.
+	LoadLoginAttempts(clave string) (*EstadoIntentos, error)
+	// RecordLoginFailure suma un fallo (reiniciando la cuenta si el último
+	// fallo es anterior a la ventana) y devuelve el total.
+	RecordLoginFailure(clave string, ahora time.Time, ventana time.Duration) (int, error)
+	LockLogin(clave string, hasta time.Time) error
+	ResetLoginAttempts(clave string) error
+	RecordLockout(e *EventoBloqueo) error
+	LoadActiveLocks(ahora time.Time) ([]*EstadoIntentos, error)
+	LoadLockoutEvents(limite int) ([]*EventoBloqueo, error)
+}
+
+// PoliticaIntentos define el backoff y el bloqueo para un tipo de clave.
+type PoliticaIntentos struct {
+	SinEspera  int           // fallos permitidos antes de empezar a esperar
+	EsperaBase time.Duration // espera tras el primer fallo con backoff; se duplica en cada uno
+	MaxFallos  int           // fallos que provocan el bloqueo temporal
+	Bloqueo    time.Duration // duración del bloqueo temporal
+	Ventana    time.Duration // sin fallos durante este tiempo, el contador vuelve a cero
+}
+
+// LimitadorIntentos aplica backoff exponencial y bloqueo temporal por cuenta y por IP.
+type LimitadorIntentos struct {
+	almacen    AlmacenIntentos
+	porCuenta  PoliticaIntentos
+	porIP      PoliticaIntentos
+	alBloquear func(e *EventoBloqueo) // opcional, p. ej. para auditoría
+}
+
+// NuevoLimitadorIntentos crea un limitador con las políticas por defecto:
+// 10 fallos por cuenta o 50 por IP provocan un bloqueo de 15 minutos.
+func NuevoLimitadorIntentos(almacen AlmacenIntentos) *LimitadorIntentos {
+	return &LimitadorIntentos{
+		almacen:   almacen,
+		porCuenta: PoliticaIntentos{SinEspera: 3, EsperaBase: time.Second, MaxFallos: 10, Bloqueo: 15 * time.Minute, Ventana: time.Hour},
+		porIP:     PoliticaIntentos{SinEspera: 20, EsperaBase: time.Second, MaxFallos: 50, Bloqueo: 15 * time.Minute, Ventana: time.Hour},
+	}
+}
+
+// AlBloquear registra una función que se invoca en cada bloqueo nuevo.
+func (l *LimitadorIntentos) AlBloquear(f func(e *EventoBloqueo)) { l.alBloquear = f }
+
+// ClaveCuenta, ClaveIP, ClaveMFA y ClavePIN construyen las claves del limitador.
+func ClaveCuenta(email string) string { return "cuenta:" + strings.ToLower(strings.TrimSpace(email)) }
+func ClaveIP(ip string) string        { return "ip:" + ip }
+func ClaveMFA(uid string) string      { return "mfa:" + uid }
+func ClavePIN(perfilID int) string    { return "pin:" + strconv.Itoa(perfilID) }
+
+// Verificar devuelve ErrDemasiadosIntentos y el tiempo restante si alguna de
+// las claves está en espera o bloqueada.
+func (l *LimitadorIntentos) Verificar(claves ...string) (time.Duration, error) {
+	return l.verificarEn(time.Now(), claves...)
+}
+
+func (l *LimitadorIntentos) verificarEn(ahora time.Time, claves ...string) (time.Duration, error) {
+	var espera time.Duration
+	for _, c := range claves {
+		e, err := l.almacen.LoadLoginAttempts(c)
+		if err != nil {
+			return 0, err
+		}
+		if e != nil && e.bloqueadoHasta.After(ahora) {
+			espera = max(espera, e.bloqueadoHasta.Sub(ahora))
+		}
+	}
+	if espera > 0 {
+		return espera, ErrDemasiadosIntentos
+	}
+	return 0, nil
+}
+
+// RegistrarFallo suma un fallo a cada clave y aplica la espera o el bloqueo
+// que corresponda según su política.
+func (l *LimitadorIntentos) RegistrarFallo(claves ...string) error {
+	return l.registrarFalloEn(time.Now(), claves...)
+}
+
+func (l *LimitadorIntentos) registrarFalloEn(ahora time.Time, claves ...string) error {
+	for _, c := range claves {
+		p := l.politica(c)
+		fallos, err := l.almacen.RecordLoginFailure(c, ahora, p.Ventana)
+		if err != nil {
+			return err
+		}
+		switch {
+		case fallos >= p.MaxFallos:
+			hasta := ahora.Add(p.Bloqueo)
+			if err := l.almacen.LockLogin(c, hasta); err != nil {
+				return err
+			}
+			// Solo se registra el evento al cruzar el umbral, no en cada fallo posterior.
+			if fallos == p.MaxFallos {
+				ev := &EventoBloqueo{clave: c, fallos: fallos, fecha: ahora, hasta: hasta}
+				if err := l.almacen.RecordLockout(ev); err != nil {
+					return err
+				}
+				if l.alBloquear != nil {
+					l.alBloquear(ev)
+				}
+			}
+		case fallos > p.SinEspera:
+			espera := p.EsperaBase << min(fallos-p.SinEspera-1, 20)
+			if err := l.almacen.LockLogin(c, ahora.Add(min(espera, p.Bloqueo))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reiniciar borra los fallos de la clave (inicio de sesión correcto o
+// desbloqueo manual de un administrador).
+func (l *LimitadorIntentos) Reiniciar(clave string) error {
+	return l.almacen.ResetLoginAttempts(clave)
+}
+
+// BloqueosActivos devuelve las claves bloqueadas en este momento.
+func (l *LimitadorIntentos) BloqueosActivos() ([]*EstadoIntentos, error) {
+	return l.almacen.LoadActiveLocks(time.Now())
+}
+
+// EventosRecientes devuelve los últimos eventos de bloqueo.
+func (l *LimitadorIntentos) EventosRecientes(limite int) ([]*EventoBloqueo, error) {
+	return l.almacen.LoadLockoutEvents(limite)
+}
+
+func (l *LimitadorIntentos) politica(clave string) PoliticaIntentos {
+	if strings.HasPrefix(clave, "ip:") {
+		return l.porIP
+	}
+	return l.porCuenta
+}
+
+// AlmacenIntentosMemoria es el backend por defecto, válido para una sola instancia.
+// Cada IP o correo probado deja un contador, así que los que ya no cuentan
+// (fuera de la ventana y sin bloqueo vigente) se descartan al registrar fallos.
+type AlmacenIntentosMemoria struct {
+	mu       sync.Mutex
+	estados  map[string]*EstadoIntentos
+	eventos  []*EventoBloqueo
+	ventana  time.Duration // la mayor ventana recibida, para limpiar sin borrar contadores vigentes
+	limpieza time.Time     // última limpieza
+}
+
+// NuevoAlmacenIntentosMemoria crea un backend en memoria vacío.
+func NuevoAlmacenIntentosMemoria() *AlmacenIntentosMemoria {
+	return &AlmacenIntentosMemoria{estados: make(map[string]*EstadoIntentos)}
+}
+
+func (m *AlmacenIntentosMemoria) LoadLoginAttempts(clave string) (*EstadoIntentos, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.estados[clave]; ok {
+		copia := *e
+		return &copia, nil
+	}
+	return nil, nil
+}
+
+func (m *AlmacenIntentosMemoria) RecordLoginFailure(clave string, ahora time.Time, ventana time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ventana = max(m.ventana, ventana)
+	if ahora.Sub(m.limpieza) > m.ventana {
+		m.limpiar(ahora)
+	}
+	e, ok := m.estados[clave]
+	if !ok || ahora.Sub(e.ultimoFallo) > ventana {
+		e = &EstadoIntentos{clave: clave}
+		m.estados[clave] = e
+	}
+	e.fallos++
+	e.ultimoFallo = ahora
+	return e.fallos, nil
+}
+
+// limpiar borra los contadores caducados. Se llama como mucho una vez por
+// ventana, así que el coste se reparte entre muchos fallos.
+func (m *AlmacenIntentosMemoria) limpiar(ahora time.Time) {
+	for clave, e := range m.estados {
+		if ahora.Sub(e.ultimoFallo) > m.ventana && !e.bloqueadoHasta.After(ahora) {
+			delete(m.estados, clave)
+		}
+	}
+	m.limpieza = ahora
+}
+
+func (m *AlmacenIntentosMemoria) LockLogin(clave string, hasta time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.estados[clave]; ok {
+		e.bloqueadoHasta = hasta
+	}
+	return nil
+}
+
+func (m *AlmacenIntentosMemoria) ResetLoginAttempts(clave string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.estados, clave)
+	return nil
+}
+
+func (m *AlmacenIntentosMemoria) RecordLockout(e *EventoBloqueo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventos = append(m.eventos, e)
+	return nil
+}
+
+func (m *AlmacenIntentosMemoria) LoadActiveLocks(ahora time.Time) ([]*EstadoIntentos, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*EstadoIntentos
+	for _, e := range m.estados {
+		if e.bloqueadoHasta.After(ahora) {
+			copia := *e
+			res = append(res, &copia)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].clave < res[j].clave })
+	return res, nil
+}
+
+func (m *AlmacenIntentosMemoria) LoadLockoutEvents(limite int) ([]*EventoBloqueo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*EventoBloqueo
+	for i := len(m.eventos) - 1; i >= 0 && len(res) < limite; i-- {
+		res = append(res, m.eventos[i])
+	}
+	return res, nil
+}
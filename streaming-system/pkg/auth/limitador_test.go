@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// limitadorPrueba usa una política corta para recorrer todo el calendario:
+// 2 fallos libres, esperas de 1, 2 y 4 s, tope de 5 s y bloqueo al 7.º fallo.
+func limitadorPrueba() (*LimitadorIntentos, *AlmacenIntentosMemoria) {
+	almacen := NuevoAlmacenIntentosMemoria()
+	politica := PoliticaIntentos{SinEspera: 2, EsperaBase: time.Second, MaxFallos: 7, Bloqueo: 5 * time.Second, Ventana: time.Hour}
+	return &LimitadorIntentos{almacen: almacen, porCuenta: politica, porIP: politica}, almacen
+}
+
+func TestLimitadorCalendario(t *testing.T) {
+	l, almacen := limitadorPrueba()
+	var eventos []*EventoBloqueo
+	l.AlBloquear(func(e *EventoBloqueo) { eventos = append(eventos, e) })
+	clave := ClaveCuenta(" Ana@Example.com ")
+	inicio := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	casos := []struct {
+		fallos  int
+		espera  time.Duration
+		eventos int
+	}{
+		{1, 0, 0},
+		{2, 0, 0},
+		{3, time.Second, 0},
+		{4, 2 * time.Second, 0},
+		{5, 4 * time.Second, 0},
+		{6, 5 * time.Second, 0}, // 8 s recortados al bloqueo
+		{7, 5 * time.Second, 1}, // bloqueo: único evento
+		{8, 5 * time.Second, 1},
+		{9, 5 * time.Second, 1},
+	}
+	for i, c := range casos {
+		ahora := inicio.Add(time.Duration(i) * time.Minute)
+		if err := l.registrarFalloEn(ahora, clave); err != nil {
+			t.Fatal(err)
+		}
+		e, _ := almacen.LoadLoginAttempts("cuenta:ana@example.com")
+		if e == nil || e.GetFallos() != c.fallos {
+			t.Fatalf("fallo %d: estado %+v", c.fallos, e)
+		}
+		espera, err := l.verificarEn(ahora, clave)
+		if espera != c.espera || (c.espera > 0) != errors.Is(err, ErrDemasiadosIntentos) {
+			t.Errorf("fallo %d: espera %v (%v), se esperaba %v", c.fallos, espera, err, c.espera)
+		}
+		if len(eventos) != c.eventos {
+			t.Errorf("fallo %d: %d eventos de bloqueo, se esperaban %d", c.fallos, len(eventos), c.eventos)
+		}
+	}
+	if registrados, _ := l.EventosRecientes(10); len(registrados) != 1 || registrados[0].GetFallos() != 7 {
+		t.Fatalf("eventos registrados: %+v", registrados)
+	}
+
+	// Pasada la espera se puede volver a intentar.
+	if _, err := l.verificarEn(inicio.Add(time.Hour), clave); err != nil {
+		t.Fatalf("tras el bloqueo: %v", err)
+	}
+	if err := l.Reiniciar(clave); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := almacen.LoadLoginAttempts(clave); e != nil {
+		t.Fatalf("Reiniciar conservó %+v", e)
+	}
+}
+
+func TestLimitadorVentana(t *testing.T) {
+	l, almacen := limitadorPrueba()
+	clave := ClaveIP("203.0.113.7")
+	inicio := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		l.registrarFalloEn(inicio.Add(time.Duration(i)*time.Second), clave)
+	}
+	// Dentro de la ventana el contador sigue; fuera, vuelve a empezar sin espera.
+	casos := []struct {
+		despues time.Duration
+		fallos  int
+	}{
+		{time.Hour, 5},
+		{2*time.Hour + time.Second, 1},
+	}
+	for _, c := range casos {
+		ahora := inicio.Add(c.despues)
+		l.registrarFalloEn(ahora, clave)
+		if e, _ := almacen.LoadLoginAttempts(clave); e.GetFallos() != c.fallos {
+			t.Fatalf("a los %v: %d fallos, se esperaban %d", c.despues, e.GetFallos(), c.fallos)
+		}
+	}
+	if espera, err := l.verificarEn(inicio.Add(2*time.Hour+time.Second), clave); err != nil {
+		t.Fatalf("tras reiniciar la ventana: espera %v", espera)
+	}
+}
+
+func TestAlmacenIntentosMemoriaLimpieza(t *testing.T) {
+	m := NuevoAlmacenIntentosMemoria()
+	inicio := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ventana := time.Hour
+	m.RecordLoginFailure("caducado", inicio, ventana)
+	m.RecordLoginFailure("bloqueado", inicio, ventana)
+	m.LockLogin("bloqueado", inicio.Add(3*time.Hour))
+	m.RecordLoginFailure("reciente", inicio.Add(30*time.Minute), ventana)
+
+	m.RecordLoginFailure("nuevo", inicio.Add(ventana+time.Minute), ventana)
+	casos := []struct {
+		clave    string
+		conserva bool
+	}{
+		{"caducado", false},
+		{"bloqueado", true},
+		{"reciente", true},
+		{"nuevo", true},
+	}
+	for _, c := range casos {
+		if e, _ := m.LoadLoginAttempts(c.clave); (e != nil) != c.conserva {
+			t.Errorf("%s: estado %+v, se esperaba conservarlo: %v", c.clave, e, c.conserva)
+		}
+	}
+	if len(m.estados) != 3 {
+		t.Fatalf("%d contadores en memoria, se esperaban 3", len(m.estados))
+	}
+}
@@ -0,0 +1,419 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrProveedorOIDC cubre fallos de descubrimiento, del token endpoint y del JWKS.
+	ErrProveedorOIDC = errors.New("el proveedor de identidad no respondió correctamente")
+	// ErrIDTokenInvalido indica un id_token con firma, emisor, audiencia, nonce o fechas incorrectos.
+	ErrIDTokenInvalido = errors.New("el proveedor de identidad devolvió un id_token inválido")
+	// ErrSolicitudOIDCInvalida indica un state que no coincide o una solicitud expirada.
+	ErrSolicitudOIDCInvalida = errors.New("la solicitud de inicio de sesión expiró o no es válida")
+	// ErrEmailExternoNoVerificado indica que el proveedor no garantiza el correo de la identidad.
+	ErrEmailExternoNoVerificado = errors.New("el proveedor de identidad no confirma que tu correo esté verificado")
+)
+
+// VigenciaSolicitudOIDC es el tiempo que tiene el usuario para volver del proveedor.
+const VigenciaSolicitudOIDC = 10 * time.Minute
+
+// intervaloRecargaJWKS evita que un kid desconocido fuerce una descarga del JWKS en cada login.
+const intervaloRecargaJWKS = time.Minute
+
+// ConfigOIDC describe el cliente registrado en el proveedor de identidad.
+type ConfigOIDC struct {
+	Nombre       string // texto del botón en la página de login
+	Issuer       string
+	ClientID     string
+	ClientSecret string // vacío para un cliente público (solo PKCE)
+	RedirectURL  string
+	Scopes       []string // por defecto openid, email y profile
+}
+
+// documentoDescubrimiento es la parte de /.well-known/openid-configuration que se usa.
+type documentoDescubrimiento struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IdentidadOIDC es la identidad externa validada a partir del id_token.
+type IdentidadOIDC struct {
+	Issuer          string
+	Subject         string
+	Email           string
+	EmailVerificado bool
+	Nombre          string
+}
+
+// SolicitudOIDC guarda lo necesario para validar la vuelta del proveedor:
+// state (CSRF), nonce (repetición del id_token) y el verificador PKCE.
+type SolicitudOIDC struct {
+	State       string
+	Nonce       string
+	Verificador string
+	expira      time.Time
+}
+
+// NuevaSolicitudOIDC genera valores aleatorios para un inicio de sesión.
+func NuevaSolicitudOIDC(ahora time.Time) SolicitudOIDC {
+	return SolicitudOIDC{
+		State:       generarTokenAleatorio(16),
+		Nonce:       generarTokenAleatorio(16),
+		Verificador: base64.RawURLEncoding.EncodeToString([]byte(generarTokenAleatorio(32))),
+		expira:      ahora.Add(VigenciaSolicitudOIDC),
+	}
+}
+
+// DesafioPKCE es el code_challenge S256 del verificador.
+func (s SolicitudOIDC) DesafioPKCE() string {
+	h := sha256.Sum256([]byte(s.Verificador))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// ClienteOIDC es el relying party: descubre el proveedor, construye la URL de
+// autorización y canjea el código por una identidad validada. El documento de
+// descubrimiento y las claves se cargan la primera vez que hacen falta.
+type ClienteOIDC struct {
+	cfg   ConfigOIDC
+	clave []byte // firma la cookie con la SolicitudOIDC en curso
+	http  *http.Client
+
+	mu             sync.Mutex
+	doc            *documentoDescubrimiento
+	claves         map[string]any // kid -> clave pública del JWKS
+	clavesCargadas time.Time
+}
+
+// NuevoClienteOIDC crea un cliente para la configuración indicada.
+func NuevoClienteOIDC(cfg ConfigOIDC, clave []byte) *ClienteOIDC {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &ClienteOIDC{cfg: cfg, clave: clave, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// GetNombre devuelve el texto a mostrar en el botón de login.
+func (c *ClienteOIDC) GetNombre() string { return c.cfg.Nombre }
+
+// URLAutorizacion devuelve la URL del proveedor a la que se redirige al usuario.
+func (c *ClienteOIDC) URLAutorizacion(ctx context.Context, s SolicitudOIDC) (string, error) {
+	doc, err := c.descubrir(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(c.cfg.Scopes, " ")},
+		"state":                 {s.State},
+		"nonce":                 {s.Nonce},
+		"code_challenge":        {s.DesafioPKCE()},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Canjear cambia el código de autorización por tokens y valida el id_token
+// contra la solicitud original.
+func (c *ClienteOIDC) Canjear(ctx context.Context, codigo string, s SolicitudOIDC) (*IdentidadOIDC, error) {
+	doc, err := c.descubrir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {codigo},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"code_verifier": {s.Verificador},
+	}
+	if c.cfg.ClientSecret == "" {
+		form.Set("client_id", c.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+	var resp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := c.pedirJSON(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || resp.IDToken == "" {
+		return nil, fmt.Errorf("%w: token endpoint %d %s %s", ErrProveedorOIDC, status, resp.Error, resp.ErrorDescription)
+	}
+	return c.validarIDToken(ctx, doc, resp.IDToken, s.Nonce)
+}
+
+// claimsIDToken son los claims del id_token que interesan.
+type claimsIDToken struct {
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"` // algunos proveedores lo envían como "true"
+	Name          string `json:"name"`
+	AZP           string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+func (c *ClienteOIDC) validarIDToken(ctx context.Context, doc *documentoDescubrimiento, crudo, nonce string) (*IdentidadOIDC, error) {
+	claims := &claimsIDToken{}
+	_, err := jwt.ParseWithClaims(crudo, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.clavePublica(ctx, doc, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIDTokenInvalido, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce", ErrIDTokenInvalido)
+	}
+	if len(claims.Audience) > 1 && claims.AZP != c.cfg.ClientID {
+		return nil, fmt.Errorf("%w: azp", ErrIDTokenInvalido)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: sub vacío", ErrIDTokenInvalido)
+	}
+	verificado := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verificado = v
+	case string:
+		verificado, _ = strconv.ParseBool(v)
+	}
+	return &IdentidadOIDC{
+		Issuer:          doc.Issuer,
+		Subject:         claims.Subject,
+		Email:           strings.TrimSpace(claims.Email),
+		EmailVerificado: verificado,
+		Nombre:          claims.Name,
+	}, nil
+}
+
+// descubrir carga el documento de descubrimiento una sola vez; si falla se
+// reintenta en la siguiente petición.
+func (c *ClienteOIDC) descubrir(ctx context.Context) (*documentoDescubrimiento, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.doc != nil {
+		return c.doc, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	doc := &documentoDescubrimiento{}
+	status, err := c.pedirJSON(req, doc)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: descubrimiento %d", ErrProveedorOIDC, status)
+	}
+	// El issuer anunciado debe ser exactamente el configurado (OIDC Discovery §4.3).
+	if strings.TrimSuffix(doc.Issuer, "/") != c.cfg.Issuer || doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: documento de descubrimiento incompleto o de otro issuer", ErrProveedorOIDC)
+	}
+	c.doc = doc
+	return doc, nil
+}
+
+// clavePublica busca el kid en el JWKS y lo vuelve a descargar si no lo
+// conoce, para soportar la rotación de claves del proveedor.
+func (c *ClienteOIDC) clavePublica(ctx context.Context, doc *documentoDescubrimiento, kid string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k := c.buscarClave(kid); k != nil {
+		return k, nil
+	}
+	if time.Since(c.clavesCargadas) < intervaloRecargaJWKS {
+		return nil, fmt.Errorf("kid %q desconocido", kid)
+	}
+	claves, err := c.cargarJWKS(ctx, doc.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	c.claves, c.clavesCargadas = claves, time.Now()
+	if k := c.buscarClave(kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("kid %q desconocido", kid)
+}
+
+func (c *ClienteOIDC) buscarClave(kid string) any {
+	if kid == "" && len(c.claves) == 1 {
+		for _, k := range c.claves {
+			return k
+		}
+	}
+	return c.claves[kid]
+}
+
+// claveJWK es una entrada del JWKS (RFC 7517).
+type claveJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (c *ClienteOIDC) cargarJWKS(ctx context.Context, uri string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []claveJWK `json:"keys"`
+	}
+	status, err := c.pedirJSON(req, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: jwks %d", ErrProveedorOIDC, status)
+	}
+	claves := make(map[string]any)
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Las claves de tipos no soportados se ignoran.
+		if pub, err := k.clavePublica(); err == nil {
+			claves[k.Kid] = pub
+		}
+	}
+	return claves, nil
+}
+
+func (k claveJWK) clavePublica() (any, error) {
+	dec := base64.RawURLEncoding.DecodeString
+	switch {
+	case k.Kty == "RSA":
+		n, err1 := dec(k.N)
+		e, err2 := dec(k.E)
+		if err := errors.Join(err1, err2); err != nil || len(e) > 4 {
+			return nil, errors.New("clave RSA mal formada")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err1 := dec(k.X)
+		y, err2 := dec(k.Y)
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, errors.New("clave EC mal formada")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("clave EC fuera de la curva")
+		}
+		return pub, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := dec(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("clave Ed25519 mal formada")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("tipo de clave %s no soportado", k.Kty)
+}
+
+func (c *ClienteOIDC) pedirJSON(req *http.Request, v any) (int, error) {
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrProveedorOIDC, err)
+	}
+	defer res.Body.Close()
+	cuerpo, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrProveedorOIDC, err)
+	}
+	if err := json.Unmarshal(cuerpo, v); err != nil && res.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("%w: respuesta no es JSON", ErrProveedorOIDC)
+	}
+	return res.StatusCode, nil
+}
+
+// SellarSolicitud serializa la solicitud en curso para guardarla en una
+// cookie; AbrirSolicitud la recupera comprobando firma y expiración.
+func (c *ClienteOIDC) SellarSolicitud(s SolicitudOIDC) string {
+	datos := s.State + "\n" + s.Nonce + "\n" + s.Verificador + "\n" + strconv.FormatInt(s.expira.Unix(), 10)
+	carga := base64.RawURLEncoding.EncodeToString([]byte(datos))
+	return carga + "." + c.firma(carga)
+}
+
+// AbrirSolicitud devuelve la solicitud sellada si su state coincide con el recibido.
+func (c *ClienteOIDC) AbrirSolicitud(sellada, state string, ahora time.Time) (SolicitudOIDC, error) {
+	carga, firma, ok := strings.Cut(sellada, ".")
+	if !ok || !hmac.Equal([]byte(firma), []byte(c.firma(carga))) {
+		return SolicitudOIDC{}, ErrSolicitudOIDCInvalida
+	}
+	datos, err := base64.RawURLEncoding.DecodeString(carga)
+	if err != nil {
+		return SolicitudOIDC{}, ErrSolicitudOIDCInvalida
+	}
+	partes := strings.Split(string(datos), "\n")
+	if len(partes) != 4 {
+		return SolicitudOIDC{}, ErrSolicitudOIDCInvalida
+	}
+	expira, err := strconv.ParseInt(partes[3], 10, 64)
+	if err != nil || ahora.Unix() > expira {
+		return SolicitudOIDC{}, ErrSolicitudOIDCInvalida
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(partes[0]), []byte(state)) != 1 {
+		return SolicitudOIDC{}, ErrSolicitudOIDCInvalida
+	}
+	return SolicitudOIDC{State: partes[0], Nonce: partes[1], Verificador: partes[2], expira: time.Unix(expira, 0)}, nil
+}
+
+func (c *ClienteOIDC) firma(carga string) string {
+	mac := hmac.New(sha256.New, c.clave)
+	mac.Write([]byte("oidc-solicitud:" + carga))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
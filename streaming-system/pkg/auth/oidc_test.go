@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// proveedorPrueba es un issuer OIDC mínimo: descubrimiento, JWKS con claves
+// Ed25519 y un token endpoint que exige el verificador PKCE del desafío
+// recibido en la autorización.
+type proveedorPrueba struct {
+	srv *httptest.Server
+
+	mu         sync.Mutex
+	publicadas map[string]ed25519.PublicKey // kid -> clave del JWKS
+	cargasJWKS int
+	desafio    string // code_challenge de la última autorización
+	idToken    string // lo que devuelve el token endpoint
+}
+
+func nuevoProveedorPrueba(t *testing.T) *proveedorPrueba {
+	p := &proveedorPrueba{publicadas: make(map[string]ed25519.PublicKey)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer": p.srv.URL, "authorization_endpoint": p.srv.URL + "/authorize",
+			"token_endpoint": p.srv.URL + "/token", "jwks_uri": p.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.cargasJWKS++
+		var claves []claveJWK
+		for kid, pub := range p.publicadas {
+			claves = append(claves, claveJWK{Kty: "OKP", Crv: "Ed25519", Kid: kid, Use: "sig", X: base64.RawURLEncoding.EncodeToString(pub)})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": claves})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		h := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.PostFormValue("code") != "codigo" || base64.RawURLEncoding.EncodeToString(h[:]) != p.desafio {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": p.idToken})
+	})
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+// nuevaClave genera una clave y, si publicar, la añade al JWKS.
+func (p *proveedorPrueba) nuevaClave(t *testing.T, kid string, publicar bool) ed25519.PrivateKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicar {
+		p.mu.Lock()
+		p.publicadas[kid] = pub
+		p.mu.Unlock()
+	}
+	return priv
+}
+
+// autorizar simula el paso por el proveedor: guarda el desafío PKCE de la
+// URL de autorización y el id_token que devolverá el canje.
+func (p *proveedorPrueba) autorizar(t *testing.T, c *ClienteOIDC, sol SolicitudOIDC, idToken string) {
+	t.Helper()
+	u, err := c.URLAutorizacion(context.Background(), sol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _ := url.Parse(u)
+	if q.Query().Get("code_challenge_method") != "S256" || q.Query().Get("nonce") != sol.Nonce || q.Query().Get("state") != sol.State {
+		t.Fatalf("URL de autorización incompleta: %s", u)
+	}
+	p.mu.Lock()
+	p.desafio, p.idToken = q.Query().Get("code_challenge"), idToken
+	p.mu.Unlock()
+}
+
+func firmarIDToken(t *testing.T, metodo jwt.SigningMethod, clave any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(metodo, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(clave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCanjearOIDC(t *testing.T) {
+	p := nuevoProveedorPrueba(t)
+	clave := p.nuevaClave(t, "k1", true)
+	ajena := p.nuevaClave(t, "k1", false) // mismo kid, clave no publicada
+	c := NuevoClienteOIDC(ConfigOIDC{Issuer: p.srv.URL + "/", ClientID: "streamgo", RedirectURL: "http://localhost/cb"}, []byte("clave"))
+
+	ahora := time.Now()
+	base := func(sol SolicitudOIDC) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": p.srv.URL, "sub": "u-1", "aud": "streamgo", "nonce": sol.Nonce,
+			"iat": ahora.Unix(), "exp": ahora.Add(5 * time.Minute).Unix(),
+			"email": " ana@example.com ", "email_verified": true, "name": "Ana",
+		}
+	}
+	casos := []struct {
+		nombre      string
+		claims      func(c jwt.MapClaims)
+		firmar      func(claims jwt.MapClaims) string
+		verificador string // si no está vacío, sustituye al de la solicitud
+		err         error
+		verificado  bool
+	}{
+		{nombre: "válido", verificado: true},
+		{nombre: "email_verified como texto", claims: func(c jwt.MapClaims) { c["email_verified"] = "true" }, verificado: true},
+		{nombre: "email_verified falso como texto", claims: func(c jwt.MapClaims) { c["email_verified"] = "false" }},
+		{nombre: "sin email_verified", claims: func(c jwt.MapClaims) { delete(c, "email_verified") }},
+		{nombre: "nonce distinto", claims: func(c jwt.MapClaims) { c["nonce"] = "otro" }, err: ErrIDTokenInvalido},
+		{nombre: "audiencia ajena", claims: func(c jwt.MapClaims) { c["aud"] = "otra-app" }, err: ErrIDTokenInvalido},
+		{nombre: "varias audiencias sin azp", claims: func(c jwt.MapClaims) { c["aud"] = []string{"streamgo", "otra-app"} }, err: ErrIDTokenInvalido},
+		{nombre: "varias audiencias con azp ajeno", claims: func(c jwt.MapClaims) {
+			c["aud"], c["azp"] = []string{"streamgo", "otra-app"}, "otra-app"
+		}, err: ErrIDTokenInvalido},
+		{nombre: "varias audiencias con azp propio", claims: func(c jwt.MapClaims) {
+			c["aud"], c["azp"] = []string{"streamgo", "otra-app"}, "streamgo"
+		}, verificado: true},
+		{nombre: "caducado", claims: func(c jwt.MapClaims) { c["exp"] = ahora.Add(-2 * time.Minute).Unix() }, err: ErrIDTokenInvalido},
+		{nombre: "sin exp", claims: func(c jwt.MapClaims) { delete(c, "exp") }, err: ErrIDTokenInvalido},
+		{nombre: "otro issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://otro.example.com" }, err: ErrIDTokenInvalido},
+		{nombre: "sin sub", claims: func(c jwt.MapClaims) { delete(c, "sub") }, err: ErrIDTokenInvalido},
+		{nombre: "firma con otra clave", firmar: func(claims jwt.MapClaims) string {
+			return firmarIDToken(t, jwt.SigningMethodEdDSA, ajena, "k1", claims)
+		}, err: ErrIDTokenInvalido},
+		{nombre: "HS256 no admitido", firmar: func(claims jwt.MapClaims) string {
+			return firmarIDToken(t, jwt.SigningMethodHS256, []byte("secreto"), "k1", claims)
+		}, err: ErrIDTokenInvalido},
+		{nombre: "verificador PKCE incorrecto", verificador: "otro-verificador", err: ErrProveedorOIDC},
+	}
+	for _, cs := range casos {
+		t.Run(cs.nombre, func(t *testing.T) {
+			sol := NuevaSolicitudOIDC(ahora)
+			claims := base(sol)
+			if cs.claims != nil {
+				cs.claims(claims)
+			}
+			firmado := ""
+			if cs.firmar != nil {
+				firmado = cs.firmar(claims)
+			} else {
+				firmado = firmarIDToken(t, jwt.SigningMethodEdDSA, clave, "k1", claims)
+			}
+			p.autorizar(t, c, sol, firmado)
+			if cs.verificador != "" {
+				sol.Verificador = cs.verificador
+			}
+
+			id, err := c.Canjear(context.Background(), "codigo", sol)
+			if !errors.Is(err, cs.err) {
+				t.Fatalf("error = %v, se esperaba %v", err, cs.err)
+			}
+			if err != nil {
+				return
+			}
+			esperada := IdentidadOIDC{Issuer: p.srv.URL, Subject: "u-1", Email: "ana@example.com", EmailVerificado: cs.verificado, Nombre: "Ana"}
+			if *id != esperada {
+				t.Fatalf("identidad %+v, se esperaba %+v", *id, esperada)
+			}
+		})
+	}
+}
+
+// TestClavesOIDCRotacion comprueba que un kid desconocido recarga el JWKS
+// como mucho una vez por intervaloRecargaJWKS.
+func TestClavesOIDCRotacion(t *testing.T) {
+	p := nuevoProveedorPrueba(t)
+	k1 := p.nuevaClave(t, "k1", true)
+	c := NuevoClienteOIDC(ConfigOIDC{Issuer: p.srv.URL, ClientID: "streamgo"}, []byte("clave"))
+	canjear := func(kid string, clave ed25519.PrivateKey) error {
+		sol := NuevaSolicitudOIDC(time.Now())
+		p.autorizar(t, c, sol, firmarIDToken(t, jwt.SigningMethodEdDSA, clave, kid, jwt.MapClaims{
+			"iss": p.srv.URL, "sub": "u-1", "aud": "streamgo", "nonce": sol.Nonce, "exp": time.Now().Add(time.Minute).Unix(),
+		}))
+		_, err := c.Canjear(context.Background(), "codigo", sol)
+		return err
+	}
+	cargas := func() int {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.cargasJWKS
+	}
+
+	if err := canjear("k1", k1); err != nil || cargas() != 1 {
+		t.Fatalf("primer canje: %v, %d cargas del JWKS", err, cargas())
+	}
+	// El proveedor rota a k2; dentro del intervalo no se vuelve a descargar.
+	k2 := p.nuevaClave(t, "k2", true)
+	for range 3 {
+		if err := canjear("k2", k2); !errors.Is(err, ErrIDTokenInvalido) || cargas() != 1 {
+			t.Fatalf("kid nuevo dentro del intervalo: %v, %d cargas", err, cargas())
+		}
+	}
+	// Pasado el intervalo, el kid desconocido provoca una recarga y se acepta.
+	c.mu.Lock()
+	c.clavesCargadas = time.Now().Add(-intervaloRecargaJWKS - time.Second)
+	c.mu.Unlock()
+	if err := canjear("k2", k2); err != nil || cargas() != 2 {
+		t.Fatalf("kid nuevo tras el intervalo: %v, %d cargas", err, cargas())
+	}
+	if err := canjear("k1", k1); err != nil || cargas() != 2 {
+		t.Fatalf("kid conocido: %v, %d cargas", err, cargas())
+	}
+}
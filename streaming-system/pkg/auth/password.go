@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrHashInvalido indica que un hash almacenado no se puede interpretar.
+var ErrHashInvalido = errors.New("formato de hash de contraseña inválido")
+
+// prefijoHashLegado es el esquema antiguo de NuevoUsuario ("HASH_" + contraseña).
+const prefijoHashLegado = "HASH_"
+
+// PasswordHasher genera y verifica hashes codificados de contraseñas.
+// El hash codificado incluye el algoritmo y sus parámetros.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash indica si el hash fue generado con otro algoritmo o con
+	// parámetros distintos a los actuales.
+	NeedsRehash(encoded string) bool
+}
+
+// hasherActual es el algoritmo usado para los hashes nuevos.
+var hasherActual PasswordHasher = NuevoArgon2idHasher()
+
+// UsarHasher cambia el algoritmo con el que se generan los hashes nuevos.
+// Los hashes existentes se migran al siguiente inicio de sesión.
+func UsarHasher(h PasswordHasher) {
+	hasherActual = h
+}
+
+// HashearContrasenia genera el hash codificado con el algoritmo actual.
+func HashearContrasenia(pass string) (string, error) {
+	return hasherActual.Hash(pass)
+}
+
+// VerificarContrasenia comprueba una contraseña contra cualquier esquema
+// soportado (argon2id, bcrypt, "HASH_" o texto plano) y devuelve además si
+// el hash debe regenerarse con el algoritmo actual.
+func VerificarContrasenia(encoded, pass string) (ok bool, rehash bool) {
+	var h PasswordHasher
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		h = NuevoArgon2idHasher()
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		h = NuevoBcryptHasher(bcrypt.DefaultCost)
+	case strings.HasPrefix(encoded, prefijoHashLegado):
+		return igualesTiempoConstante(encoded, prefijoHashLegado+pass), true
+	default:
+		// Filas sembradas con la contraseña en texto plano.
+		return encoded != "" && igualesTiempoConstante(encoded, pass), true
+	}
+	valido, err := h.Verify(encoded, pass)
+	if err != nil || !valido {
+		return false, false
+	}
+	return true, hasherActual.NeedsRehash(encoded)
+}
+
+func igualesTiempoConstante(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// --- bcrypt ---
+
+// BcryptHasher usa bcrypt; el coste va codificado en el propio hash.
+type BcryptHasher struct {
+	cost int
+}
+
+// NuevoBcryptHasher crea un hasher bcrypt con el coste indicado.
+func NuevoBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (b *BcryptHasher) Hash(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(h), nil
+}
+
+func (b *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.cost
+}
+
+// --- argon2id ---
+
+// Argon2idHasher usa argon2id con el formato PHC:
+// $argon2id$v=19$m=<KiB>,t=<iteraciones>,p=<hilos>$<sal>$<hash>
+type Argon2idHasher struct {
+	memoria     uint32 // KiB
+	iteraciones uint32
+	hilos       uint8
+	largoSal    int
+	largoClave  uint32
+}
+
+// NuevoArgon2idHasher crea un hasher argon2id con los parámetros recomendados por OWASP.
+func NuevoArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{memoria: 19 * 1024, iteraciones: 2, hilos: 1, largoSal: 16, largoClave: 32}
+}
+
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	sal := make([]byte, a.largoSal)
+	if _, err := rand.Read(sal); err != nil {
+		return "", err
+	}
+	clave := argon2.IDKey([]byte(password), sal, a.iteraciones, a.memoria, a.hilos, a.largoClave)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.memoria, a.iteraciones, a.hilos,
+		base64.RawStdEncoding.EncodeToString(sal), base64.RawStdEncoding.EncodeToString(clave)), nil
+}
+
+func (a *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	p, sal, clave, err := decodificarArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	otra := argon2.IDKey([]byte(password), sal, p.iteraciones, p.memoria, p.hilos, uint32(len(clave)))
+	return subtle.ConstantTimeCompare(clave, otra) == 1, nil
+}
+
+func (a *Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, sal, clave, err := decodificarArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.memoria != a.memoria || p.iteraciones != a.iteraciones || p.hilos != a.hilos ||
+		len(sal) != a.largoSal || uint32(len(clave)) != a.largoClave
+}
+
+func decodificarArgon2id(encoded string) (*Argon2idHasher, []byte, []byte, error) {
+	partes := strings.Split(encoded, "$")
+	if len(partes) != 6 || partes[1] != "argon2id" {
+		return nil, nil, nil, ErrHashInvalido
+	}
+	var version int
+	if _, err := fmt.Sscanf(partes[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrHashInvalido
+	}
+	p := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(partes[3], "m=%d,t=%d,p=%d", &p.memoria, &p.iteraciones, &p.hilos); err != nil {
+		return nil, nil, nil, ErrHashInvalido
+	}
+	sal, err := base64.RawStdEncoding.DecodeString(partes[4])
+	if err != nil {
+		return nil, nil, nil, ErrHashInvalido
+	}
+	clave, err := base64.RawStdEncoding.DecodeString(partes[5])
+	if err != nil || len(clave) == 0 {
+		return nil, nil, nil, ErrHashInvalido
+	}
+	return p, sal, clave, nil
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// usarHasherEnPrueba cambia hasherActual durante la prueba.
+func usarHasherEnPrueba(t *testing.T, h PasswordHasher) {
+	anterior := hasherActual
+	UsarHasher(h)
+	t.Cleanup(func() { UsarHasher(anterior) })
+}
+
+func hashDe(t *testing.T, h PasswordHasher, pass string) string {
+	t.Helper()
+	s, err := h.Hash(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerificarContrasenia(t *testing.T) {
+	const pass = "Secreta123!"
+	argon := hashDe(t, NuevoArgon2idHasher(), pass)
+	argonDebil := hashDe(t, &Argon2idHasher{memoria: 8 * 1024, iteraciones: 1, hilos: 1, largoSal: 16, largoClave: 32}, pass)
+	bcryptHash := hashDe(t, NuevoBcryptHasher(bcrypt.MinCost), pass)
+
+	casos := []struct {
+		nombre     string
+		almacenado string
+		pass       string
+		ok, rehash bool
+	}{
+		{"argon2id actual", argon, pass, true, false},
+		{"argon2id incorrecta", argon, "otra", false, false},
+		{"argon2id con otros parámetros", argonDebil, pass, true, true},
+		{"argon2id mal formado", "$argon2id$v=19$m=19456$sal$hash", pass, false, false},
+		{"argon2id de otra versión", strings.Replace(argon, "v=19", "v=16", 1), pass, false, false},
+		{"bcrypt", bcryptHash, pass, true, true},
+		{"bcrypt incorrecta", bcryptHash, "otra", false, false},
+		{"bcrypt $2y$", "$2y$" + strings.TrimPrefix(bcryptHash, "$2a$"), pass, true, true},
+		{"legado HASH_", "HASH_" + pass, pass, true, true},
+		{"legado HASH_ incorrecta", "HASH_" + pass, "otra", false, true},
+		{"legado sin confundir con la contraseña", "HASH_" + pass, "HASH_" + pass, false, true},
+		{"texto plano", "pass123", "pass123", true, true},
+		{"texto plano incorrecta", "pass123", "pass124", false, true},
+		{"hash vacío", "", "", false, true},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			ok, rehash := VerificarContrasenia(c.almacenado, c.pass)
+			if ok != c.ok || (ok && rehash != c.rehash) {
+				t.Fatalf("VerificarContrasenia = %v, %v; se esperaba %v, %v", ok, rehash, c.ok, c.rehash)
+			}
+		})
+	}
+}
+
+func TestVerificarContraseniaConBcryptActual(t *testing.T) {
+	usarHasherEnPrueba(t, NuevoBcryptHasher(bcrypt.MinCost))
+	const pass = "Secreta123!"
+	casos := []struct {
+		nombre     string
+		almacenado string
+		rehash     bool
+	}{
+		{"mismo coste", hashDe(t, NuevoBcryptHasher(bcrypt.MinCost), pass), false},
+		{"otro coste", hashDe(t, NuevoBcryptHasher(bcrypt.MinCost+1), pass), true},
+		{"argon2id", hashDe(t, NuevoArgon2idHasher(), pass), true},
+	}
+	for _, c := range casos {
+		if ok, rehash := VerificarContrasenia(c.almacenado, pass); !ok || rehash != c.rehash {
+			t.Errorf("%s: VerificarContrasenia = %v, %v; se esperaba true, %v", c.nombre, ok, rehash, c.rehash)
+		}
+	}
+}
+
+func TestIniciarSesionMigraHashUnaVez(t *testing.T) {
+	const correo, pass = "ana@example.com", "pass123"
+	casos := []struct {
+		nombre     string
+		almacenado string
+		migra      bool
+	}{
+		{"texto plano", pass, true},
+		{"legado HASH_", "HASH_" + pass, true},
+		{"bcrypt", hashDe(t, NuevoBcryptHasher(bcrypt.MinCost), pass), true},
+		{"argon2id actual", hashDe(t, NuevoArgon2idHasher(), pass), false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			u := RecreateUsuarioFromDB("1", "ana", correo, c.almacenado, nil)
+			if err := u.IniciarSesion(correo, "otra"); err != ErrCredencialesInvalidas || u.RequiereGuardarHash() {
+				t.Fatalf("contraseña incorrecta: %v, migrado %v", err, u.RequiereGuardarHash())
+			}
+			if err := u.IniciarSesion(correo, pass); err != nil {
+				t.Fatal(err)
+			}
+			if u.RequiereGuardarHash() != c.migra {
+				t.Fatalf("primer inicio: migrado %v, se esperaba %v", u.RequiereGuardarHash(), c.migra)
+			}
+			if c.migra && !strings.HasPrefix(u.GetContraseniaHash(), "$argon2id$") {
+				t.Fatalf("hash migrado %q", u.GetContraseniaHash())
+			}
+			// El mismo usuario ya tiene el hash nuevo: no se vuelve a informar.
+			if err := u.IniciarSesion(correo, pass); err != nil || u.RequiereGuardarHash() {
+				t.Fatalf("segundo inicio: %v, migrado %v", err, u.RequiereGuardarHash())
+			}
+		})
+	}
+}
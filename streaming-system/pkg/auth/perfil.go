@@ -1,19 +1,153 @@
 package auth
 
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrNombrePerfilInvalido indica un nombre vacío o demasiado largo.
+	ErrNombrePerfilInvalido = errors.New("el nombre del perfil debe tener entre 1 y 30 caracteres")
+	// ErrAvatarInvalido indica un avatar fuera del conjunto disponible.
+	ErrAvatarInvalido = errors.New("avatar no disponible")
+	// ErrIdiomaInvalido indica un idioma no soportado.
+	ErrIdiomaInvalido = errors.New("idioma no soportado")
+	// ErrClasificacionInvalida indica un nivel de control parental desconocido.
+	ErrClasificacionInvalida = errors.New("nivel de control parental no válido")
+	// ErrPINInvalido indica un PIN que no tiene exactamente 4 dígitos.
+	ErrPINInvalido = errors.New("el PIN debe tener 4 dígitos")
+	// ErrPINIncorrecto indica que el PIN no coincide con el del perfil.
+	ErrPINIncorrecto = errors.New("PIN incorrecto")
+	// ErrPerfilNoEncontrado indica que el perfil no existe o no es del usuario.
+	ErrPerfilNoEncontrado = errors.New("perfil no encontrado")
+)
+
+// Avatar es una imagen de perfil del conjunto incluido en la aplicación.
+type Avatar struct {
+	ID     string
+	Emoji  string
+	Nombre string
+}
+
+// avatares es el conjunto de avatares incluido; el primero es el predeterminado.
+var avatares = []Avatar{
+	{"clasico", "👤", "Clásico"},
+	{"robot", "🤖", "Robot"},
+	{"gato", "🐱", "Gato"},
+	{"zorro", "🦊", "Zorro"},
+	{"panda", "🐼", "Panda"},
+	{"alien", "👽", "Alien"},
+	{"dragon", "🐉", "Dragón"},
+	{"astronauta", "🧑‍🚀", "Astronauta"},
+}
+
+// AvataresDisponibles devuelve el conjunto de avatares seleccionables.
+func AvataresDisponibles() []Avatar { return avatares }
+
+func buscarAvatar(id string) (Avatar, bool) {
+	for _, a := range avatares {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Avatar{}, false
+}
+
+// Idioma es un idioma de interfaz o de subtítulos (código ISO 639-1).
+type Idioma struct {
+	Codigo string
+	Nombre string
+}
+
+var idiomas = []Idioma{
+	{"es", "Español"},
+	{"en", "English"},
+	{"pt", "Português"},
+	{"fr", "Français"},
+}
+
+// IdiomasDisponibles devuelve los idiomas soportados.
+func IdiomasDisponibles() []Idioma { return idiomas }
+
+func esIdiomaValido(codigo string) bool {
+	for _, i := range idiomas {
+		if i.Codigo == codigo {
+			return true
+		}
+	}
+	return false
+}
+
+// NivelParental es una clasificación máxima seleccionable para un perfil.
+type NivelParental struct {
+	EdadMaxima int
+	Nombre     string
+}
+
+// EdadMaximaInfantil es la clasificación máxima hasta la que un perfil se considera infantil.
+const EdadMaximaInfantil = 7
+
+var nivelesParentales = []NivelParental{
+	{0, "Todo público"},
+	{7, "Niños (hasta 7+)"},
+	{13, "Adolescentes (hasta 13+)"},
+	{16, "Jóvenes (hasta 16+)"},
+	{18, "Adultos (todo el catálogo)"},
+}
+
+// NivelesParentales devuelve los niveles de control parental disponibles.
+func NivelesParentales() []NivelParental { return nivelesParentales }
+
+// PreferenciasPerfil son los ajustes de reproducción propios de cada perfil.
+type PreferenciasPerfil struct {
+	IdiomaInterfaz   string // código de IdiomasDisponibles
+	IdiomaSubtitulos string // "" desactiva los subtítulos
+	Autoplay         bool   // reproducir automáticamente el siguiente episodio
+}
+
+// PreferenciasPredeterminadas son las preferencias de un perfil nuevo.
+func PreferenciasPredeterminadas() PreferenciasPerfil {
+	return PreferenciasPerfil{IdiomaInterfaz: "es", Autoplay: true}
+}
+
+// Validar comprueba que los idiomas estén soportados.
+func (p PreferenciasPerfil) Validar() error {
+	if !esIdiomaValido(p.IdiomaInterfaz) {
+		return ErrIdiomaInvalido
+	}
+	if p.IdiomaSubtitulos != "" && !esIdiomaValido(p.IdiomaSubtitulos) {
+		return ErrIdiomaInvalido
+	}
+	return nil
+}
+
 // Perfil representa un perfil de reproducción asociado a un Usuario.
 type Perfil struct {
-	id     int    // ID único del perfil
-	nombre string // Nombre del perfil (e.g., "Adulto", "Niño")
+	id           int    // ID único del perfil
+	nombre       string // Nombre del perfil (e.g., "Adulto", "Niño")
+	avatar       string // ID de AvataresDisponibles
+	edadMaxima   int    // CLASIFICACION_EDAD_MAXIMA: contenido con clasificación mayor no se muestra
+	pinHash      string // hash del PIN de 4 dígitos; vacío si el perfil no tiene PIN
+	preferencias PreferenciasPerfil
 }
 
 // NuevoPerfil es un constructor para Perfil.
 func NuevoPerfil(id int, nombre string) *Perfil {
 	return &Perfil{
-		id:     id,
-		nombre: nombre,
+		id:           id,
+		nombre:       nombre,
+		avatar:       avatares[0].ID,
+		edadMaxima:   18,
+		preferencias: PreferenciasPredeterminadas(),
 	}
 }
 
+// RecreatePerfilFromDB reconstruye un perfil persistido.
+func RecreatePerfilFromDB(id int, nombre, avatar string, edadMaxima int, pinHash string, pref PreferenciasPerfil) *Perfil {
+	return &Perfil{id: id, nombre: nombre, avatar: avatar, edadMaxima: edadMaxima, pinHash: pinHash, preferencias: pref}
+}
+
 // GetID devuelve el ID del perfil.
 func (p *Perfil) GetID() int {
 	return p.id
@@ -24,12 +158,108 @@ func (p *Perfil) GetNombre() string {
 	return p.nombre
 }
 
-// EditarNombre simula la modificación del nombre del perfil.
-func (p *Perfil) EditarNombre(nuevoNombre string) {
+// GetAvatar devuelve el avatar del perfil; uno desconocido se muestra como el predeterminado.
+func (p *Perfil) GetAvatar() Avatar {
+	if a, ok := buscarAvatar(p.avatar); ok {
+		return a
+	}
+	return avatares[0]
+}
+
+// GetClasificacionMaxima devuelve la edad máxima de clasificación permitida.
+func (p *Perfil) GetClasificacionMaxima() int {
+	return p.edadMaxima
+}
+
+// EsInfantil indica si el perfil está restringido a contenido infantil.
+func (p *Perfil) EsInfantil() bool {
+	return p.edadMaxima <= EdadMaximaInfantil
+}
+
+// CambiarClasificacionMaxima asigna uno de los NivelesParentales.
+func (p *Perfil) CambiarClasificacionMaxima(edad int) error {
+	for _, n := range nivelesParentales {
+		if n.EdadMaxima == edad {
+			p.edadMaxima = edad
+			return nil
+		}
+	}
+	return ErrClasificacionInvalida
+}
+
+// TieneNivelPropio indica que la edad máxima guardada no es uno de los
+// NivelesParentales, como en los perfiles anteriores a ellos (99, 14...).
+func (p *Perfil) TieneNivelPropio() bool {
+	for _, n := range nivelesParentales {
+		if n.EdadMaxima == p.edadMaxima {
+			return false
+		}
+	}
+	return true
+}
+
+// GetPreferencias devuelve las preferencias del perfil.
+func (p *Perfil) GetPreferencias() PreferenciasPerfil {
+	return p.preferencias
+}
+
+// EditarNombre valida y cambia el nombre del perfil.
+func (p *Perfil) EditarNombre(nuevoNombre string) error {
+	nuevoNombre = strings.TrimSpace(nuevoNombre)
+	if n := utf8.RuneCountInString(nuevoNombre); n == 0 || n > 30 {
+		return ErrNombrePerfilInvalido
+	}
 	p.nombre = nuevoNombre
+	return nil
+}
+
+// CambiarAvatar asigna un avatar del conjunto disponible.
+func (p *Perfil) CambiarAvatar(id string) error {
+	if _, ok := buscarAvatar(id); !ok {
+		return ErrAvatarInvalido
+	}
+	p.avatar = id
+	return nil
+}
+
+// ActualizarPreferencias valida y reemplaza las preferencias del perfil.
+func (p *Perfil) ActualizarPreferencias(pref PreferenciasPerfil) error {
+	if err := pref.Validar(); err != nil {
+		return err
+	}
+	p.preferencias = pref
+	return nil
+}
+
+// TienePIN indica si seleccionar el perfil exige su PIN.
+func (p *Perfil) TienePIN() bool { return p.pinHash != "" }
+
+// GetPINHash devuelve el hash del PIN para persistirlo.
+func (p *Perfil) GetPINHash() string { return p.pinHash }
+
+// EstablecerPIN valida y hashea un PIN nuevo de 4 dígitos.
+func (p *Perfil) EstablecerPIN(pin string) error {
+	if len(pin) != 4 || strings.IndexFunc(pin, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return ErrPINInvalido
+	}
+	hash, err := HashearContrasenia(pin)
+	if err != nil {
+		return err
+	}
+	p.pinHash = hash
+	return nil
 }
 
-// EliminarPerfil simula la eliminación del perfil (lógica a implementar).
-func (p *Perfil) EliminarPerfil() {
-	// Aquí iría la lógica de persistencia para eliminar.
+// QuitarPIN elimina el PIN del perfil.
+func (p *Perfil) QuitarPIN() { p.pinHash = "" }
+
+// VerificarPIN comprueba el PIN; un perfil sin PIN no exige ninguno.
+func (p *Perfil) VerificarPIN(pin string) error {
+	if !p.TienePIN() {
+		return nil
+	}
+	if ok, _ := VerificarContrasenia(p.pinHash, pin); !ok {
+		return ErrPINIncorrecto
+	}
+	return nil
 }
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrTokenRecuperacionInvalido cubre tokens inexistentes, usados o expirados.
+	ErrTokenRecuperacionInvalido = errors.New("el enlace de recuperación no es válido o ya expiró")
+	// ErrContraseniaDebil indica que la contraseña no cumple la longitud mínima.
+	ErrContraseniaDebil = errors.New("la contraseña debe tener al menos 8 caracteres")
+)
+
+// VigenciaTokenRecuperacion es el tiempo de validez de un enlace de recuperación.
+const VigenciaTokenRecuperacion = 30 * time.Minute
+
+// TokenRecuperacion es un token de un solo uso para restablecer la contraseña.
+// Solo se persiste su hash.
+type TokenRecuperacion struct {
+	hash      string
+	usuarioID string
+	expira    time.Time
+}
+
+// NuevoTokenRecuperacion genera un token para el usuario y devuelve también
+// su valor en claro, que solo viaja en el correo.
+func NuevoTokenRecuperacion(usuarioID string) (string, *TokenRecuperacion) {
+	plano := generarTokenAleatorio(32)
+	return plano, &TokenRecuperacion{
+		hash:      HashToken(plano),
+		usuarioID: usuarioID,
+		expira:    time.Now().Add(VigenciaTokenRecuperacion),
+	}
+}
+
+func (t *TokenRecuperacion) GetHash() string      { return t.hash }
+func (t *TokenRecuperacion) GetUsuarioID() string { return t.usuarioID }
+func (t *TokenRecuperacion) GetExpira() time.Time { return t.expira }
+
+// HashToken devuelve el SHA-256 en hexadecimal de un token de un solo uso.
+func HashToken(plano string) string {
+	h := sha256.Sum256([]byte(plano))
+	return hex.EncodeToString(h[:])
+}
+
+// ValidarContrasenia comprueba los requisitos mínimos de una contraseña nueva.
+func ValidarContrasenia(pass string) error {
+	if utf8.RuneCountInString(pass) < 8 {
+		return ErrContraseniaDebil
+	}
+	return nil
+}
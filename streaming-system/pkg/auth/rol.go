@@ -0,0 +1,60 @@
+package auth
+
+// Rol agrupa un conjunto de permisos asignables a un Usuario.
+type Rol string
+
+const (
+	RolViewer        Rol = "viewer"
+	RolSupport       Rol = "support"
+	RolContentEditor Rol = "content-editor"
+	RolBillingAdmin  Rol = "billing-admin"
+	RolSuperAdmin    Rol = "super-admin"
+)
+
+// Permiso es una acción concreta que un rol habilita.
+type Permiso string
+
+const (
+	PermisoVerCatalogo       Permiso = "catalogo:ver"
+	PermisoGestionarCatalogo Permiso = "catalogo:gestionar"
+	PermisoVerFacturacion    Permiso = "facturacion:ver"
+	PermisoGestionarRoles    Permiso = "roles:gestionar"
+	PermisoAccederAdmin      Permiso = "admin:acceder"
+	PermisoGestionarCuentas  Permiso = "cuentas:gestionar"
+	PermisoVerAuditoria      Permiso = "auditoria:ver"
+	PermisoVerReportes       Permiso = "reportes:ver"
+	PermisoGestionarClaves   Permiso = "claves_api:gestionar"
+	PermisoSuplantar         Permiso = "usuarios:suplantar"
+)
+
+// permisosPorRol define qué permisos concede cada rol.
+var permisosPorRol = map[Rol][]Permiso{
+	RolViewer:        {PermisoVerCatalogo},
+	RolSupport:       {PermisoVerCatalogo, PermisoAccederAdmin, PermisoSuplantar},
+	RolContentEditor: {PermisoVerCatalogo, PermisoAccederAdmin, PermisoGestionarCatalogo},
+	RolBillingAdmin:  {PermisoVerCatalogo, PermisoAccederAdmin, PermisoVerFacturacion, PermisoVerReportes},
+	RolSuperAdmin: {PermisoVerCatalogo, PermisoAccederAdmin, PermisoGestionarCatalogo,
+		PermisoVerFacturacion, PermisoGestionarRoles, PermisoGestionarCuentas, PermisoVerAuditoria,
+		PermisoVerReportes, PermisoGestionarClaves, PermisoSuplantar},
+}
+
+// RolesDisponibles devuelve todos los roles en orden de menor a mayor privilegio.
+func RolesDisponibles() []Rol {
+	return []Rol{RolViewer, RolSupport, RolContentEditor, RolBillingAdmin, RolSuperAdmin}
+}
+
+// EsRolValido indica si el rol está definido.
+func EsRolValido(r Rol) bool {
+	_, ok := permisosPorRol[r]
+	return ok
+}
+
+// Concede indica si el rol incluye el permiso.
+func (r Rol) Concede(p Permiso) bool {
+	for _, q := range permisosPorRol[r] {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
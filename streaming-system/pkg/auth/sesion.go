@@ -0,0 +1,368 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NombreCookieSesion es el nombre de la cookie que transporta la sesión.
+const NombreCookieSesion = "streamgo_sesion"
+
+// ErrSesionInvalida indica que la cookie no existe, está alterada o la sesión expiró.
+var ErrSesionInvalida = errors.New("sesión inválida o expirada")
+
+// intervaloUltimoAcceso limita cada cuánto se persiste el último acceso de una sesión.
+const intervaloUltimoAcceso = time.Minute
+
+// Dispositivo identifica desde dónde se inició una sesión.
+type Dispositivo struct {
+	UserAgent string
+	IP        string
+}
+
+// Descripcion resume el user agent como "Navegador en Sistema".
+func (d Dispositivo) Descripcion() string {
+	ua := d.UserAgent
+	navegador := "Navegador desconocido"
+	for _, n := range []struct{ marca, nombre string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"},
+		{"Safari/", "Safari"}, {"curl/", "curl"}, {"okhttp", "App Android"}, {"CFNetwork", "App iOS"},
+	} {
+		if strings.Contains(ua, n.marca) {
+			navegador = n.nombre
+			break
+		}
+	}
+	for _, so := range []struct{ marca, nombre string }{
+		{"Android", "Android"}, {"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, so.marca) {
+			return navegador + " en " + so.nombre
+		}
+	}
+	return navegador
+}
+
+// Sesion asocia un token de Autenticacion con el perfil seleccionado y el
+// dispositivo desde el que se abrió.
+type Sesion struct {
+	autenticacion *Autenticacion
+	perfilID      int  // 0 mientras no se haya elegido perfil
+	mfaPendiente  bool // falta verificar (o inscribir) el segundo factor
+	creada        time.Time
+	ultimoAcceso  time.Time
+	dispositivo   Dispositivo
+	suplantadoID  string // usuario que el titular (agente de soporte) está suplantando
+}
+
+// NuevaSesion crea una sesión a partir de una autenticación ya generada.
+func NuevaSesion(a *Autenticacion, d Dispositivo) *Sesion {
+	ahora := time.Now()
+	return &Sesion{autenticacion: a, creada: ahora, ultimoAcceso: ahora, dispositivo: d}
+}
+
+// RecreateSesionFromDB reconstruye una sesión persistida.
+func RecreateSesionFromDB(a *Autenticacion, perfilID int, mfaPendiente bool, creada, ultimoAcceso time.Time, d Dispositivo, suplantadoID string) *Sesion {
+	return &Sesion{autenticacion: a, perfilID: perfilID, mfaPendiente: mfaPendiente, creada: creada, ultimoAcceso: ultimoAcceso, dispositivo: d, suplantadoID: suplantadoID}
+}
+
+func (s *Sesion) GetToken() string                 { return s.autenticacion.GetToken() }
+func (s *Sesion) GetUsuarioID() string             { return s.autenticacion.GetUsuarioID() }
+func (s *Sesion) GetExpiracion() time.Time         { return s.autenticacion.GetExpireDate() }
+func (s *Sesion) GetAutenticacion() *Autenticacion { return s.autenticacion }
+func (s *Sesion) GetPerfilID() int                 { return s.perfilID }
+func (s *Sesion) GetCreada() time.Time             { return s.creada }
+func (s *Sesion) SeleccionarPerfil(perfilID int)   { s.perfilID = perfilID }
+func (s *Sesion) EstaVigente() bool                { return s.autenticacion.VerificarToken() }
+func (s *Sesion) MFAPendiente() bool               { return s.mfaPendiente }
+func (s *Sesion) CompletarMFA()                    { s.mfaPendiente = false }
+func (s *Sesion) GetUltimoAcceso() time.Time       { return s.ultimoAcceso }
+func (s *Sesion) GetDispositivo() Dispositivo      { return s.dispositivo }
+func (s *Sesion) GetSuplantadoID() string          { return s.suplantadoID }
+func (s *Sesion) EstaSuplantando() bool            { return s.suplantadoID != "" }
+
+// IniciarSuplantacion hace que la sesión actúe como el usuario indicado. El
+// perfil elegido se descarta porque pertenecía al titular.
+func (s *Sesion) IniciarSuplantacion(uid string) {
+	s.suplantadoID, s.perfilID = uid, 0
+}
+
+// TerminarSuplantacion devuelve la sesión a su titular.
+func (s *Sesion) TerminarSuplantacion() {
+	s.suplantadoID, s.perfilID = "", 0
+}
+
+// copiar duplica la sesión y su autenticación.
+func (s *Sesion) copiar() *Sesion {
+	c := *s
+	if s.autenticacion != nil {
+		a := *s.autenticacion
+		c.autenticacion = &a
+	}
+	return &c
+}
+
+// GetID es un identificador público de la sesión derivado del token, para
+// listarla y revocarla sin exponer el token.
+func (s *Sesion) GetID() string {
+	h := sha256.Sum256([]byte(s.GetToken()))
+	return hex.EncodeToString(h[:8])
+}
+
+// AlmacenSesiones abstrae dónde se persisten las sesiones (memoria, MySQL...).
+type AlmacenSesiones interface {
+	SaveSession(s *Sesion) error
+	LoadSession(token string) (*Sesion, error)
+	DeleteSession(token string) error
+	DeleteSessionsByUserID(uid string) error
+	// LoadSessionsByUserID devuelve las sesiones no expiradas del usuario.
+	LoadSessionsByUserID(uid string) ([]*Sesion, error)
+	// TouchSession registra el último acceso y la IP desde la que se hizo.
+	TouchSession(token string, ultimoAcceso time.Time, ip string) error
+}
+
+// AlmacenSesionesMemoria guarda las sesiones en un mapa protegido por mutex.
+// Como el almacén MySQL, entrega copias: lo que un handler cambie en su sesión
+// no llega a las demás peticiones hasta que la guarde.
+type AlmacenSesionesMemoria struct {
+	mu       sync.Mutex
+	sesiones map[string]*Sesion // Clave: token
+}
+
+// NuevoAlmacenSesionesMemoria crea un almacén en memoria vacío.
+func NuevoAlmacenSesionesMemoria() *AlmacenSesionesMemoria {
+	return &AlmacenSesionesMemoria{sesiones: make(map[string]*Sesion)}
+}
+
+func (m *AlmacenSesionesMemoria) SaveSession(s *Sesion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sesiones[s.GetToken()] = s.copiar()
+	return nil
+}
+
+func (m *AlmacenSesionesMemoria) LoadSession(token string) (*Sesion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sesiones[token]; ok {
+		return s.copiar(), nil
+	}
+	return nil, ErrSesionInvalida
+}
+
+func (m *AlmacenSesionesMemoria) DeleteSession(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sesiones, token)
+	return nil
+}
+
+func (m *AlmacenSesionesMemoria) DeleteSessionsByUserID(uid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for token, s := range m.sesiones {
+		if s.GetUsuarioID() == uid {
+			delete(m.sesiones, token)
+		}
+	}
+	return nil
+}
+
+func (m *AlmacenSesionesMemoria) LoadSessionsByUserID(uid string) ([]*Sesion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*Sesion
+	for _, s := range m.sesiones {
+		if s.GetUsuarioID() == uid && s.EstaVigente() {
+			res = append(res, s.copiar())
+		}
+	}
+	return res, nil
+}
+
+func (m *AlmacenSesionesMemoria) TouchSession(token string, ultimoAcceso time.Time, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sesiones[token]; ok {
+		s.ultimoAcceso = ultimoAcceso
+		s.dispositivo.IP = ip
+	}
+	return nil
+}
+
+// GestorSesiones emite y valida la cookie firmada de sesión.
+type GestorSesiones struct {
+	almacen AlmacenSesiones
+	clave   []byte                       // clave HMAC para firmar la cookie
+	ipDe    func(r *http.Request) string // cómo obtener la IP del cliente
+}
+
+// NuevoGestorSesiones crea un gestor sobre el almacén indicado.
+func NuevoGestorSesiones(almacen AlmacenSesiones, clave []byte) *GestorSesiones {
+	return &GestorSesiones{almacen: almacen, clave: clave, ipDe: ipRemota}
+}
+
+// UsarIPCliente cambia cómo se obtiene la IP que se registra para cada sesión
+// (por ejemplo, para confiar en X-Forwarded-For detrás de un proxy).
+func (g *GestorSesiones) UsarIPCliente(f func(r *http.Request) string) { g.ipDe = f }
+
+func ipRemota(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Iniciar persiste la sesión del usuario autenticado y escribe la cookie.
+// Debe llamarse después de Usuario.IniciarSesion, que genera el token. Con
+// mfaPendiente la sesión solo da acceso a la verificación del segundo factor.
+func (g *GestorSesiones) Iniciar(w http.ResponseWriter, r *http.Request, u *Usuario, mfaPendiente bool) (*Sesion, error) {
+	a := u.GetAutenticacion()
+	if !a.VerificarToken() {
+		return nil, ErrSesionInvalida
+	}
+	s := NuevaSesion(a, Dispositivo{UserAgent: r.UserAgent(), IP: g.ipDe(r)})
+	s.mfaPendiente = mfaPendiente
+	if err := g.almacen.SaveSession(s); err != nil {
+		return nil, err
+	}
+	g.escribirCookie(w, s.GetToken(), s.GetExpiracion())
+	return s, nil
+}
+
+// Resolver valida la cookie de la petición y devuelve su sesión vigente.
+func (g *GestorSesiones) Resolver(r *http.Request) (*Sesion, error) {
+	c, err := r.Cookie(NombreCookieSesion)
+	if err != nil {
+		return nil, ErrSesionInvalida
+	}
+	token, ok := g.verificarFirma(c.Value)
+	if !ok {
+		return nil, ErrSesionInvalida
+	}
+	s, err := g.almacen.LoadSession(token)
+	if err != nil {
+		return nil, ErrSesionInvalida
+	}
+	if !s.EstaVigente() {
+		g.almacen.DeleteSession(token)
+		return nil, ErrSesionInvalida
+	}
+	if ahora := time.Now(); ahora.Sub(s.ultimoAcceso) > intervaloUltimoAcceso {
+		ip := g.ipDe(r)
+		if err := g.almacen.TouchSession(token, ahora, ip); err != nil {
+			return nil, err
+		}
+		// s es una copia propia de esta petición.
+		s.ultimoAcceso, s.dispositivo.IP = ahora, ip
+	}
+	return s, nil
+}
+
+// Guardar persiste los cambios de una sesión (por ejemplo, el perfil elegido).
+func (g *GestorSesiones) Guardar(s *Sesion) error {
+	return g.almacen.SaveSession(s)
+}
+
+// Cerrar revoca la sesión de la petición y borra la cookie.
+func (g *GestorSesiones) Cerrar(w http.ResponseWriter, r *http.Request) error {
+	defer g.escribirCookie(w, "", time.Unix(0, 0))
+	s, err := g.Resolver(r)
+	if err != nil {
+		return nil
+	}
+	token := s.GetToken()
+	s.GetAutenticacion().RevocarToken()
+	return g.almacen.DeleteSession(token)
+}
+
+// CerrarTodas revoca todas las sesiones de un usuario.
+func (g *GestorSesiones) CerrarTodas(uid string) error {
+	return g.almacen.DeleteSessionsByUserID(uid)
+}
+
+// Listar devuelve las sesiones vigentes del usuario, la más reciente primero.
+func (g *GestorSesiones) Listar(uid string) ([]*Sesion, error) {
+	res, err := g.almacen.LoadSessionsByUserID(uid)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ultimoAcceso.After(res[j].ultimoAcceso) })
+	return res, nil
+}
+
+// CerrarPorID revoca una sesión del usuario a partir de su GetID.
+func (g *GestorSesiones) CerrarPorID(uid, id string) error {
+	sesiones, err := g.almacen.LoadSessionsByUserID(uid)
+	if err != nil {
+		return err
+	}
+	for _, s := range sesiones {
+		if s.GetID() == id {
+			return g.almacen.DeleteSession(s.GetToken())
+		}
+	}
+	return ErrSesionInvalida
+}
+
+// CerrarOtras revoca todas las sesiones del usuario salvo la indicada y
+// devuelve cuántas cerró.
+func (g *GestorSesiones) CerrarOtras(uid string, actual *Sesion) (int, error) {
+	sesiones, err := g.almacen.LoadSessionsByUserID(uid)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range sesiones {
+		if s.GetToken() == actual.GetToken() {
+			continue
+		}
+		if err := g.almacen.DeleteSession(s.GetToken()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (g *GestorSesiones) escribirCookie(w http.ResponseWriter, token string, expira time.Time) {
+	valor := ""
+	if token != "" {
+		valor = token + "." + g.firmar(token)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     NombreCookieSesion,
+		Value:    valor,
+		Path:     "/",
+		Expires:  expira,
+		HttpOnly: true,
+		// Lax: la cookie no acompaña a POST de otros sitios, pero sí a los
+		// enlaces de los correos y a la vuelta del proveedor OIDC.
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (g *GestorSesiones) firmar(token string) string {
+	mac := hmac.New(sha256.New, g.clave)
+	mac.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (g *GestorSesiones) verificarFirma(valor string) (string, bool) {
+	token, firma, ok := strings.Cut(valor, ".")
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, hmac.Equal([]byte(firma), []byte(g.firmar(token)))
+}
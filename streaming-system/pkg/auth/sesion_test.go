@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// peticionConCookie construye una petición con la cookie de sesión indicada;
+// con valor vacío no lleva cookie.
+func peticionConCookie(valor string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if valor != "" {
+		r.AddCookie(&http.Cookie{Name: NombreCookieSesion, Value: valor})
+	}
+	return r
+}
+
+// alterar cambia el primer carácter de s.
+func alterar(s string) string {
+	if s[0] == '0' {
+		return "1" + s[1:]
+	}
+	return "0" + s[1:]
+}
+
+func TestResolverSesion(t *testing.T) {
+	almacen := NuevoAlmacenSesionesMemoria()
+	g := NuevoGestorSesiones(almacen, []byte("clave"))
+	otraClave := NuevoGestorSesiones(almacen, []byte("otra clave"))
+
+	vigente := sesionPrueba(t, "1")
+	if err := almacen.SaveSession(vigente); err != nil {
+		t.Fatal(err)
+	}
+	caducada := RecreateSesionFromDB(RecreateAutenticacionFromDB(generarTokenAleatorio(32), "1", time.Now().Add(-time.Minute)),
+		0, false, time.Now().Add(-25*time.Hour), time.Now().Add(-time.Hour), Dispositivo{}, "")
+	if err := almacen.SaveSession(caducada); err != nil {
+		t.Fatal(err)
+	}
+	sinGuardar := sesionPrueba(t, "1")
+
+	token := vigente.GetToken()
+	firma := g.firmar(token)
+	casos := []struct {
+		nombre string
+		cookie string
+		ok     bool
+	}{
+		{"válida", token + "." + firma, true},
+		{"sin cookie", "", false},
+		{"sin firma", token, false},
+		{"firma vacía", token + ".", false},
+		{"sin token", "." + firma, false},
+		{"firma alterada", token + "." + alterar(firma), false},
+		{"token alterado", alterar(token) + "." + firma, false},
+		{"firma de otra sesión", token + "." + g.firmar(sinGuardar.GetToken()), false},
+		{"firmada con otra clave", token + "." + otraClave.firmar(token), false},
+		{"sesión no guardada", sinGuardar.GetToken() + "." + g.firmar(sinGuardar.GetToken()), false},
+		{"sesión expirada", caducada.GetToken() + "." + g.firmar(caducada.GetToken()), false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			s, err := g.Resolver(peticionConCookie(c.cookie))
+			if !c.ok {
+				if !errors.Is(err, ErrSesionInvalida) || s != nil {
+					t.Fatalf("Resolver = %v, %v; se esperaba %v", s, err, ErrSesionInvalida)
+				}
+				return
+			}
+			if err != nil || s.GetToken() != token || s.GetUsuarioID() != "1" {
+				t.Fatalf("Resolver = %v, %v", s, err)
+			}
+		})
+	}
+
+	// La sesión expirada se borra del almacén al resolverla.
+	if _, err := almacen.LoadSession(caducada.GetToken()); !errors.Is(err, ErrSesionInvalida) {
+		t.Fatalf("la sesión expirada sigue en el almacén: %v", err)
+	}
+}
+
+func TestIniciarYCerrarSesion(t *testing.T) {
+	g := NuevoGestorSesiones(NuevoAlmacenSesionesMemoria(), []byte("clave"))
+	u := RecreateUsuarioFromDB("1", "ana", "ana@example.com", "", nil)
+	rec := httptest.NewRecorder()
+	if _, err := g.Iniciar(rec, peticionConCookie(""), u, false); !errors.Is(err, ErrSesionInvalida) {
+		t.Fatalf("Iniciar sin token generado: %v", err)
+	}
+
+	u.IniciarSesionExterna()
+	s, err := g.Iniciar(rec, peticionConCookie(""), u, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie := rec.Result().Cookies()[0]
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || !cookie.Expires.Equal(s.GetExpiracion().Truncate(time.Second)) {
+		t.Fatalf("cookie %+v", cookie)
+	}
+	resuelta, err := g.Resolver(peticionConCookie(cookie.Value))
+	if err != nil || resuelta.GetToken() != s.GetToken() || !resuelta.MFAPendiente() {
+		t.Fatalf("Resolver = %v, %v", resuelta, err)
+	}
+
+	cierre := httptest.NewRecorder()
+	if err := g.Cerrar(cierre, peticionConCookie(cookie.Value)); err != nil {
+		t.Fatal(err)
+	}
+	if borrada := cierre.Result().Cookies()[0]; borrada.Value != "" || borrada.Expires.After(time.Now()) {
+		t.Fatalf("cookie tras cerrar %+v", borrada)
+	}
+	if _, err := g.Resolver(peticionConCookie(cookie.Value)); !errors.Is(err, ErrSesionInvalida) {
+		t.Fatalf("la cookie sigue valiendo tras cerrar: %v", err)
+	}
+}
+
+// TestResolverUltimoAcceso comprueba que el último acceso y la IP se
+// persisten como mucho una vez por intervaloUltimoAcceso.
+func TestResolverUltimoAcceso(t *testing.T) {
+	almacen := NuevoAlmacenSesionesMemoria()
+	g := NuevoGestorSesiones(almacen, []byte("clave"))
+	hace := time.Now().Add(-2 * intervaloUltimoAcceso)
+	s := RecreateSesionFromDB(RecreateAutenticacionFromDB(generarTokenAleatorio(32), "1", time.Now().Add(time.Hour)),
+		0, false, hace, hace, Dispositivo{IP: "198.51.100.1"}, "")
+	almacen.SaveSession(s)
+	cookie := s.GetToken() + "." + g.firmar(s.GetToken())
+
+	r := peticionConCookie(cookie)
+	r.RemoteAddr = "203.0.113.7:1234"
+	resuelta, err := g.Resolver(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guardada, _ := almacen.LoadSession(s.GetToken())
+	if !guardada.GetUltimoAcceso().After(hace) || guardada.GetDispositivo().IP != "203.0.113.7" || resuelta.GetDispositivo().IP != "203.0.113.7" {
+		t.Fatalf("último acceso %v desde %s", guardada.GetUltimoAcceso(), guardada.GetDispositivo().IP)
+	}
+
+	// Dentro del intervalo no se vuelve a escribir.
+	r = peticionConCookie(cookie)
+	r.RemoteAddr = "192.0.2.9:1234"
+	if _, err := g.Resolver(r); err != nil {
+		t.Fatal(err)
+	}
+	if guardada, _ := almacen.LoadSession(s.GetToken()); guardada.GetDispositivo().IP != "203.0.113.7" {
+		t.Fatalf("IP %s actualizada dentro del intervalo", guardada.GetDispositivo().IP)
+	}
+}
@@ -0,0 +1,20 @@
+package auth
+
+import "errors"
+
+var (
+	// ErrSuplantacionNoPermitida indica que el agente no puede suplantar al usuario indicado.
+	ErrSuplantacionNoPermitida = errors.New("no puedes suplantar a este usuario")
+	// ErrBloqueadoEnSuplantacion indica una acción vedada mientras se suplanta a un usuario.
+	ErrBloqueadoEnSuplantacion = errors.New("esta acción no está disponible mientras suplantas a un usuario")
+)
+
+// ValidarSuplantacion comprueba que el agente tenga el permiso de suplantar y
+// que el objetivo sea un suscriptor: suplantar a alguien con acceso al panel
+// daría al agente permisos que su rol no tiene.
+func ValidarSuplantacion(agente, objetivo *Usuario) error {
+	if !agente.TienePermiso(PermisoSuplantar) || agente.GetID() == objetivo.GetID() || objetivo.TienePermiso(PermisoAccederAdmin) {
+		return ErrSuplantacionNoPermitida
+	}
+	return nil
+}
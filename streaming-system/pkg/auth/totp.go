@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// ErrCodigoMFAInvalido indica un código TOTP o de recuperación incorrecto o ya usado.
+var ErrCodigoMFAInvalido = errors.New("código de verificación inválido")
+
+const (
+	periodoTOTP  = 30 // segundos por paso (RFC 6238)
+	digitosTOTP  = 6
+	ventanaTOTP  = 1 // pasos de tolerancia por desfase de reloj
+	emisorTOTP   = "StreamGo"
+	largoSecreto = 20 // 160 bits, lo recomendado por RFC 4226
+)
+
+var codificacionBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ConfigMFA es la configuración de segundo factor (TOTP) de un usuario.
+type ConfigMFA struct {
+	usuarioID  string
+	secreto    string // base32
+	activo     bool   // true una vez confirmado el primer código
+	ultimoPaso int64  // último paso TOTP aceptado, para impedir reutilizar códigos
+}
+
+// NuevaConfigMFA genera un secreto nuevo, pendiente de confirmación.
+func NuevaConfigMFA(usuarioID string) *ConfigMFA {
+	b := make([]byte, largoSecreto)
+	if _, err := rand.Read(b); err != nil {
+		panic("auth: no se pudo leer de crypto/rand: " + err.Error())
+	}
+	return &ConfigMFA{usuarioID: usuarioID, secreto: codificacionBase32.EncodeToString(b)}
+}
+
+// RecreateConfigMFAFromDB reconstruye una configuración persistida.
+func RecreateConfigMFAFromDB(usuarioID, secreto string, activo bool, ultimoPaso int64) *ConfigMFA {
+	return &ConfigMFA{usuarioID: usuarioID, secreto: secreto, activo: activo, ultimoPaso: ultimoPaso}
+}
+
+func (c *ConfigMFA) GetUsuarioID() string { return c.usuarioID }
+func (c *ConfigMFA) GetSecreto() string   { return c.secreto }
+func (c *ConfigMFA) EstaActivo() bool     { return c != nil && c.activo }
+func (c *ConfigMFA) GetUltimoPaso() int64 { return c.ultimoPaso }
+func (c *ConfigMFA) Activar()             { c.activo = true }
+
+// URI devuelve el enlace otpauth:// que entienden las apps autenticadoras.
+func (c *ConfigMFA) URI(cuenta string) string {
+	etiqueta := url.PathEscape(emisorTOTP + ":" + cuenta)
+	q := url.Values{}
+	q.Set("secret", c.secreto)
+	q.Set("issuer", emisorTOTP)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(digitosTOTP))
+	q.Set("period", fmt.Sprint(periodoTOTP))
+	return "otpauth://totp/" + etiqueta + "?" + q.Encode()
+}
+
+// QRPNG devuelve el código QR de URI en formato PNG.
+func (c *ConfigMFA) QRPNG(cuenta string, tamanio int) ([]byte, error) {
+	return qrcode.Encode(c.URI(cuenta), qrcode.Medium, tamanio)
+}
+
+// VerificarCodigo comprueba un código TOTP dentro de la ventana de tolerancia.
+// Devuelve el paso aceptado, que debe persistirse como último paso usado.
+func (c *ConfigMFA) VerificarCodigo(codigo string, ahora time.Time) (int64, error) {
+	codigo = strings.ReplaceAll(strings.TrimSpace(codigo), " ", "")
+	clave, err := codificacionBase32.DecodeString(c.secreto)
+	if err != nil || len(codigo) != digitosTOTP {
+		return 0, ErrCodigoMFAInvalido
+	}
+	actual := ahora.Unix() / periodoTOTP
+	for paso := actual - ventanaTOTP; paso <= actual+ventanaTOTP; paso++ {
+		if paso <= c.ultimoPaso {
+			continue
+		}
+		if hmac.Equal([]byte(hotp(clave, paso)), []byte(codigo)) {
+			c.ultimoPaso = paso
+			return paso, nil
+		}
+	}
+	return 0, ErrCodigoMFAInvalido
+}
+
+// hotp implementa RFC 4226 con HMAC-SHA1 y truncado dinámico.
+func hotp(clave []byte, contador int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(contador))
+	mac := hmac.New(sha1.New, clave)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	valor := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digitosTOTP, valor%1000000)
+}
+
+// GenerarCodigosRecuperacion crea n códigos de un solo uso con formato
+// xxxxx-xxxxx. Devuelve los códigos en claro (se muestran una vez) y sus hashes.
+func GenerarCodigosRecuperacion(n int) (planos []string, hashes []string) {
+	for i := 0; i < n; i++ {
+		t := generarTokenAleatorio(5)
+		codigo := t[:5] + "-" + t[5:]
+		planos = append(planos, codigo)
+		hashes = append(hashes, HashCodigoRecuperacion(codigo))
+	}
+	return planos, hashes
+}
+
+// HashCodigoRecuperacion normaliza y hashea un código de recuperación.
+func HashCodigoRecuperacion(codigo string) string {
+	return HashToken(strings.ToLower(strings.TrimSpace(codigo)))
+}
+
+// MFAObligatorio indica si alguno de los roles del usuario exige segundo factor.
+func MFAObligatorio(u *Usuario, rolesObligatorios []Rol) bool {
+	for _, r := range rolesObligatorios {
+		if u.TieneRol(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// secretoRFC es la clave SHA1 de los vectores de prueba de RFC 6238
+// ("12345678901234567890") en base32.
+const secretoRFC = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Vectores del apéndice B de RFC 6238 (SHA1). El RFC publica códigos de 8
+// dígitos; con 6 dígitos el resultado son sus 6 últimos.
+var vectoresRFC6238 = []struct {
+	unix   int64
+	codigo string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestHOTPVectoresRFC6238(t *testing.T) {
+	clave, err := codificacionBase32.DecodeString(secretoRFC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectoresRFC6238 {
+		if got := hotp(clave, v.unix/periodoTOTP); got != v.codigo {
+			t.Errorf("T=%d: código %s, se esperaba %s", v.unix, got, v.codigo)
+		}
+	}
+}
+
+func TestVerificarCodigo(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		codigo     string
+		ahora      int64 // segundos Unix al verificar
+		ultimoPaso int64
+		paso       int64 // 0 si debe rechazarse
+	}{
+		{"paso actual", "050471", 1111111111, 0, 37037037},
+		{"con espacios", " 050 471 ", 1111111111, 0, 37037037},
+		{"paso anterior dentro de la ventana", "081804", 1111111111, 0, 37037036},
+		{"paso siguiente dentro de la ventana", "050471", 1111111109, 0, 37037037},
+		{"fuera de la ventana", "081804", 1111111111 + 2*periodoTOTP, 0, 0},
+		{"código ya usado", "050471", 1111111111, 37037037, 0},
+		{"paso anterior al último usado", "081804", 1111111111, 37037036, 0},
+		{"incorrecto", "123456", 1111111111, 0, 0},
+		{"longitud incorrecta", "14050471", 1111111111, 0, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			cfg := RecreateConfigMFAFromDB("42", secretoRFC, true, c.ultimoPaso)
+			paso, err := cfg.VerificarCodigo(c.codigo, time.Unix(c.ahora, 0))
+			if c.paso == 0 {
+				if !errors.Is(err, ErrCodigoMFAInvalido) {
+					t.Fatalf("error = %v, se esperaba ErrCodigoMFAInvalido", err)
+				}
+				if cfg.GetUltimoPaso() != c.ultimoPaso {
+					t.Fatalf("un código rechazado cambió el último paso a %d", cfg.GetUltimoPaso())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerificarCodigo: %v", err)
+			}
+			if paso != c.paso || cfg.GetUltimoPaso() != c.paso {
+				t.Fatalf("paso %d, último paso %d, se esperaba %d", paso, cfg.GetUltimoPaso(), c.paso)
+			}
+			if _, err := cfg.VerificarCodigo(c.codigo, time.Unix(c.ahora, 0)); !errors.Is(err, ErrCodigoMFAInvalido) {
+				t.Fatalf("el mismo código se aceptó dos veces: %v", err)
+			}
+		})
+	}
+}
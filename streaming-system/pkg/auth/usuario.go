@@ -6,18 +6,28 @@ import (
 
 type Usuario struct {
 	id, nombre, correo, contraseniaHash string
+	hashActualizado                     bool // el hash cambió y debe persistirse
+	emailVerificado                     bool
+	roles                               []Rol
 	perfiles                            []*Perfil
 	suscripcion                         *billing.Suscripcion
 	autenticacion                       *Autenticacion
 }
 
-func NuevoUsuario(id, nombre, correo, pass string) *Usuario {
+func NuevoUsuario(id, nombre, correo, pass string) (*Usuario, error) {
+	if err := ValidarEmail(correo); err != nil {
+		return nil, err
+	}
+	hash, err := HashearContrasenia(pass)
+	if err != nil {
+		return nil, err
+	}
 	return &Usuario{
 		id: id, nombre: nombre, correo: correo,
-		contraseniaHash: "HASH_" + pass,
+		contraseniaHash: hash,
 		perfiles:        make([]*Perfil, 0),
 		autenticacion:   &Autenticacion{},
-	}
+	}, nil
 }
 
 func RecreateUsuarioFromDB(id, nombre, correo, hash string, sus *billing.Suscripcion) *Usuario {
@@ -35,15 +45,54 @@ func (u *Usuario) GetCorreo() string                    { return u.correo }
 func (u *Usuario) GetContraseniaHash() string           { return u.contraseniaHash }
 func (u *Usuario) GetPerfiles() []*Perfil               { return u.perfiles }
 func (u *Usuario) GetSuscripcion() *billing.Suscripcion { return u.suscripcion }
+func (u *Usuario) GetAutenticacion() *Autenticacion     { return u.autenticacion }
+func (u *Usuario) EmailVerificado() bool                { return u.emailVerificado }
+func (u *Usuario) MarcarEmailVerificado()               { u.emailVerificado = true }
 
+// CambiarContrasenia valida y hashea la contraseña nueva.
+func (u *Usuario) CambiarContrasenia(nueva string) error {
+	if err := ValidarContrasenia(nueva); err != nil {
+		return err
+	}
+	hash, err := HashearContrasenia(nueva)
+	if err != nil {
+		return err
+	}
+	u.contraseniaHash = hash
+	u.hashActualizado = true
+	return nil
+}
+
+// IniciarSesion valida las credenciales y genera el token de sesión. Si el
+// hash almacenado usa un esquema antiguo se regenera con el algoritmo actual;
+// RequiereGuardarHash indica entonces que hay que persistirlo, solo hasta el
+// siguiente inicio de sesión: un hash ya migrado no se vuelve a informar.
 func (u *Usuario) IniciarSesion(correo, pass string) error {
-	if u.correo == correo && u.contraseniaHash == "HASH_"+pass {
-		u.autenticacion.GenerarToken(u.id)
-		return nil
+	ok, rehash := VerificarContrasenia(u.contraseniaHash, pass)
+	if u.correo != correo || !ok {
+		return ErrCredencialesInvalidas
+	}
+	u.hashActualizado = false
+	if rehash {
+		if nuevo, err := HashearContrasenia(pass); err == nil {
+			u.contraseniaHash = nuevo
+			u.hashActualizado = true
+		}
 	}
-	return ErrCredencialesInvalidas
+	u.autenticacion.GenerarToken(u.id)
+	return nil
 }
 
+// IniciarSesionExterna genera el token de sesión de un usuario ya
+// autenticado por un proveedor de identidad externo (OIDC).
+func (u *Usuario) IniciarSesionExterna() {
+	u.autenticacion.GenerarToken(u.id)
+}
+
+// RequiereGuardarHash indica si el último IniciarSesion migró el hash de la
+// contraseña o si CambiarContrasenia lo cambió después.
+func (u *Usuario) RequiereGuardarHash() bool { return u.hashActualizado }
+
 func (u *Usuario) AsignarSuscripcion(s *billing.Suscripcion) { u.suscripcion = s }
 func (u *Usuario) TieneSuscripcionActiva() bool {
 	return u.suscripcion != nil && u.suscripcion.EstaActiva()
@@ -56,3 +105,31 @@ func (u *Usuario) CrearPerfil(nombre string) *Perfil {
 }
 
 func (u *Usuario) AgregarPerfilExistente(p *Perfil) { u.perfiles = append(u.perfiles, p) }
+
+// GetRoles devuelve los roles del usuario; sin roles asignados es RolViewer.
+func (u *Usuario) GetRoles() []Rol {
+	if len(u.roles) == 0 {
+		return []Rol{RolViewer}
+	}
+	return u.roles
+}
+
+func (u *Usuario) AsignarRoles(roles []Rol) { u.roles = roles }
+
+func (u *Usuario) TieneRol(r Rol) bool {
+	for _, q := range u.GetRoles() {
+		if q == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *Usuario) TienePermiso(p Permiso) bool {
+	for _, r := range u.GetRoles() {
+		if r.Concede(p) {
+			return true
+		}
+	}
+	return false
+}
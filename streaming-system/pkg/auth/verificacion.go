@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEmailInvalido indica que el correo no tiene un formato válido.
+	ErrEmailInvalido = errors.New("el correo electrónico no es válido")
+	// ErrEmailEnUso indica que ya existe una cuenta con ese correo.
+	ErrEmailEnUso = errors.New("ya existe una cuenta con ese correo electrónico")
+	// ErrEnlaceVerificacionInvalido cubre firmas incorrectas, enlaces expirados
+	// y enlaces emitidos para un correo que ya no es el de la cuenta.
+	ErrEnlaceVerificacionInvalido = errors.New("el enlace de verificación no es válido o ya expiró")
+)
+
+// VigenciaEnlaceVerificacion es el tiempo de validez de un enlace de verificación.
+const VigenciaEnlaceVerificacion = 48 * time.Hour
+
+// ValidarEmail comprueba que el correo sea una dirección simple (sin nombre visible).
+func ValidarEmail(email string) error {
+	a, err := mail.ParseAddress(email)
+	if err != nil || a.Address != email || len(email) > 254 {
+		return ErrEmailInvalido
+	}
+	return nil
+}
+
+// FirmanteVerificacion emite y valida enlaces de verificación de correo. Los
+// enlaces no se persisten: llevan el usuario, el correo y la expiración
+// firmados con HMAC-SHA256.
+type FirmanteVerificacion struct {
+	clave []byte
+}
+
+// NuevoFirmanteVerificacion crea un firmante con la clave indicada.
+func NuevoFirmanteVerificacion(clave []byte) *FirmanteVerificacion {
+	return &FirmanteVerificacion{clave: clave}
+}
+
+// Firmar devuelve el token de verificación del correo actual del usuario.
+func (f *FirmanteVerificacion) Firmar(u *Usuario, ahora time.Time) string {
+	datos := u.id + "\n" + u.correo + "\n" + strconv.FormatInt(ahora.Add(VigenciaEnlaceVerificacion).Unix(), 10)
+	carga := base64.RawURLEncoding.EncodeToString([]byte(datos))
+	return carga + "." + f.firma(carga)
+}
+
+// Verificar valida la firma y la expiración y devuelve el usuario y el correo
+// para los que se emitió el token.
+func (f *FirmanteVerificacion) Verificar(token string, ahora time.Time) (uid, email string, err error) {
+	carga, firma, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(firma), []byte(f.firma(carga))) {
+		return "", "", ErrEnlaceVerificacionInvalido
+	}
+	datos, err := base64.RawURLEncoding.DecodeString(carga)
+	if err != nil {
+		return "", "", ErrEnlaceVerificacionInvalido
+	}
+	partes := strings.Split(string(datos), "\n")
+	if len(partes) != 3 {
+		return "", "", ErrEnlaceVerificacionInvalido
+	}
+	expira, err := strconv.ParseInt(partes[2], 10, 64)
+	if err != nil || ahora.Unix() > expira {
+		return "", "", ErrEnlaceVerificacionInvalido
+	}
+	return partes[0], partes[1], nil
+}
+
+func (f *FirmanteVerificacion) firma(carga string) string {
+	mac := hmac.New(sha256.New, f.clave)
+	// Prefijo de dominio: la clave puede compartirse con la cookie de sesión.
+	mac.Write([]byte("verificacion-email:" + carga))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,285 @@
+package busqueda
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"streaming-system/pkg/content"
+)
+
+// Pesos de cada campo: una coincidencia en el título cuenta como tres en la descripción.
+const (
+	pesoTitulo      = 3.0
+	pesoGenero      = 2.0
+	pesoReparto     = 2.0 // director de la película o narrador del documental
+	pesoTema        = 1.5
+	pesoDescripcion = 1.0
+	pesoEpisodio    = 1.0 // títulos de episodios de series y documentales
+)
+
+// Parámetros de BM25.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// documento es un contenido tal como lo ve el índice.
+type documento struct {
+	contenido content.Contenible
+	terminos  map[string]float64 // frecuencia ponderada por campo
+	longitud  float64
+	titulo    []string // tokens del título, para las sugerencias
+}
+
+// Resultado es un contenido encontrado con su puntuación BM25.
+type Resultado struct {
+	Contenido  content.Contenible
+	Puntuacion float64
+}
+
+// Buscador es un índice invertido del catálogo. Se mantiene al día solo:
+// antes de cada consulta compara la instantánea del gestor con la última
+// indexada y reindexa únicamente los contenidos que cambiaron. Es seguro para
+// uso concurrente.
+type Buscador struct {
+	gestor *content.GestorDeContenido
+
+	mu            sync.RWMutex
+	indexada      *content.Instantanea
+	docs          map[string]*documento
+	invertido     map[string]map[string]float64 // término -> ID -> frecuencia ponderada
+	vocabulario   []string                      // términos ordenados, para los prefijos
+	longitudTotal float64
+}
+
+// NuevoBuscador crea un buscador sobre el catálogo del gestor.
+func NuevoBuscador(g *content.GestorDeContenido) *Buscador {
+	b := &Buscador{gestor: g, docs: make(map[string]*documento), invertido: make(map[string]map[string]float64)}
+	b.sincronizar()
+	return b
+}
+
+// sincronizar aplica al índice los cambios del catálogo desde la última
+// instantánea indexada. Los contenidos del gestor no se modifican nunca (se
+// reemplazan por copias), así que basta comparar punteros.
+func (b *Buscador) sincronizar() {
+	actual := b.gestor.Instantanea()
+	b.mu.RLock()
+	aldia := b.indexada != nil && b.indexada.Version() == actual.Version()
+	b.mu.RUnlock()
+	if aldia {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	actual = b.gestor.Instantanea()
+	if b.indexada != nil && b.indexada.Version() >= actual.Version() {
+		return
+	}
+	cambios := false
+	for _, c := range actual.Todos() {
+		if d, ok := b.docs[c.GetID()]; !ok || d.contenido != c {
+			b.quitar(c.GetID())
+			b.indexar(c)
+			cambios = true
+		}
+	}
+	for id := range b.docs {
+		if _, err := actual.PorID(id); err != nil {
+			b.quitar(id)
+			cambios = true
+		}
+	}
+	if cambios {
+		b.vocabulario = b.vocabulario[:0]
+		for t := range b.invertido {
+			b.vocabulario = append(b.vocabulario, t)
+		}
+		slices.Sort(b.vocabulario)
+	}
+	b.indexada = actual
+}
+
+func (b *Buscador) indexar(c content.Contenible) {
+	d := &documento{contenido: c, terminos: make(map[string]float64), titulo: Tokenizar(c.GetTitulo())}
+	agregar := func(texto string, peso float64) {
+		for _, t := range Tokenizar(texto) {
+			d.terminos[t] += peso
+			d.longitud += peso
+		}
+	}
+	agregar(c.GetTitulo(), pesoTitulo)
+	agregar(c.GetDescripcion(), pesoDescripcion)
+	for _, g := range content.GenerosDe(c) {
+		agregar(g, pesoGenero)
+	}
+	switch v := c.(type) {
+	case *content.Pelicula:
+		agregar(v.GetDirector(), pesoReparto)
+	case *content.Serie:
+		for _, ep := range v.ObtenerEpisodios() {
+			agregar(ep.GetTitulo(), pesoEpisodio)
+		}
+	case *content.Documental:
+		agregar(v.GetNarrador(), pesoReparto)
+		agregar(v.GetTema(), pesoTema)
+		for _, ep := range v.ObtenerEpisodios() {
+			agregar(ep.GetTitulo(), pesoEpisodio)
+		}
+	}
+
+	b.docs[c.GetID()] = d
+	b.longitudTotal += d.longitud
+	for t, f := range d.terminos {
+		if b.invertido[t] == nil {
+			b.invertido[t] = make(map[string]float64)
+		}
+		b.invertido[t][c.GetID()] = f
+	}
+}
+
+func (b *Buscador) quitar(id string) {
+	d, ok := b.docs[id]
+	if !ok {
+		return
+	}
+	for t := range d.terminos {
+		delete(b.invertido[t], id)
+		if len(b.invertido[t]) == 0 {
+			delete(b.invertido, t)
+		}
+	}
+	b.longitudTotal -= d.longitud
+	delete(b.docs, id)
+}
+
+// expandir devuelve los términos del vocabulario que cuentan para un término
+// de la consulta, con su peso: el propio término si existe o, si no, los que
+// están a pocas letras de distancia, con menos peso cuanto más lejos.
+func (b *Buscador) expandir(termino string) map[string]float64 {
+	if _, ok := b.invertido[termino]; ok {
+		return map[string]float64{termino: 1}
+	}
+	res := make(map[string]float64)
+	max := toleranciaErrores(termino)
+	if max == 0 {
+		return res
+	}
+	runas := []rune(termino)
+	for _, v := range b.vocabulario {
+		if d := distancia(runas, []rune(v), max); d <= max {
+			res[v] = 1 / float64(1+d)
+		}
+	}
+	return res
+}
+
+// Buscar devuelve los contenidos que contienen alguno de los términos de la
+// consulta, del más relevante al menos. Los términos que no aparecen en el
+// catálogo se buscan con tolerancia a errores de escritura. filtro, si no es
+// nil, descarta contenidos (por ejemplo, por control parental).
+func (b *Buscador) Buscar(consulta string, filtro func(content.Contenible) bool) []Resultado {
+	b.sincronizar()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	n := float64(len(b.docs))
+	if n == 0 {
+		return nil
+	}
+	media := b.longitudTotal / n
+	puntos := make(map[string]float64)
+	vistos := make(map[string]bool)
+	for _, termino := range Tokenizar(consulta) {
+		if vistos[termino] {
+			continue
+		}
+		vistos[termino] = true
+		for t, peso := range b.expandir(termino) {
+			docs := b.invertido[t]
+			df := float64(len(docs))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for id, tf := range docs {
+				norma := bm25K1 * (1 - bm25B + bm25B*b.docs[id].longitud/media)
+				puntos[id] += peso * idf * tf * (bm25K1 + 1) / (tf + norma)
+			}
+		}
+	}
+
+	var res []Resultado
+	for id, p := range puntos {
+		if c := b.docs[id].contenido; filtro == nil || filtro(c) {
+			res = append(res, Resultado{c, p})
+		}
+	}
+	slices.SortFunc(res, func(x, y Resultado) int {
+		if x.Puntuacion != y.Puntuacion {
+			if x.Puntuacion > y.Puntuacion {
+				return -1
+			}
+			return 1
+		}
+		return content.CompararContenidos(x.Contenido, y.Contenido)
+	})
+	return res
+}
+
+// Sugerir autocompleta títulos mientras se escribe: la última palabra de la
+// consulta se toma como prefijo y las anteriores deben aparecer completas en
+// el título. Primero van los títulos que empiezan por la consulta. Devuelve
+// como mucho limite contenidos.
+func (b *Buscador) Sugerir(consulta string, limite int, filtro func(content.Contenible) bool) []content.Contenible {
+	tokens := Tokenizar(consulta)
+	if len(tokens) == 0 || limite <= 0 {
+		return nil
+	}
+	completos, prefijo := tokens[:len(tokens)-1], tokens[len(tokens)-1]
+	b.sincronizar()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Candidatos: documentos con algún término que empieza por el prefijo.
+	candidatos := make(map[string]bool)
+	i, _ := slices.BinarySearch(b.vocabulario, prefijo)
+	for ; i < len(b.vocabulario) && strings.HasPrefix(b.vocabulario[i], prefijo); i++ {
+		for id := range b.invertido[b.vocabulario[i]] {
+			candidatos[id] = true
+		}
+	}
+
+	inicio := strings.Join(tokens, " ")
+	var res []content.Contenible
+	empieza := make(map[string]bool)
+	for id := range candidatos {
+		d := b.docs[id]
+		if filtro != nil && !filtro(d.contenido) || !coincideTitulo(d.titulo, completos, prefijo) {
+			continue
+		}
+		empieza[id] = strings.HasPrefix(strings.Join(d.titulo, " "), inicio)
+		res = append(res, d.contenido)
+	}
+	slices.SortFunc(res, func(x, y content.Contenible) int {
+		if ex, ey := empieza[x.GetID()], empieza[y.GetID()]; ex != ey {
+			if ex {
+				return -1
+			}
+			return 1
+		}
+		return content.CompararContenidos(x, y)
+	})
+	return res[:min(limite, len(res))]
+}
+
+// coincideTitulo indica si el título contiene todas las palabras completas y
+// alguna que empiece por el prefijo.
+func coincideTitulo(titulo, completos []string, prefijo string) bool {
+	for _, t := range completos {
+		if !slices.Contains(titulo, t) {
+			return false
+		}
+	}
+	return slices.ContainsFunc(titulo, func(t string) bool { return strings.HasPrefix(t, prefijo) })
+}
@@ -0,0 +1,143 @@
+package busqueda
+
+import (
+	"slices"
+	"testing"
+
+	"streaming-system/pkg/content"
+)
+
+func catalogoDePrueba() *content.GestorDeContenido {
+	return content.NuevoGestorDeContenido([]content.Contenible{
+		content.NuevaPelicula("1", "Viaje al centro", "Aventura bajo tierra", []string{"Aventura"}, "Verne", "", 100, 1959, "es", 7),
+		content.NuevaPelicula("2", "Océano", "Un largo viaje por el mar", []string{"Naturaleza"}, "Cousteau", "", 90, 2010, "fr", 0),
+		content.NuevoDocumental("3", "Historia de España", "Dos mil años", "Historia", "Reyes", "Ana", 50, 0),
+		content.NuevaPelicula("4", "Película secreta", "Nada que ver", []string{"Drama"}, "Anónimo", "", 80, 2020, "es", 18),
+		content.NuevaSerie("5", "El gran viaje", "Una familia recorre el país en caravana durante un verano", "Comedia", 2, 7),
+	})
+}
+
+func ids(cs []content.Contenible) []string {
+	var res []string
+	for _, c := range cs {
+		res = append(res, c.GetID())
+	}
+	return res
+}
+
+func idsResultados(rs []Resultado) []string {
+	var res []string
+	for _, r := range rs {
+		res = append(res, r.Contenido.GetID())
+	}
+	return res
+}
+
+func sinAdultos(c content.Contenible) bool { return c.GetClasificacionEdad() < 18 }
+
+func TestBuscar(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		consulta string
+		filtro   func(content.Contenible) bool
+		esperado []string
+	}{
+		{"el título pesa más que la descripción", "viaje", nil, []string{"1", "5", "2"}},
+		{"sin tildes", "oceano", nil, []string{"2"}},
+		{"mayúsculas y ñ", "ESPANA", nil, []string{"3"}},
+		{"director", "verne", nil, []string{"1"}},
+		{"tema y narrador", "reyes ana", nil, []string{"3"}},
+		{"varios términos suman", "viaje mar", nil, []string{"2", "1", "5"}},
+		{"términos repetidos cuentan una vez", "oceano oceano", nil, []string{"2"}},
+		{"transposición", "vaije", nil, []string{"1", "5", "2"}},
+		{"dos errores en palabra larga", "pelicluaa", nil, []string{"4"}},
+		{"un error en palabra media", "secrata", nil, []string{"4"}},
+		{"sin tolerancia en palabras cortas", "mra", nil, nil},
+		{"un error en palabra corta", "vuaje", nil, []string{"1", "5", "2"}},
+		{"demasiados errores", "vuajr", nil, nil},
+		{"sin coincidencias", "zzzz", nil, nil},
+		{"consulta vacía", "  ¿? ", nil, nil},
+		{"filtro", "secreta", sinAdultos, nil},
+	}
+	b := NuevoBuscador(catalogoDePrueba())
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			res := b.Buscar(c.consulta, c.filtro)
+			if got := idsResultados(res); !slices.Equal(got, c.esperado) {
+				t.Fatalf("Buscar(%q) = %v, se esperaba %v", c.consulta, got, c.esperado)
+			}
+			for i := 1; i < len(res); i++ {
+				if res[i].Puntuacion > res[i-1].Puntuacion {
+					t.Fatalf("resultados desordenados: %+v", res)
+				}
+			}
+		})
+	}
+}
+
+func TestBuscarExpansion(t *testing.T) {
+	// Un término exacto no se expande; uno con erratas puntúa menos que el exacto.
+	b := NuevoBuscador(catalogoDePrueba())
+	exacto := b.Buscar("secreta", nil)
+	errata := b.Buscar("secrata", nil)
+	if len(exacto) != 1 || len(errata) != 1 || errata[0].Puntuacion >= exacto[0].Puntuacion {
+		t.Fatalf("exacto %+v, con errata %+v", exacto, errata)
+	}
+	if got := b.expandir("viaje"); len(got) != 1 || got["viaje"] != 1 {
+		t.Fatalf("expandir(viaje) = %v", got)
+	}
+	if got := b.expandir("vaije"); got["viaje"] != 0.5 {
+		t.Fatalf("expandir(vaije) = %v", got)
+	}
+}
+
+func TestBuscarSigueAlCatalogo(t *testing.T) {
+	g := catalogoDePrueba()
+	b := NuevoBuscador(g)
+	g.InsertarContenido(content.NuevaPelicula("6", "Viaje a la luna", "", []string{"Ciencia ficción"}, "Méliès", "", 14, 1902, "fr", 0))
+	g.BorrarContenido("5")
+	if err := g.Actualizar("2", func(c content.Contenible) error {
+		content.EditarComunes(c, "Océano", "Ballenas y delfines", 0)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := idsResultados(b.Buscar("viaje", nil)); !slices.Equal(got, []string{"1", "6"}) {
+		t.Fatalf("tras los cambios, Buscar(viaje) = %v", got)
+	}
+	if got := idsResultados(b.Buscar("delfines", nil)); !slices.Equal(got, []string{"2"}) {
+		t.Fatalf("Buscar(delfines) = %v", got)
+	}
+	if got := idsResultados(b.Buscar("caravana", nil)); got != nil {
+		t.Fatalf("un contenido borrado sigue en el índice: %v", got)
+	}
+}
+
+func TestSugerir(t *testing.T) {
+	casos := []struct {
+		consulta string
+		limite   int
+		filtro   func(content.Contenible) bool
+		esperado []string
+	}{
+		{"via", 10, nil, []string{"1", "5"}},
+		{"via", 1, nil, []string{"1"}},
+		{"gran v", 10, nil, []string{"5"}},
+		{"historia de es", 10, nil, []string{"3"}},
+		{"espa", 10, nil, []string{"3"}},
+		{"cen", 10, nil, []string{"1"}},
+		{"al via", 10, nil, []string{"1"}},
+		{"del via", 10, nil, nil}, // las palabras completas deben estar en el título
+		{"pel", 10, sinAdultos, nil},
+		{"pel", 10, nil, []string{"4"}},
+		{"mar", 10, nil, nil}, // solo aparece en la descripción
+		{"", 10, nil, nil},
+		{"via", 0, nil, nil},
+	}
+	b := NuevoBuscador(catalogoDePrueba())
+	for _, c := range casos {
+		if got := ids(b.Sugerir(c.consulta, c.limite, c.filtro)); !slices.Equal(got, c.esperado) {
+			t.Errorf("Sugerir(%q, %d) = %v, se esperaba %v", c.consulta, c.limite, got, c.esperado)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package busqueda
+
+import (
+	"strings"
+	"unicode"
+)
+
+// plegado sustituye las letras con tilde o diacrítico por su base, para que
+// "pelicula" encuentre "película" y "espana" encuentre "España".
+var plegado = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a', 'å': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ñ': 'n', 'ç': 'c', 'ý': 'y', 'ÿ': 'y',
+}
+
+// Normalizar pasa el texto a minúsculas y quita los diacríticos.
+func Normalizar(s string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if base, ok := plegado[r]; ok {
+			return base
+		}
+		return r
+	}, s)
+}
+
+// Tokenizar normaliza el texto y lo parte en palabras (letras y dígitos).
+func Tokenizar(s string) []string {
+	return strings.FieldsFunc(Normalizar(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// distancia calcula la distancia de edición entre a y b contando también
+// la transposición de dos letras contiguas ("pleicula"). Devuelve max+1 en
+// cuanto sabe que la distancia supera max.
+func distancia(a, b []rune, max int) int {
+	if d := len(a) - len(b); d > max || -d > max {
+		return max + 1
+	}
+	// Tres filas: la anterior a la anterior hace falta para la transposición.
+	previa2 := make([]int, len(b)+1)
+	previa := make([]int, len(b)+1)
+	actual := make([]int, len(b)+1)
+	for j := range previa {
+		previa[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		actual[0] = i
+		minimo := actual[0]
+		for j := 1; j <= len(b); j++ {
+			coste := 1
+			if a[i-1] == b[j-1] {
+				coste = 0
+			}
+			actual[j] = min(previa[j]+1, actual[j-1]+1, previa[j-1]+coste)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				actual[j] = min(actual[j], previa2[j-2]+1)
+			}
+			minimo = min(minimo, actual[j])
+		}
+		if minimo > max {
+			return max + 1
+		}
+		previa2, previa, actual = previa, actual, previa2
+	}
+	return previa[len(b)]
+}
+
+// toleranciaErrores es cuántas letras puede tener mal un término de la
+// consulta según su longitud: ninguna en palabras cortas, donde casi todo
+// estaría a una letra de distancia.
+func toleranciaErrores(termino string) int {
+	switch n := len([]rune(termino)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
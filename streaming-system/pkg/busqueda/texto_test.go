@@ -0,0 +1,68 @@
+package busqueda
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenizar(t *testing.T) {
+	casos := []struct {
+		texto    string
+		esperado []string
+	}{
+		{"Película", []string{"pelicula"}},
+		{"  El Señor de los Anillos: 2ª parte ", []string{"el", "senor", "de", "los", "anillos", "2ª", "parte"}},
+		{"Pokémon, ¡atrápalos!", []string{"pokemon", "atrapalos"}},
+		{"ÁÉÍÓÚÜÑ", []string{"aeiouun"}},
+		{"año-2001", []string{"ano", "2001"}},
+		{"¿?¡!", nil},
+	}
+	for _, c := range casos {
+		if got := Tokenizar(c.texto); !slices.Equal(got, c.esperado) {
+			t.Errorf("Tokenizar(%q) = %q, se esperaba %q", c.texto, got, c.esperado)
+		}
+	}
+}
+
+func TestDistancia(t *testing.T) {
+	casos := []struct {
+		a, b     string
+		max      int
+		esperado int
+	}{
+		{"pelicula", "pelicula", 2, 0},
+		{"pelicula", "pelicla", 2, 1},   // borrado
+		{"pelicula", "peliculas", 2, 1}, // inserción
+		{"pelicula", "pelicola", 2, 1},  // sustitución
+		{"pelicula", "pleicula", 2, 1},  // transposición
+		{"pelicula", "plecila", 2, 3},   // supera max: devuelve max+1
+		{"viaje", "vaije", 1, 1},
+		{"viaje", "vuajr", 1, 2},
+		{"mar", "marinero", 2, 3}, // longitudes demasiado distintas
+		{"", "abc", 3, 3},
+		{"ñandu", "nandu", 1, 1}, // distancia sobre runas, no bytes
+	}
+	for _, c := range casos {
+		if got := distancia([]rune(c.a), []rune(c.b), c.max); got != c.esperado {
+			t.Errorf("distancia(%q, %q, %d) = %d, se esperaba %d", c.a, c.b, c.max, got, c.esperado)
+		}
+	}
+}
+
+func TestToleranciaErrores(t *testing.T) {
+	casos := []struct {
+		termino  string
+		esperado int
+	}{
+		{"mar", 0},
+		{"viaj", 1},
+		{"españa", 1},
+		{"pelicul", 1},
+		{"pelicula", 2},
+	}
+	for _, c := range casos {
+		if got := toleranciaErrores(c.termino); got != c.esperado {
+			t.Errorf("toleranciaErrores(%q) = %d, se esperaba %d", c.termino, got, c.esperado)
+		}
+	}
+}
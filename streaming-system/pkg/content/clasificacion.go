@@ -0,0 +1,45 @@
+package content
+
+import "fmt"
+
+// Clasificacion es una calificación por edad del catálogo (CLASIFICACION_EDAD).
+type Clasificacion struct {
+	Edad     int
+	Etiqueta string
+}
+
+// clasificaciones son las calificaciones que ofrece el panel, de menor a mayor.
+var clasificaciones = []Clasificacion{
+	{0, "ATP"},
+	{7, "7+"},
+	{13, "13+"},
+	{16, "16+"},
+	{18, "18+"},
+}
+
+// ClasificacionesDisponibles devuelve las calificaciones seleccionables.
+func ClasificacionesDisponibles() []Clasificacion { return clasificaciones }
+
+// EsClasificacionValida indica si la edad es una de ClasificacionesDisponibles.
+func EsClasificacionValida(edad int) bool {
+	for _, c := range clasificaciones {
+		if c.Edad == edad {
+			return true
+		}
+	}
+	return false
+}
+
+// EtiquetaClasificacion devuelve el texto a mostrar para una edad mínima.
+func EtiquetaClasificacion(edad int) string {
+	if edad <= 0 {
+		return "ATP"
+	}
+	return fmt.Sprintf("%d+", edad)
+}
+
+// ApropiadoPara indica si el contenido puede mostrarse a un perfil cuya
+// clasificación máxima es edadMaxima.
+func ApropiadoPara(c Contenible, edadMaxima int) bool {
+	return c.GetClasificacionEdad() <= edadMaxima
+}
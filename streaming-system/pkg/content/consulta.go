@@ -0,0 +1,322 @@
+package content
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrCursorInvalido indica un cursor de paginación mal formado o de otro orden.
+	ErrCursorInvalido = errors.New("el cursor de paginación no es válido")
+	// ErrOrdenInvalido indica un criterio de orden desconocido.
+	ErrOrdenInvalido = errors.New("el criterio de orden no es válido")
+)
+
+// Criterios de orden de ConsultaCatalogo.
+const (
+	OrdenTitulo    = "titulo"    // alfabético (CompararContenidos); el predeterminado
+	OrdenRecientes = "recientes" // año de estreno descendente; sin año al final
+	OrdenAntiguos  = "antiguos"  // año de estreno ascendente; sin año al final
+)
+
+// Tamaños de página de ConsultaCatalogo.
+const (
+	LimitePaginaPredeterminado = 24
+	LimitePaginaMaximo         = 100
+)
+
+// ConsultaCatalogo filtra, ordena y pagina el catálogo. Los campos vacíos no
+// filtran; los de lista aceptan cualquiera de sus valores.
+type ConsultaCatalogo struct {
+	Tipos           []string // TipoPelicula, TipoSerie, TipoDocumental
+	Generos         []string // sin distinguir mayúsculas
+	Clasificaciones []int    // edades mínimas exactas
+	AnioDesde       int      // inclusive; 0 = sin límite
+	AnioHasta       int      // inclusive; 0 = sin límite
+	SoloEstrenos    bool
+	// Visible descarta lo que el usuario no puede ver (control parental) antes
+	// de filtrar, para que tampoco cuente en las facetas. nil = todo.
+	Visible func(Contenible) bool
+
+	Orden  string // OrdenTitulo si está vacío
+	Cursor string // PaginaCatalogo.Siguiente de la página anterior
+	Limite int    // LimitePaginaPredeterminado si es 0
+}
+
+// ValorFaceta es un valor de un filtro con cuántos contenidos tendría.
+type ValorFaceta struct {
+	Valor    string
+	Cantidad int
+}
+
+// Facetas cuenta, para cada filtro, cuántos contenidos habría con cada valor
+// si se aplicaran los demás filtros de la consulta pero no ese. Así se puede
+// mostrar qué pasaría al cambiar de opción dentro de un mismo filtro.
+type Facetas struct {
+	Tipos           []ValorFaceta // en el orden de TipoPelicula, TipoSerie, TipoDocumental
+	Generos         []ValorFaceta // alfabético
+	Clasificaciones []ValorFaceta // de menor a mayor edad
+	Anios           []ValorFaceta // del más reciente al más antiguo
+	Estrenos        int
+}
+
+// PaginaCatalogo es el resultado de una ConsultaCatalogo.
+type PaginaCatalogo struct {
+	Contenidos []Contenible
+	Total      int    // contenidos que cumplen los filtros, en todas las páginas
+	Siguiente  string // cursor de la página siguiente; vacío si es la última
+	Facetas    Facetas
+}
+
+// dimensiones de filtro, para calcular las facetas.
+const (
+	filtroTipo = iota
+	filtroGenero
+	filtroClasificacion
+	filtroAnio
+	filtroEstreno
+	numFiltros
+)
+
+// cursorCatalogo es la posición del último contenido de una página.
+type cursorCatalogo struct {
+	Orden  string `json:"o"`
+	Anio   int    `json:"a,omitempty"`
+	Titulo string `json:"t"`
+	ID     string `json:"i"`
+}
+
+func cursorDe(c Contenible, orden string) cursorCatalogo {
+	return cursorCatalogo{Orden: orden, Anio: AnioDe(c), Titulo: c.GetTitulo(), ID: c.GetID()}
+}
+
+func (c cursorCatalogo) codificar() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodificarCursor(s, orden string) (cursorCatalogo, error) {
+	var c cursorCatalogo
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.Orden != orden || c.ID == "" {
+		return cursorCatalogo{}, ErrCursorInvalido
+	}
+	return c, nil
+}
+
+// compararCursores ordena posiciones según el criterio. Todos los criterios
+// desempatan con CompararContenidos, así que el orden es total y un cursor
+// sigue siendo válido aunque el catálogo cambie entre páginas.
+func compararCursores(a, b cursorCatalogo, orden string) int {
+	porTitulo := cmp.Or(
+		strings.Compare(strings.ToLower(a.Titulo), strings.ToLower(b.Titulo)),
+		strings.Compare(a.ID, b.ID),
+	)
+	if orden == OrdenTitulo || a.Anio == b.Anio {
+		return porTitulo
+	}
+	// Los contenidos sin año van al final en ambos sentidos.
+	if a.Anio == 0 || b.Anio == 0 {
+		return cmp.Compare(b.Anio, a.Anio)
+	}
+	if orden == OrdenRecientes {
+		return cmp.Compare(b.Anio, a.Anio)
+	}
+	return cmp.Compare(a.Anio, b.Anio)
+}
+
+// Consultar aplica la consulta a la instantánea.
+func (in *Instantanea) Consultar(q ConsultaCatalogo) (*PaginaCatalogo, error) {
+	orden := cmp.Or(q.Orden, OrdenTitulo)
+	if orden != OrdenTitulo && orden != OrdenRecientes && orden != OrdenAntiguos {
+		return nil, ErrOrdenInvalido
+	}
+	limite := q.Limite
+	if limite <= 0 {
+		limite = LimitePaginaPredeterminado
+	}
+	limite = min(limite, LimitePaginaMaximo)
+	var desde *cursorCatalogo
+	if q.Cursor != "" {
+		c, err := decodificarCursor(q.Cursor, orden)
+		if err != nil {
+			return nil, err
+		}
+		desde = &c
+	}
+
+	generos := make(map[string]bool, len(q.Generos))
+	for _, g := range q.Generos {
+		generos[ClaveGenero(g)] = true
+	}
+	conteoTipos := make(map[string]int)
+	conteoGeneros := make(map[string]int)
+	conteoClasif := make(map[int]int)
+	conteoAnios := make(map[int]int)
+	res := &PaginaCatalogo{}
+	var coinciden []Contenible
+
+	for _, c := range in.candidatos(q, generos) {
+		if q.Visible != nil && !q.Visible(c) {
+			continue
+		}
+		anio := AnioDe(c)
+		var pasa [numFiltros]bool
+		pasa[filtroTipo] = len(q.Tipos) == 0 || slices.Contains(q.Tipos, TipoDe(c))
+		pasa[filtroGenero] = len(generos) == 0 || slices.ContainsFunc(GenerosDe(c), func(g string) bool { return generos[ClaveGenero(g)] })
+		pasa[filtroClasificacion] = len(q.Clasificaciones) == 0 || slices.Contains(q.Clasificaciones, c.GetClasificacionEdad())
+		pasa[filtroAnio] = (q.AnioDesde == 0 || anio != 0 && anio >= q.AnioDesde) && (q.AnioHasta == 0 || anio != 0 && anio <= q.AnioHasta)
+		pasa[filtroEstreno] = !q.SoloEstrenos || c.EsEstreno()
+
+		// salvo(f) indica si pasa todos los filtros menos f.
+		salvo := func(f int) bool {
+			for i, ok := range pasa {
+				if i != f && !ok {
+					return false
+				}
+			}
+			return true
+		}
+		if salvo(filtroTipo) {
+			conteoTipos[TipoDe(c)]++
+		}
+		if salvo(filtroGenero) {
+			for _, g := range GenerosDe(c) {
+				conteoGeneros[ClaveGenero(g)]++
+			}
+		}
+		if salvo(filtroClasificacion) {
+			conteoClasif[c.GetClasificacionEdad()]++
+		}
+		if salvo(filtroAnio) && anio != 0 {
+			conteoAnios[anio]++
+		}
+		if salvo(filtroEstreno) && c.EsEstreno() {
+			res.Facetas.Estrenos++
+		}
+		if salvo(-1) {
+			coinciden = append(coinciden, c)
+		}
+	}
+
+	// in.orden ya está en OrdenTitulo.
+	if orden != OrdenTitulo {
+		slices.SortStableFunc(coinciden, func(a, b Contenible) int {
+			return compararCursores(cursorDe(a, orden), cursorDe(b, orden), orden)
+		})
+	}
+	res.Total = len(coinciden)
+	inicio := 0
+	if desde != nil {
+		inicio, _ = slices.BinarySearchFunc(coinciden, *desde, func(c Contenible, d cursorCatalogo) int {
+			return compararCursores(cursorDe(c, orden), d, orden)
+		})
+		// El propio contenido del cursor ya se mostró.
+		if inicio < len(coinciden) && coinciden[inicio].GetID() == desde.ID {
+			inicio++
+		}
+	}
+	fin := min(inicio+limite, len(coinciden))
+	res.Contenidos = slices.Clone(coinciden[inicio:fin])
+	if fin < len(coinciden) {
+		res.Siguiente = cursorDe(coinciden[fin-1], orden).codificar()
+	}
+
+	for _, t := range []string{TipoPelicula, TipoSerie, TipoDocumental} {
+		if n := conteoTipos[t]; n > 0 {
+			res.Facetas.Tipos = append(res.Facetas.Tipos, ValorFaceta{t, n})
+		}
+	}
+	for _, g := range in.generos {
+		if n := conteoGeneros[ClaveGenero(g)]; n > 0 {
+			res.Facetas.Generos = append(res.Facetas.Generos, ValorFaceta{g, n})
+		}
+	}
+	for _, cl := range clasificacionesPresentes(conteoClasif) {
+		res.Facetas.Clasificaciones = append(res.Facetas.Clasificaciones, ValorFaceta{strconv.Itoa(cl), conteoClasif[cl]})
+	}
+	for _, a := range in.Anios() {
+		if n := conteoAnios[a]; n > 0 {
+			res.Facetas.Anios = append(res.Facetas.Anios, ValorFaceta{strconv.Itoa(a), n})
+		}
+	}
+	return res, nil
+}
+
+// candidatos devuelve, en orden de título, los contenidos que hay que examinar.
+// Las facetas cuentan todo lo que falla como mucho un filtro, así que con menos
+// de dos filtros indexados hay que recorrer el catálogo entero. Con dos o más
+// basta la unión de los dos índices más pequeños: lo que no está en ninguno
+// falla al menos dos filtros y no cuenta en nada.
+func (in *Instantanea) candidatos(q ConsultaCatalogo, generos map[string]bool) []Contenible {
+	var listas [][]Contenible
+	if len(q.Tipos) > 0 {
+		listas = append(listas, unirIndice(in.porTipo, q.Tipos))
+	}
+	if len(generos) > 0 {
+		listas = append(listas, unirIndice(in.porGenero, slices.Collect(maps.Keys(generos))))
+	}
+	if len(q.Clasificaciones) > 0 {
+		listas = append(listas, unirIndice(in.porClasificacion, q.Clasificaciones))
+	}
+	if q.AnioDesde != 0 || q.AnioHasta != 0 {
+		var anios []int
+		for a := range in.porAnio {
+			if (q.AnioDesde == 0 || a >= q.AnioDesde) && (q.AnioHasta == 0 || a <= q.AnioHasta) {
+				anios = append(anios, a)
+			}
+		}
+		listas = append(listas, unirIndice(in.porAnio, anios))
+	}
+	if len(listas) < 2 {
+		return in.orden
+	}
+	slices.SortFunc(listas, func(a, b []Contenible) int { return cmp.Compare(len(a), len(b)) })
+	return unirOrdenadas(listas[0], listas[1])
+}
+
+// unirIndice une las listas del índice para varias claves.
+func unirIndice[K comparable](indice map[K][]Contenible, claves []K) []Contenible {
+	var res []Contenible
+	for _, k := range claves {
+		res = unirOrdenadas(res, indice[k])
+	}
+	return res
+}
+
+// unirOrdenadas mezcla dos listas ordenadas con CompararContenidos sin repetir
+// contenidos (uno puede estar en varios géneros).
+func unirOrdenadas(a, b []Contenible) []Contenible {
+	res := make([]Contenible, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch d := CompararContenidos(a[0], b[0]); {
+		case d < 0:
+			res, a = append(res, a[0]), a[1:]
+		case d > 0:
+			res, b = append(res, b[0]), b[1:]
+		default:
+			res, a, b = append(res, a[0]), a[1:], b[1:]
+		}
+	}
+	return append(append(res, a...), b...)
+}
+
+func clasificacionesPresentes(conteo map[int]int) []int {
+	res := make([]int, 0, len(conteo))
+	for edad := range conteo {
+		res = append(res, edad)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Consultar aplica la consulta al estado actual del catálogo.
+func (g *GestorDeContenido) Consultar(q ConsultaCatalogo) (*PaginaCatalogo, error) {
+	return g.Instantanea().Consultar(q)
+}
@@ -0,0 +1,266 @@
+package content
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// catalogoConsulta tiene dos títulos iguales (desempate por ID), varios
+// contenidos del mismo año y dos sin año, que van al final en los órdenes por año.
+func catalogoConsulta() *GestorDeContenido {
+	amanecer := NuevaPelicula("1", "Amanecer", "", []string{"Drama"}, "", "", 90, 2001, "es", 0)
+	MarcarEstreno(amanecer, true)
+	ciudad := NuevaPelicula("3", "Ciudad", "", []string{"Drama", "Acción"}, "", "", 90, 2010, "es", 13)
+	MarcarEstreno(ciudad, true)
+	return NuevoGestorDeContenido([]Contenible{
+		amanecer,
+		NuevaPelicula("7", "amanecer", "", []string{"Drama"}, "", "", 90, 2001, "es", 0),
+		NuevaPelicula("2", "Bosque", "", []string{"Terror"}, "", "", 90, 1999, "es", 16),
+		ciudad,
+		NuevaPelicula("4", "Delta", "", []string{"Acción"}, "", "", 90, 2001, "es", 7),
+		NuevaSerie("5", "Eco", "", "Drama", 1, 7),
+		NuevoDocumental("6", "Faro", "", "Historia", "", "", 50, 0),
+	})
+}
+
+func idsDe(cs []Contenible) []string {
+	res := []string{}
+	for _, c := range cs {
+		res = append(res, c.GetID())
+	}
+	return res
+}
+
+// recorrer pide páginas hasta la última y devuelve los IDs en orden.
+func recorrer(t *testing.T, g *GestorDeContenido, q ConsultaCatalogo) []string {
+	t.Helper()
+	ids := []string{}
+	for paginas := 0; ; paginas++ {
+		if paginas > 100 {
+			t.Fatal("la paginación no termina")
+		}
+		p, err := g.Consultar(q)
+		if err != nil {
+			t.Fatalf("Consultar: %v", err)
+		}
+		ids = append(ids, idsDe(p.Contenidos)...)
+		if p.Siguiente == "" {
+			return ids
+		}
+		q.Cursor = p.Siguiente
+	}
+}
+
+func TestConsultarPaginacion(t *testing.T) {
+	casos := []struct {
+		orden    string
+		esperado []string
+	}{
+		{"", []string{"1", "7", "2", "3", "4", "5", "6"}},
+		{OrdenTitulo, []string{"1", "7", "2", "3", "4", "5", "6"}},
+		{OrdenRecientes, []string{"3", "1", "7", "4", "2", "5", "6"}},
+		{OrdenAntiguos, []string{"2", "1", "7", "4", "3", "5", "6"}},
+	}
+	g := catalogoConsulta()
+	for _, c := range casos {
+		for _, limite := range []int{1, 2, 3, 6, 7, 0} {
+			if got := recorrer(t, g, ConsultaCatalogo{Orden: c.orden, Limite: limite}); !slices.Equal(got, c.esperado) {
+				t.Errorf("orden %q, límite %d: %v, se esperaba %v", c.orden, limite, got, c.esperado)
+			}
+		}
+	}
+}
+
+func TestConsultarPaginacionConCambios(t *testing.T) {
+	// Entre la primera página y las siguientes cambia el catálogo: lo que ya
+	// se mostró no se repite y lo que faltaba no se salta.
+	casos := []struct {
+		nombre  string
+		orden   string
+		limite  int
+		primera []string
+		cambio  func(g *GestorDeContenido)
+		resto   []string
+	}{
+		{"alta antes del cursor", OrdenTitulo, 2, []string{"1", "7"}, func(g *GestorDeContenido) {
+			g.InsertarContenido(NuevaPelicula("8", "Abeto", "", nil, "", "", 90, 2000, "es", 0))
+		}, []string{"2", "3", "4", "5", "6"}},
+		{"alta después del cursor", OrdenTitulo, 2, []string{"1", "7"}, func(g *GestorDeContenido) {
+			g.InsertarContenido(NuevaPelicula("8", "Cebra", "", nil, "", "", 90, 2000, "es", 0))
+		}, []string{"2", "8", "3", "4", "5", "6"}},
+		{"baja del contenido del cursor", OrdenTitulo, 2, []string{"1", "7"}, func(g *GestorDeContenido) {
+			g.BorrarContenido("7")
+		}, []string{"2", "3", "4", "5", "6"}},
+		{"baja de uno pendiente", OrdenTitulo, 2, []string{"1", "7"}, func(g *GestorDeContenido) {
+			g.BorrarContenido("3")
+		}, []string{"2", "4", "5", "6"}},
+		{"alta en el mismo año que el cursor", OrdenRecientes, 2, []string{"3", "1"}, func(g *GestorDeContenido) {
+			g.InsertarContenido(NuevaPelicula("8", "Bravo", "", nil, "", "", 90, 2001, "es", 0))
+		}, []string{"7", "8", "4", "2", "5", "6"}},
+		{"alta en un año ya mostrado", OrdenRecientes, 2, []string{"3", "1"}, func(g *GestorDeContenido) {
+			g.InsertarContenido(NuevaPelicula("8", "Zorro", "", nil, "", "", 90, 2010, "es", 0))
+		}, []string{"7", "4", "2", "5", "6"}},
+		{"alta sin año con el cursor entre los sin año", OrdenAntiguos, 6, []string{"2", "1", "7", "4", "3", "5"}, func(g *GestorDeContenido) {
+			g.InsertarContenido(NuevoDocumental("8", "Abismo", "", "Historia", "", "", 50, 0))
+			g.InsertarContenido(NuevoDocumental("9", "Glaciar", "", "Historia", "", "", 50, 0))
+		}, []string{"6", "9"}},
+		{"baja de todos los pendientes", OrdenAntiguos, 3, []string{"2", "1", "7"}, func(g *GestorDeContenido) {
+			for _, id := range []string{"4", "3", "5", "6"} {
+				g.BorrarContenido(id)
+			}
+		}, []string{}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			g := catalogoConsulta()
+			q := ConsultaCatalogo{Orden: c.orden, Limite: c.limite}
+			p, err := g.Consultar(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := idsDe(p.Contenidos); !slices.Equal(got, c.primera) || p.Siguiente == "" {
+				t.Fatalf("primera página %v (siguiente %q), se esperaba %v", got, p.Siguiente, c.primera)
+			}
+			c.cambio(g)
+			q.Cursor = p.Siguiente
+			if got := recorrer(t, g, q); !slices.Equal(got, c.resto) {
+				t.Fatalf("resto %v, se esperaba %v", got, c.resto)
+			}
+		})
+	}
+}
+
+func TestConsultarErrores(t *testing.T) {
+	g := catalogoConsulta()
+	p, err := g.Consultar(ConsultaCatalogo{Limite: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	casos := []struct {
+		nombre string
+		q      ConsultaCatalogo
+		err    error
+	}{
+		{"orden desconocido", ConsultaCatalogo{Orden: "popular"}, ErrOrdenInvalido},
+		{"cursor que no es base64", ConsultaCatalogo{Cursor: "%%%"}, ErrCursorInvalido},
+		{"cursor que no es JSON", ConsultaCatalogo{Cursor: base64.RawURLEncoding.EncodeToString([]byte("hola"))}, ErrCursorInvalido},
+		{"cursor sin ID", ConsultaCatalogo{Cursor: cursorCatalogo{Orden: OrdenTitulo, Titulo: "A"}.codificar()}, ErrCursorInvalido},
+		{"cursor de otro orden", ConsultaCatalogo{Orden: OrdenRecientes, Cursor: p.Siguiente}, ErrCursorInvalido},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if _, err := g.Consultar(c.q); !errors.Is(err, c.err) {
+				t.Fatalf("error = %v, se esperaba %v", err, c.err)
+			}
+		})
+	}
+}
+
+func TestConsultarLimite(t *testing.T) {
+	casos := []struct {
+		limite, esperado int
+	}{
+		{0, LimitePaginaPredeterminado},
+		{-5, LimitePaginaPredeterminado},
+		{10, 10},
+		{LimitePaginaMaximo + 1, LimitePaginaMaximo},
+	}
+	var cs []Contenible
+	for i := range LimitePaginaMaximo + 10 {
+		cs = append(cs, NuevaSerie(strconv.Itoa(i+1), "Serie", "", "Drama", 1, 0))
+	}
+	g := NuevoGestorDeContenido(cs)
+	for _, c := range casos {
+		p, err := g.Consultar(ConsultaCatalogo{Limite: c.limite})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p.Contenidos) != c.esperado || p.Total != len(cs) {
+			t.Errorf("límite %d: %d contenidos de %d, se esperaban %d", c.limite, len(p.Contenidos), p.Total, c.esperado)
+		}
+	}
+}
+
+func TestConsultarFacetas(t *testing.T) {
+	// Cada faceta cuenta con los demás filtros aplicados pero no el suyo.
+	casos := []struct {
+		nombre  string
+		q       ConsultaCatalogo
+		ids     []string
+		facetas Facetas
+	}{
+		{"sin filtros", ConsultaCatalogo{}, []string{"1", "7", "2", "3", "4", "5", "6"}, Facetas{
+			Tipos:           []ValorFaceta{{TipoPelicula, 5}, {TipoSerie, 1}, {TipoDocumental, 1}},
+			Generos:         []ValorFaceta{{"Acción", 2}, {"Drama", 4}, {"Historia", 1}, {"Terror", 1}},
+			Clasificaciones: []ValorFaceta{{"0", 3}, {"7", 2}, {"13", 1}, {"16", 1}},
+			Anios:           []ValorFaceta{{"2010", 1}, {"2001", 3}, {"1999", 1}},
+			Estrenos:        2,
+		}},
+		{"género sin distinguir mayúsculas", ConsultaCatalogo{Generos: []string{"drama"}}, []string{"1", "7", "3", "5"}, Facetas{
+			Tipos:           []ValorFaceta{{TipoPelicula, 3}, {TipoSerie, 1}},
+			Generos:         []ValorFaceta{{"Acción", 2}, {"Drama", 4}, {"Historia", 1}, {"Terror", 1}},
+			Clasificaciones: []ValorFaceta{{"0", 2}, {"7", 1}, {"13", 1}},
+			Anios:           []ValorFaceta{{"2010", 1}, {"2001", 2}},
+			Estrenos:        2,
+		}},
+		{"tipo, género y año", ConsultaCatalogo{Tipos: []string{TipoPelicula}, Generos: []string{"Drama"}, AnioDesde: 2001}, []string{"1", "7", "3"}, Facetas{
+			Tipos:           []ValorFaceta{{TipoPelicula, 3}},
+			Generos:         []ValorFaceta{{"Acción", 2}, {"Drama", 3}},
+			Clasificaciones: []ValorFaceta{{"0", 2}, {"13", 1}},
+			Anios:           []ValorFaceta{{"2010", 1}, {"2001", 2}},
+			Estrenos:        2,
+		}},
+		{"estrenos y clasificación", ConsultaCatalogo{SoloEstrenos: true, Clasificaciones: []int{0}}, []string{"1"}, Facetas{
+			Tipos:           []ValorFaceta{{TipoPelicula, 1}},
+			Generos:         []ValorFaceta{{"Drama", 1}},
+			Clasificaciones: []ValorFaceta{{"0", 1}, {"13", 1}},
+			Anios:           []ValorFaceta{{"2001", 1}},
+			Estrenos:        1,
+		}},
+		{"contenido en dos de los géneros pedidos", ConsultaCatalogo{Tipos: []string{TipoPelicula}, Generos: []string{"Drama", "acción"}}, []string{"1", "7", "3", "4"}, Facetas{
+			Tipos:           []ValorFaceta{{TipoPelicula, 4}, {TipoSerie, 1}},
+			Generos:         []ValorFaceta{{"Acción", 2}, {"Drama", 3}, {"Terror", 1}},
+			Clasificaciones: []ValorFaceta{{"0", 2}, {"7", 1}, {"13", 1}},
+			Anios:           []ValorFaceta{{"2010", 1}, {"2001", 3}},
+			Estrenos:        2,
+		}},
+		{"varios valores del mismo filtro", ConsultaCatalogo{Clasificaciones: []int{7, 16}, AnioHasta: 2001}, []string{"2", "4"}, Facetas{
+			Tipos:           []ValorFaceta{{TipoPelicula, 2}},
+			Generos:         []ValorFaceta{{"Acción", 1}, {"Terror", 1}},
+			Clasificaciones: []ValorFaceta{{"0", 2}, {"7", 1}, {"16", 1}},
+			Anios:           []ValorFaceta{{"2001", 1}, {"1999", 1}},
+		}},
+		{"lo no visible no cuenta", ConsultaCatalogo{Visible: func(c Contenible) bool { return c.GetClasificacionEdad() < 13 }}, []string{"1", "7", "4", "5", "6"}, Facetas{
+			Tipos:           []ValorFaceta{{TipoPelicula, 3}, {TipoSerie, 1}, {TipoDocumental, 1}},
+			Generos:         []ValorFaceta{{"Acción", 1}, {"Drama", 3}, {"Historia", 1}},
+			Clasificaciones: []ValorFaceta{{"0", 3}, {"7", 2}},
+			Anios:           []ValorFaceta{{"2001", 3}},
+			Estrenos:        1,
+		}},
+		{"sin resultados", ConsultaCatalogo{Tipos: []string{TipoSerie}, Generos: []string{"Terror"}}, []string{}, Facetas{
+			Tipos:           []ValorFaceta{{TipoPelicula, 1}},
+			Generos:         []ValorFaceta{{"Drama", 1}},
+			Clasificaciones: nil,
+			Anios:           nil,
+		}},
+	}
+	g := catalogoConsulta()
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			p, err := g.Consultar(c.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := idsDe(p.Contenidos); !slices.Equal(got, c.ids) || p.Total != len(c.ids) {
+				t.Fatalf("contenidos %v (total %d), se esperaba %v", got, p.Total, c.ids)
+			}
+			if !reflect.DeepEqual(p.Facetas, c.facetas) {
+				t.Fatalf("facetas\n%+v\nse esperaba\n%+v", p.Facetas, c.facetas)
+			}
+		})
+	}
+}
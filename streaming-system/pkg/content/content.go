@@ -3,10 +3,109 @@ package content
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 var ErrContenidoNoEncontrado = errors.New("contenido no encontrado")
 
+// ErrTipoNoSoportado indica un Contenible que el gestor no sabe modificar.
+var ErrTipoNoSoportado = errors.New("tipo de contenido no soportado")
+
+// Tipos de contenido, tal como se guardan en contenidos.TIPO.
+const (
+	TipoPelicula   = "pelicula"
+	TipoSerie      = "serie"
+	TipoDocumental = "documental"
+)
+
+// MarcarEstreno destaca o no el contenido como estreno. Solo debe usarse
+// antes de pasarlo al gestor o sobre la copia de GestorDeContenido.Actualizar.
+func MarcarEstreno(c Contenible, estreno bool) {
+	switch v := c.(type) {
+	case *Pelicula:
+		v.estreno = estreno
+	case *Serie:
+		v.estreno = estreno
+	case *Documental:
+		v.estreno = estreno
+	}
+}
+
+// EditarComunes cambia el título, la descripción y la clasificación. Como
+// MarcarEstreno, solo sobre contenidos que el gestor aún no publica.
+func EditarComunes(c Contenible, titulo, descripcion string, edadMinima int) {
+	switch v := c.(type) {
+	case *Pelicula:
+		v.titulo, v.descripcion, v.edadMinima = titulo, descripcion, edadMinima
+	case *Serie:
+		v.titulo, v.descripcion, v.edadMinima = titulo, descripcion, edadMinima
+	case *Documental:
+		v.titulo, v.descripcion, v.edadMinima = titulo, descripcion, edadMinima
+	}
+}
+
+// ReemplazarDatos copia en c todos los datos de nuevo, incluidas sus
+// temporadas o episodios, para reemplazar un contenido desde
+// GestorDeContenido.Actualizar. Los dos deben ser del mismo tipo.
+func ReemplazarDatos(c, nuevo Contenible) error {
+	if TipoDe(c) != TipoDe(nuevo) {
+		return ErrCambioDeTipo
+	}
+	switch v := c.(type) {
+	case *Pelicula:
+		*v = *clonar(nuevo).(*Pelicula)
+	case *Serie:
+		*v = *clonar(nuevo).(*Serie)
+	case *Documental:
+		*v = *clonar(nuevo).(*Documental)
+	default:
+		return ErrTipoNoSoportado
+	}
+	return nil
+}
+
+// clonar devuelve una copia del contenido que se puede modificar sin afectar
+// al original, o nil si el tipo no es conocido. Los episodios se comparten
+// porque no cambian una vez creados.
+func clonar(c Contenible) Contenible {
+	switch v := c.(type) {
+	case *Pelicula:
+		copia := *v
+		copia.generos = slices.Clone(v.generos)
+		return &copia
+	case *Serie:
+		copia := *v
+		copia.temporadas = make([]*Temporada, len(v.temporadas))
+		for i, t := range v.temporadas {
+			tc := *t
+			tc.episodios = slices.Clone(t.episodios)
+			copia.temporadas[i] = &tc
+		}
+		return &copia
+	case *Documental:
+		copia := *v
+		copia.episodios = slices.Clone(v.episodios)
+		return &copia
+	}
+	return nil
+}
+
+// TipoDe devuelve el tipo de un contenido, o "" si no es uno conocido.
+func TipoDe(c Contenible) string {
+	switch c.(type) {
+	case *Pelicula:
+		return TipoPelicula
+	case *Serie:
+		return TipoSerie
+	case *Documental:
+		return TipoDocumental
+	}
+	return ""
+}
+
 // Contenible actualizado con GetDescripcion
 type Contenible interface {
 	GetID() string
@@ -14,6 +113,8 @@ type Contenible interface {
 	GetDescripcion() string // <-- NUEVO: Ahora la descripción es accesible
 	GetGenero() string
 	GetDuracionTotal() float32
+	GetClasificacionEdad() int // edad mínima recomendada; 0 es apto para todo público
+	EsEstreno() bool           // destacado como novedad (ES_ESTRENO)
 	Reproducir()
 }
 
@@ -23,33 +124,133 @@ type Pelicula struct {
 	id          string
 	titulo      string
 	descripcion string
-	genero      string
+	generos     []string
 	director    string
 	trailerLink string
-	duracion    float32
+	duracion    float32 // minutos; 0 si no se conoce
+	anio        int     // año de estreno; 0 si no se conoce
+	idioma      string  // código ISO 639-1 del idioma original
+	edadMinima  int
+	estreno     bool
 }
 
-func NuevaPelicula(id, titulo, descripcion, genero, director, trailerLink string, duracion float32) *Pelicula {
+// NuevaPelicula crea una película; los géneros se normalizan con
+// NormalizarGeneros y el idioma se pasa a minúsculas. No valida: ver Validar.
+func NuevaPelicula(id, titulo, descripcion string, generos []string, director, trailerLink string, duracion float32, anio int, idioma string, edadMinima int) *Pelicula {
 	return &Pelicula{
-		id: id, titulo: titulo, descripcion: descripcion, genero: genero,
-		director: director, trailerLink: trailerLink, duracion: duracion,
+		id: id, titulo: strings.TrimSpace(titulo), descripcion: strings.TrimSpace(descripcion), generos: NormalizarGeneros(generos),
+		director: strings.TrimSpace(director), trailerLink: strings.TrimSpace(trailerLink), duracion: duracion,
+		anio: anio, idioma: strings.ToLower(strings.TrimSpace(idioma)), edadMinima: edadMinima,
 	}
 }
 
 func (p *Pelicula) GetID() string             { return p.id }
 func (p *Pelicula) GetTitulo() string         { return p.titulo }
 func (p *Pelicula) GetDescripcion() string    { return p.descripcion } // <-- Implementación
-func (p *Pelicula) GetGenero() string         { return p.genero }
+func (p *Pelicula) GetGeneros() []string      { return p.generos }
+func (p *Pelicula) GetGenero() string         { return strings.Join(p.generos, ", ") }
+func (p *Pelicula) GetDirector() string       { return p.director }
+func (p *Pelicula) GetTrailerLink() string    { return p.trailerLink }
+func (p *Pelicula) GetAnio() int              { return p.anio }
+func (p *Pelicula) GetIdioma() string         { return p.idioma }
 func (p *Pelicula) GetDuracionTotal() float32 { return p.duracion }
+func (p *Pelicula) GetClasificacionEdad() int { return p.edadMinima }
+func (p *Pelicula) EsEstreno() bool           { return p.estreno }
 func (p *Pelicula) Reproducir() {
 	fmt.Printf("▶️ Iniciando reproducción: Película '%s'\n", p.titulo)
 }
 
+// SepararGeneros parte una lista de géneros separada por comas.
+func SepararGeneros(s string) []string {
+	return NormalizarGeneros(strings.Split(s, ","))
+}
+
+// NormalizarGeneros recorta los géneros y quita vacíos y repetidos (sin
+// distinguir mayúsculas), conservando el orden.
+func NormalizarGeneros(generos []string) []string {
+	res := make([]string, 0, len(generos))
+	vistos := make(map[string]bool)
+	for _, g := range generos {
+		g = strings.TrimSpace(g)
+		if g == "" || vistos[strings.ToLower(g)] {
+			continue
+		}
+		vistos[strings.ToLower(g)] = true
+		res = append(res, g)
+	}
+	return res
+}
+
 // --- ESTRUCTURA: Serie ---
 
+var (
+	// ErrDatosEpisodio indica un episodio sin número, sin título, sin duración o con textos demasiado largos.
+	ErrDatosEpisodio = errors.New("el episodio necesita número, título (hasta 100 caracteres), una duración positiva y una descripción de hasta 500 caracteres")
+	// ErrDatosTemporada indica un número de temporada no positivo o un título demasiado largo.
+	ErrDatosTemporada = errors.New("la temporada necesita un número positivo y un título de hasta 100 caracteres")
+	// ErrTemporadaNoEncontrada indica que la serie no tiene esa temporada.
+	ErrTemporadaNoEncontrada = errors.New("la temporada no existe")
+	// ErrEpisodioNoEncontrado indica que la temporada no tiene ese episodio.
+	ErrEpisodioNoEncontrado = errors.New("el episodio no existe")
+)
+
 type Episodio struct {
-	titulo   string
-	duracion float32
+	numero      int
+	titulo      string
+	descripcion string
+	duracion    float32
+	emision     time.Time // cero si no se conoce la fecha de emisión
+}
+
+// NuevoEpisodio valida y crea un episodio. La duración está en minutos.
+func NuevoEpisodio(numero int, titulo, descripcion string, duracion float32, emision time.Time) (*Episodio, error) {
+	titulo, descripcion = strings.TrimSpace(titulo), strings.TrimSpace(descripcion)
+	if numero <= 0 || titulo == "" || duracion <= 0 || utf8.RuneCountInString(titulo) > 100 || utf8.RuneCountInString(descripcion) > 500 {
+		return nil, ErrDatosEpisodio
+	}
+	return &Episodio{numero: numero, titulo: titulo, descripcion: descripcion, duracion: duracion, emision: emision}, nil
+}
+
+// RecreateEpisodioFromDB reconstruye un episodio persistido sin volver a validarlo.
+func RecreateEpisodioFromDB(numero int, titulo, descripcion string, duracion float32, emision time.Time) *Episodio {
+	return &Episodio{numero: numero, titulo: titulo, descripcion: descripcion, duracion: duracion, emision: emision}
+}
+
+func (e *Episodio) GetNumero() int         { return e.numero }
+func (e *Episodio) GetTitulo() string      { return e.titulo }
+func (e *Episodio) GetDescripcion() string { return e.descripcion }
+func (e *Episodio) GetDuracion() float32   { return e.duracion }
+func (e *Episodio) GetEmision() time.Time  { return e.emision }
+
+// Temporada agrupa los episodios de una serie, ordenados por número.
+type Temporada struct {
+	numero    int
+	titulo    string // opcional
+	episodios []*Episodio
+}
+
+func (t *Temporada) GetNumero() int                { return t.numero }
+func (t *Temporada) GetTitulo() string             { return t.titulo }
+func (t *Temporada) ObtenerEpisodios() []*Episodio { return t.episodios }
+func (t *Temporada) GetDuracionTotal() float32 {
+	var total float32
+	for _, ep := range t.episodios {
+		total += ep.duracion
+	}
+	return total
+}
+
+// guardarEpisodio reemplaza el episodio con el mismo número o lo inserta en orden.
+func (t *Temporada) guardarEpisodio(ep *Episodio) {
+	i := 0
+	for i < len(t.episodios) && t.episodios[i].numero < ep.numero {
+		i++
+	}
+	if i < len(t.episodios) && t.episodios[i].numero == ep.numero {
+		t.episodios[i] = ep
+		return
+	}
+	t.episodios = append(t.episodios[:i], append([]*Episodio{ep}, t.episodios[i:]...)...)
 }
 
 type Serie struct {
@@ -57,25 +258,33 @@ type Serie struct {
 	titulo      string
 	descripcion string
 	genero      string
-	temporadas  int
-	episodios   []*Episodio
+	temporadas  []*Temporada // ordenadas por número
+	edadMinima  int
+	estreno     bool
 }
 
-func NuevaSerie(id, titulo, descripcion, genero string, temporadas int) *Serie {
-	return &Serie{
+// NuevaSerie crea la serie con las temporadas 1..temporadas vacías.
+func NuevaSerie(id, titulo, descripcion, genero string, temporadas, edadMinima int) *Serie {
+	s := &Serie{
 		id: id, titulo: titulo, descripcion: descripcion, genero: genero,
-		temporadas: temporadas, episodios: make([]*Episodio, 0),
+		temporadas: make([]*Temporada, 0, temporadas), edadMinima: edadMinima,
+	}
+	for n := 1; n <= temporadas; n++ {
+		s.temporadas = append(s.temporadas, &Temporada{numero: n})
 	}
+	return s
 }
 
-func (s *Serie) GetID() string          { return s.id }
-func (s *Serie) GetTitulo() string      { return s.titulo }
-func (s *Serie) GetDescripcion() string { return s.descripcion } // <-- Implementación
-func (s *Serie) GetGenero() string      { return s.genero }
+func (s *Serie) GetID() string             { return s.id }
+func (s *Serie) GetTitulo() string         { return s.titulo }
+func (s *Serie) GetDescripcion() string    { return s.descripcion } // <-- Implementación
+func (s *Serie) GetGenero() string         { return s.genero }
+func (s *Serie) GetClasificacionEdad() int { return s.edadMinima }
+func (s *Serie) EsEstreno() bool           { return s.estreno }
 func (s *Serie) GetDuracionTotal() float32 {
 	var total float32
-	for _, ep := range s.episodios {
-		total += ep.duracion
+	for _, t := range s.temporadas {
+		total += t.GetDuracionTotal()
 	}
 	return total
 }
@@ -84,7 +293,147 @@ func (s *Serie) Reproducir() {
 }
 
 // Métodos adicionales de Serie
+
+// AgregarEpisodio añade un episodio al final de la última temporada (la 1 si no hay ninguna).
 func (s *Serie) AgregarEpisodio(titulo string, duracion float32) {
-	s.episodios = append(s.episodios, &Episodio{titulo: titulo, duracion: duracion})
+	if len(s.temporadas) == 0 {
+		s.temporadas = append(s.temporadas, &Temporada{numero: 1})
+	}
+	t := s.temporadas[len(s.temporadas)-1]
+	t.episodios = append(t.episodios, &Episodio{numero: len(t.episodios) + 1, titulo: titulo, duracion: duracion})
+}
+
+// ObtenerEpisodios devuelve todos los episodios, temporada tras temporada.
+func (s *Serie) ObtenerEpisodios() []*Episodio {
+	var res []*Episodio
+	for _, t := range s.temporadas {
+		res = append(res, t.episodios...)
+	}
+	return res
+}
+
+func (s *Serie) ObtenerTemporadas() []*Temporada { return s.temporadas }
+func (s *Serie) GetNumeroTemporadas() int        { return len(s.temporadas) }
+
+// ObtenerTemporada devuelve la temporada con ese número, o nil.
+func (s *Serie) ObtenerTemporada(numero int) *Temporada {
+	for _, t := range s.temporadas {
+		if t.numero == numero {
+			return t
+		}
+	}
+	return nil
+}
+
+// GuardarTemporada crea la temporada o, si ya existe, le cambia el título.
+func (s *Serie) GuardarTemporada(numero int, titulo string) error {
+	titulo = strings.TrimSpace(titulo)
+	if numero <= 0 || utf8.RuneCountInString(titulo) > 100 {
+		return ErrDatosTemporada
+	}
+	if t := s.ObtenerTemporada(numero); t != nil {
+		t.titulo = titulo
+		return nil
+	}
+	i := 0
+	for i < len(s.temporadas) && s.temporadas[i].numero < numero {
+		i++
+	}
+	s.temporadas = append(s.temporadas[:i], append([]*Temporada{{numero: numero, titulo: titulo}}, s.temporadas[i:]...)...)
+	return nil
+}
+
+// BorrarTemporada elimina la temporada con todos sus episodios.
+func (s *Serie) BorrarTemporada(numero int) error {
+	for i, t := range s.temporadas {
+		if t.numero == numero {
+			s.temporadas = append(s.temporadas[:i], s.temporadas[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTemporadaNoEncontrada
+}
+
+// GuardarEpisodio añade el episodio a la temporada o reemplaza el que tenga su número.
+func (s *Serie) GuardarEpisodio(temporada int, ep *Episodio) error {
+	t := s.ObtenerTemporada(temporada)
+	if t == nil {
+		return ErrTemporadaNoEncontrada
+	}
+	t.guardarEpisodio(ep)
+	return nil
+}
+
+// BorrarEpisodio elimina un episodio de una temporada.
+func (s *Serie) BorrarEpisodio(temporada, numero int) error {
+	t := s.ObtenerTemporada(temporada)
+	if t == nil {
+		return ErrTemporadaNoEncontrada
+	}
+	for i, ep := range t.episodios {
+		if ep.numero == numero {
+			t.episodios = append(t.episodios[:i], t.episodios[i+1:]...)
+			return nil
+		}
+	}
+	return ErrEpisodioNoEncontrado
+}
+
+// --- ESTRUCTURA: Documental ---
+
+// Documental es una pieza de no ficción sobre un tema. Puede ser un único
+// largometraje o estar dividido en episodios.
+type Documental struct {
+	id          string
+	titulo      string
+	descripcion string
+	genero      string
+	tema        string
+	narrador    string
+	duracion    float32 // solo se usa si no tiene episodios
+	episodios   []*Episodio
+	edadMinima  int
+	estreno     bool
+}
+
+func NuevoDocumental(id, titulo, descripcion, genero, tema, narrador string, duracion float32, edadMinima int) *Documental {
+	return &Documental{
+		id: id, titulo: titulo, descripcion: descripcion, genero: genero, tema: tema,
+		narrador: narrador, duracion: duracion, episodios: make([]*Episodio, 0), edadMinima: edadMinima,
+	}
+}
+
+func (d *Documental) GetID() string             { return d.id }
+func (d *Documental) GetTitulo() string         { return d.titulo }
+func (d *Documental) GetDescripcion() string    { return d.descripcion }
+func (d *Documental) GetGenero() string         { return d.genero }
+func (d *Documental) GetClasificacionEdad() int { return d.edadMinima }
+func (d *Documental) EsEstreno() bool           { return d.estreno }
+func (d *Documental) GetTema() string           { return d.tema }
+func (d *Documental) GetNarrador() string       { return d.narrador }
+
+// GetDuracionTotal suma los episodios o, si no los tiene, devuelve la duración propia.
+func (d *Documental) GetDuracionTotal() float32 {
+	if !d.EsEpisodico() {
+		return d.duracion
+	}
+	var total float32
+	for _, ep := range d.episodios {
+		total += ep.duracion
+	}
+	return total
+}
+func (d *Documental) Reproducir() {
+	if d.EsEpisodico() {
+		fmt.Printf("▶️ Iniciando reproducción: Documental '%s' (Primer episodio)\n", d.titulo)
+		return
+	}
+	fmt.Printf("▶️ Iniciando reproducción: Documental '%s'\n", d.titulo)
+}
+
+// Métodos adicionales de Documental
+func (d *Documental) AgregarEpisodio(titulo string, duracion float32) {
+	d.episodios = append(d.episodios, &Episodio{numero: len(d.episodios) + 1, titulo: titulo, duracion: duracion})
 }
-func (s *Serie) ObtenerEpisodios() []*Episodio { return s.episodios }
+func (d *Documental) ObtenerEpisodios() []*Episodio { return d.episodios }
+func (d *Documental) EsEpisodico() bool             { return len(d.episodios) > 0 }
@@ -0,0 +1,565 @@
+package content
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrFormatoDesconocido indica un formato de importación o exportación no soportado.
+	ErrFormatoDesconocido = errors.New("el formato debe ser csv, json o ndjson")
+	// ErrCabeceraCSV indica un CSV sin las columnas tipo e id o con columnas desconocidas.
+	ErrCabeceraCSV = errors.New("la cabecera del CSV debe incluir tipo e id y solo columnas conocidas")
+	// ErrTipoRegistro indica un tipo de fila distinto de pelicula, serie, documental, temporada o episodio.
+	ErrTipoRegistro = errors.New("el tipo debe ser pelicula, serie, documental, temporada o episodio")
+	// ErrCambioDeTipo indica que la fila cambiaría el tipo de un contenido existente.
+	ErrCambioDeTipo = errors.New("ya existe un contenido con ese ID y es de otro tipo")
+	// ErrIDRepetido indica que el archivo define dos veces el mismo contenido.
+	ErrIDRepetido = errors.New("el archivo ya define un contenido con ese ID")
+	// ErrSinClasificacion indica un contenido sin clasificación por edad.
+	ErrSinClasificacion = errors.New("falta la clasificación por edad")
+	// ErrEpisodioSinContenido indica un episodio de una serie o documental que no existe.
+	ErrEpisodioSinContenido = errors.New("el episodio no corresponde a ninguna serie ni documental")
+	// ErrTemporadaSinSerie indica una temporada de una serie que no existe.
+	ErrTemporadaSinSerie = errors.New("la temporada no corresponde a ninguna serie")
+	// ErrEpisodioDeContenidoConErrores indica un episodio cuya serie o documental tiene errores en el archivo.
+	ErrEpisodioDeContenidoConErrores = errors.New("la serie o el documental del episodio tiene errores en el archivo")
+	// ErrFechaEmision indica una fecha de emisión que no es AAAA-MM-DD.
+	ErrFechaEmision = errors.New("la fecha de emisión debe tener el formato AAAA-MM-DD")
+)
+
+// Formatos de importación y exportación del catálogo.
+const (
+	FormatoCSV    = "csv"
+	FormatoJSON   = "json"
+	FormatoNDJSON = "ndjson"
+)
+
+// Tipos de las filas que no son contenidos: TipoTemporada crea o renombra una
+// temporada de una serie y TipoEpisodio añade episodios a una serie o a un documental.
+const (
+	TipoTemporada = "temporada"
+	TipoEpisodio  = "episodio"
+)
+
+// RegistroCatalogo es una fila de importación o exportación. Las columnas del
+// CSV y las claves del JSON son los nombres de las etiquetas json.
+//
+// Las filas de contenido (pelicula, serie, documental) crean el contenido o,
+// si ya existe su ID, reemplazan sus metadatos conservando las temporadas y
+// episodios. Las filas de temporada usan id para la serie y crean la temporada
+// o le cambian el título (titulo_temporada), aunque no tenga episodios. Las
+// filas de episodio usan id para la serie o el documental:
+// en una serie crean o reemplazan el episodio temporada/episodio (creando la
+// temporada si hace falta); en un documental, los episodios del archivo
+// reemplazan a los que tuviera, en el orden del archivo.
+type RegistroCatalogo struct {
+	Tipo            string  `json:"tipo"`
+	ID              string  `json:"id"`
+	Titulo          string  `json:"titulo"`
+	Descripcion     string  `json:"descripcion,omitempty"`
+	Generos         string  `json:"generos,omitempty"` // separados por comas
+	Clasificacion   *int    `json:"clasificacion,omitempty"`
+	Estreno         bool    `json:"estreno,omitempty"`
+	Director        string  `json:"director,omitempty"`
+	Trailer         string  `json:"trailer,omitempty"`
+	Duracion        float32 `json:"duracion,omitempty"` // minutos
+	Anio            int     `json:"anio,omitempty"`
+	Idioma          string  `json:"idioma,omitempty"`
+	Tema            string  `json:"tema,omitempty"`
+	Narrador        string  `json:"narrador,omitempty"`
+	Temporadas      int     `json:"temporadas,omitempty"` // temporadas 1..N vacías de una serie nueva
+	Temporada       int     `json:"temporada,omitempty"`
+	TituloTemporada string  `json:"titulo_temporada,omitempty"`
+	Episodio        int     `json:"episodio,omitempty"`
+	Emision         string  `json:"emision,omitempty"` // AAAA-MM-DD
+}
+
+// columnasCSV es el orden de las columnas al exportar.
+var columnasCSV = []string{
+	"tipo", "id", "titulo", "descripcion", "generos", "clasificacion", "estreno", "director", "trailer", "duracion",
+	"anio", "idioma", "tema", "narrador", "temporadas", "temporada", "titulo_temporada", "episodio", "emision",
+}
+
+// FilaCatalogo es un registro leído con su posición en el archivo (línea del
+// CSV o del NDJSON, elemento del JSON). Err indica que no se pudo leer.
+type FilaCatalogo struct {
+	Numero   int
+	Registro RegistroCatalogo
+	Err      error
+}
+
+// LeerCatalogo lee todas las filas de un archivo. Devuelve error solo si el
+// archivo en conjunto no se puede leer; los problemas de cada fila quedan en
+// FilaCatalogo.Err.
+func LeerCatalogo(r io.Reader, formato string) ([]FilaCatalogo, error) {
+	switch formato {
+	case FormatoCSV:
+		return leerCSV(r)
+	case FormatoJSON:
+		var elementos []json.RawMessage
+		if err := json.NewDecoder(r).Decode(&elementos); err != nil {
+			return nil, fmt.Errorf("JSON no válido: se esperaba una lista de objetos: %w", err)
+		}
+		filas := make([]FilaCatalogo, len(elementos))
+		for i, e := range elementos {
+			filas[i] = FilaCatalogo{Numero: i + 1}
+			filas[i].Err = decodificarRegistro(e, &filas[i].Registro)
+		}
+		return filas, nil
+	case FormatoNDJSON:
+		var filas []FilaCatalogo
+		lector := bufio.NewScanner(r)
+		lector.Buffer(nil, 1<<20)
+		for n := 1; lector.Scan(); n++ {
+			if linea := bytes.TrimSpace(lector.Bytes()); len(linea) > 0 {
+				f := FilaCatalogo{Numero: n}
+				f.Err = decodificarRegistro(linea, &f.Registro)
+				filas = append(filas, f)
+			}
+		}
+		return filas, lector.Err()
+	}
+	return nil, ErrFormatoDesconocido
+}
+
+func decodificarRegistro(b []byte, reg *RegistroCatalogo) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(reg); err != nil {
+		return fmt.Errorf("JSON no válido: %w", err)
+	}
+	return nil
+}
+
+func leerCSV(r io.Reader) ([]FilaCatalogo, error) {
+	lector := csv.NewReader(r)
+	lector.FieldsPerRecord = -1
+	cabecera, err := lector.Read()
+	if err != nil {
+		return nil, fmt.Errorf("CSV sin cabecera: %w", err)
+	}
+	indice := make(map[string]int, len(cabecera))
+	for i, c := range cabecera {
+		c = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(c, "\ufeff")))
+		if !slices.Contains(columnasCSV, c) {
+			return nil, ErrCabeceraCSV
+		}
+		indice[c] = i
+	}
+	if _, ok := indice["tipo"]; !ok {
+		return nil, ErrCabeceraCSV
+	}
+	if _, ok := indice["id"]; !ok {
+		return nil, ErrCabeceraCSV
+	}
+
+	var filas []FilaCatalogo
+	for {
+		campos, err := lector.Read()
+		if err == io.EOF {
+			return filas, nil
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				return nil, err
+			}
+			filas = append(filas, FilaCatalogo{Numero: pe.Line, Err: err})
+			continue
+		}
+		linea, _ := lector.FieldPos(0)
+		valor := func(col string) string {
+			if i, ok := indice[col]; ok && i < len(campos) {
+				return strings.TrimSpace(campos[i])
+			}
+			return ""
+		}
+		if strings.Join(campos, "") == "" {
+			continue
+		}
+		f := FilaCatalogo{Numero: linea}
+		f.Registro, f.Err = registroDeCSV(valor)
+		filas = append(filas, f)
+	}
+}
+
+// registroDeCSV convierte los campos de texto; un número vacío es 0.
+func registroDeCSV(valor func(string) string) (RegistroCatalogo, error) {
+	reg := RegistroCatalogo{
+		Tipo: valor("tipo"), ID: valor("id"), Titulo: valor("titulo"), Descripcion: valor("descripcion"),
+		Generos: valor("generos"), Director: valor("director"), Trailer: valor("trailer"), Idioma: valor("idioma"),
+		Tema: valor("tema"), Narrador: valor("narrador"), TituloTemporada: valor("titulo_temporada"), Emision: valor("emision"),
+	}
+	var errs []error
+	entero := func(col string) int {
+		if valor(col) == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(valor(col))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q no es un número entero", col, valor(col)))
+		}
+		return n
+	}
+	if valor("clasificacion") != "" {
+		edad := entero("clasificacion")
+		reg.Clasificacion = &edad
+	}
+	reg.Anio, reg.Temporadas, reg.Temporada, reg.Episodio = entero("anio"), entero("temporadas"), entero("temporada"), entero("episodio")
+	if d := valor("duracion"); d != "" {
+		duracion, err := strconv.ParseFloat(d, 32)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("duracion: %q no es un número", d))
+		}
+		reg.Duracion = float32(duracion)
+	}
+	if e := valor("estreno"); e != "" {
+		estreno, err := strconv.ParseBool(e)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("estreno: %q debe ser true/false o 1/0", e))
+		}
+		reg.Estreno = estreno
+	}
+	return reg, errors.Join(errs...)
+}
+
+// ErrorFila son los problemas de una fila del archivo.
+type ErrorFila struct {
+	Fila    int
+	ID      string
+	Errores []string
+}
+
+// ResultadoImportacion describe lo que hace (o haría) una importación.
+type ResultadoImportacion struct {
+	Creados      []string // IDs de los contenidos nuevos
+	Actualizados []string // IDs de los contenidos existentes que cambian
+	Temporadas   int      // filas de temporada aplicadas
+	Episodios    int      // filas de episodio aplicadas
+	Errores      []ErrorFila
+	// Contenidos son los contenidos resultantes, completos (con todas sus
+	// temporadas y episodios), listos para persistir y pasar al gestor.
+	Contenidos []Contenible
+}
+
+// Valida indica si ninguna fila tiene errores. Una importación con errores no
+// debe aplicarse: así el catálogo nunca queda a medio importar.
+func (r *ResultadoImportacion) Valida() bool { return len(r.Errores) == 0 }
+
+// PlanificarImportacion calcula el resultado de aplicar las filas sobre la
+// instantánea, sin modificarla: sirve tanto para la simulación como para la
+// importación real.
+func PlanificarImportacion(in *Instantanea, filas []FilaCatalogo) *ResultadoImportacion {
+	res := &ResultadoImportacion{}
+	porID := make(map[string]Contenible) // contenidos que cambian, ya copiados
+	definidos := make(map[string]bool)   // IDs con fila de contenido en el archivo
+	conErrores := make(map[string]bool)  // IDs cuya fila de contenido falló
+	var orden []string
+	fallo := func(f FilaCatalogo, errs ...error) {
+		ef := ErrorFila{Fila: f.Numero, ID: f.Registro.ID}
+		for _, e := range errs {
+			ef.Errores = append(ef.Errores, mensajesDe(e)...)
+		}
+		res.Errores = append(res.Errores, ef)
+	}
+
+	// Primero los contenidos, para que un episodio pueda ir antes que su serie.
+	for _, f := range filas {
+		reg := f.Registro
+		reg.ID = strings.TrimSpace(reg.ID)
+		if f.Err != nil {
+			fallo(f, f.Err)
+			if !esFilaDeSerie(reg.Tipo) {
+				conErrores[reg.ID] = true
+			}
+			continue
+		}
+		if esFilaDeSerie(reg.Tipo) {
+			continue
+		}
+		if definidos[reg.ID] {
+			fallo(f, ErrIDRepetido)
+			continue
+		}
+		definidos[reg.ID] = true
+		anterior, _ := in.PorID(reg.ID)
+		c, err := contenidoDeRegistro(reg, anterior)
+		if err == nil {
+			err = Validar(c)
+		}
+		if err != nil {
+			fallo(f, err)
+			conErrores[reg.ID] = true
+			continue
+		}
+		if anterior == nil {
+			res.Creados = append(res.Creados, reg.ID)
+		} else {
+			res.Actualizados = append(res.Actualizados, reg.ID)
+		}
+		porID[reg.ID] = c
+		orden = append(orden, reg.ID)
+	}
+
+	reemplazados := make(map[string]bool) // documentales cuyos episodios ya se vaciaron
+	for _, f := range filas {
+		reg := f.Registro
+		reg.ID = strings.TrimSpace(reg.ID)
+		if f.Err != nil || !esFilaDeSerie(reg.Tipo) {
+			continue
+		}
+		if conErrores[reg.ID] {
+			fallo(f, ErrEpisodioDeContenidoConErrores)
+			continue
+		}
+		c, ok := porID[reg.ID]
+		if !ok {
+			existente, err := in.PorID(reg.ID)
+			if err != nil {
+				fallo(f, sinContenido(reg.Tipo))
+				continue
+			}
+			c = clonar(existente)
+		}
+		var err error
+		switch v := c.(type) {
+		case *Serie:
+			if reg.Tipo == TipoTemporada {
+				err = v.GuardarTemporada(reg.Temporada, reg.TituloTemporada)
+			} else {
+				err = aplicarEpisodioSerie(v, reg)
+			}
+		case *Documental:
+			if reg.Tipo == TipoTemporada {
+				err = ErrTemporadaSinSerie
+				break
+			}
+			if !reemplazados[reg.ID] {
+				v.episodios = nil
+				reemplazados[reg.ID] = true
+			}
+			if _, err = NuevoEpisodio(len(v.episodios)+1, reg.Titulo, "", reg.Duracion, time.Time{}); err == nil {
+				v.AgregarEpisodio(strings.TrimSpace(reg.Titulo), reg.Duracion)
+			}
+		default:
+			err = sinContenido(reg.Tipo)
+		}
+		if err != nil {
+			fallo(f, err)
+			continue
+		}
+		if _, ok := porID[reg.ID]; !ok {
+			porID[reg.ID] = c
+			orden = append(orden, reg.ID)
+			res.Actualizados = append(res.Actualizados, reg.ID)
+		}
+		if reg.Tipo == TipoTemporada {
+			res.Temporadas++
+		} else {
+			res.Episodios++
+		}
+	}
+
+	for _, id := range orden {
+		res.Contenidos = append(res.Contenidos, porID[id])
+	}
+	slices.SortStableFunc(res.Errores, func(a, b ErrorFila) int { return cmp.Compare(a.Fila, b.Fila) })
+	return res
+}
+
+// esFilaDeSerie indica las filas que completan una serie o un documental
+// definido en otra fila o ya existente.
+func esFilaDeSerie(tipo string) bool { return tipo == TipoTemporada || tipo == TipoEpisodio }
+
+func sinContenido(tipo string) error {
+	if tipo == TipoTemporada {
+		return ErrTemporadaSinSerie
+	}
+	return ErrEpisodioSinContenido
+}
+
+// contenidoDeRegistro construye el contenido de una fila. Si ya existía,
+// conserva lo que la fila no describe: temporadas y episodios.
+func contenidoDeRegistro(reg RegistroCatalogo, anterior Contenible) (Contenible, error) {
+	if reg.Tipo != TipoPelicula && reg.Tipo != TipoSerie && reg.Tipo != TipoDocumental {
+		return nil, ErrTipoRegistro
+	}
+	if anterior != nil && TipoDe(anterior) != reg.Tipo {
+		return nil, ErrCambioDeTipo
+	}
+	if reg.Clasificacion == nil {
+		return nil, ErrSinClasificacion
+	}
+	edad := *reg.Clasificacion
+	var c Contenible
+	switch reg.Tipo {
+	case TipoPelicula:
+		c = NuevaPelicula(reg.ID, strings.TrimSpace(reg.Titulo), strings.TrimSpace(reg.Descripcion), SepararGeneros(reg.Generos),
+			strings.TrimSpace(reg.Director), strings.TrimSpace(reg.Trailer), reg.Duracion, reg.Anio, strings.TrimSpace(reg.Idioma), edad)
+	case TipoDocumental:
+		d := NuevoDocumental(reg.ID, strings.TrimSpace(reg.Titulo), strings.TrimSpace(reg.Descripcion), strings.TrimSpace(reg.Generos),
+			strings.TrimSpace(reg.Tema), strings.TrimSpace(reg.Narrador), reg.Duracion, edad)
+		if anterior != nil {
+			d.episodios = append(d.episodios, anterior.(*Documental).episodios...)
+		}
+		c = d
+	case TipoSerie:
+		if anterior == nil {
+			c = NuevaSerie(reg.ID, strings.TrimSpace(reg.Titulo), strings.TrimSpace(reg.Descripcion), strings.TrimSpace(reg.Generos),
+				min(max(reg.Temporadas, 0), 50), edad)
+			break
+		}
+		s := clonar(anterior).(*Serie)
+		s.titulo, s.descripcion = strings.TrimSpace(reg.Titulo), strings.TrimSpace(reg.Descripcion)
+		s.genero, s.edadMinima = strings.TrimSpace(reg.Generos), edad
+		c = s
+	default:
+		return nil, ErrTipoRegistro
+	}
+	MarcarEstreno(c, reg.Estreno)
+	return c, nil
+}
+
+func aplicarEpisodioSerie(s *Serie, reg RegistroCatalogo) error {
+	var emision time.Time
+	if reg.Emision != "" {
+		var err error
+		if emision, err = time.Parse(time.DateOnly, reg.Emision); err != nil {
+			return ErrFechaEmision
+		}
+	}
+	ep, err := NuevoEpisodio(reg.Episodio, reg.Titulo, reg.Descripcion, reg.Duracion, emision)
+	if err != nil {
+		return err
+	}
+	// Crea la temporada si no existe; solo cambia el título si la fila trae uno.
+	titulo := reg.TituloTemporada
+	if t := s.ObtenerTemporada(reg.Temporada); t != nil && titulo == "" {
+		titulo = t.titulo
+	}
+	if err := s.GuardarTemporada(reg.Temporada, titulo); err != nil {
+		return err
+	}
+	return s.GuardarEpisodio(reg.Temporada, ep)
+}
+
+// mensajesDe separa los errores unidos con errors.Join.
+func mensajesDe(err error) []string {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		var res []string
+		for _, e := range j.Unwrap() {
+			res = append(res, mensajesDe(e)...)
+		}
+		return res
+	}
+	return []string{err.Error()}
+}
+
+// RegistrosDe describe un contenido como filas de exportación: la del
+// contenido seguida, en las series, de una por temporada con sus episodios
+// detrás y, en los documentales, de una por episodio. Cada temporada lleva su
+// número y título, así que las vacías o no consecutivas se conservan.
+func RegistrosDe(c Contenible) []RegistroCatalogo {
+	edad := c.GetClasificacionEdad()
+	reg := RegistroCatalogo{
+		Tipo: TipoDe(c), ID: c.GetID(), Titulo: c.GetTitulo(), Descripcion: c.GetDescripcion(),
+		Generos: c.GetGenero(), Clasificacion: &edad, Estreno: c.EsEstreno(),
+	}
+	res := []RegistroCatalogo{reg}
+	switch v := c.(type) {
+	case *Pelicula:
+		res[0].Director, res[0].Trailer, res[0].Duracion = v.director, v.trailerLink, v.duracion
+		res[0].Anio, res[0].Idioma = v.anio, v.idioma
+	case *Documental:
+		res[0].Tema, res[0].Narrador = v.tema, v.narrador
+		if !v.EsEpisodico() {
+			res[0].Duracion = v.duracion
+		}
+		for _, ep := range v.episodios {
+			res = append(res, RegistroCatalogo{Tipo: TipoEpisodio, ID: v.id, Titulo: ep.titulo, Duracion: ep.duracion})
+		}
+	case *Serie:
+		for _, t := range v.temporadas {
+			res = append(res, RegistroCatalogo{Tipo: TipoTemporada, ID: v.id, Temporada: t.numero, TituloTemporada: t.titulo})
+			for _, ep := range t.episodios {
+				r := RegistroCatalogo{Tipo: TipoEpisodio, ID: v.id, Temporada: t.numero, Episodio: ep.numero, Titulo: ep.titulo, Descripcion: ep.descripcion, Duracion: ep.duracion}
+				if !ep.emision.IsZero() {
+					r.Emision = ep.emision.Format(time.DateOnly)
+				}
+				res = append(res, r)
+			}
+		}
+	}
+	return res
+}
+
+// ExportarCatalogo escribe los contenidos en el formato indicado, de modo que
+// LeerCatalogo y PlanificarImportacion los reconstruyan en otro entorno.
+func ExportarCatalogo(w io.Writer, formato string, contenidos []Contenible) error {
+	var registros []RegistroCatalogo
+	for _, c := range contenidos {
+		registros = append(registros, RegistrosDe(c)...)
+	}
+	switch formato {
+	case FormatoJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		if registros == nil {
+			registros = []RegistroCatalogo{}
+		}
+		return e.Encode(registros)
+	case FormatoNDJSON:
+		e := json.NewEncoder(w)
+		for _, r := range registros {
+			if err := e.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatoCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(columnasCSV)
+		for _, r := range registros {
+			cw.Write(camposCSV(r))
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return ErrFormatoDesconocido
+}
+
+// camposCSV sigue el orden de columnasCSV; los números en cero quedan vacíos.
+func camposCSV(r RegistroCatalogo) []string {
+	entero := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	clasificacion, duracion, estreno := "", "", ""
+	if r.Clasificacion != nil {
+		clasificacion = strconv.Itoa(*r.Clasificacion)
+	}
+	if r.Duracion != 0 {
+		duracion = strconv.FormatFloat(float64(r.Duracion), 'f', -1, 32)
+	}
+	if !esFilaDeSerie(r.Tipo) {
+		estreno = strconv.FormatBool(r.Estreno)
+	}
+	return []string{
+		r.Tipo, r.ID, r.Titulo, r.Descripcion, r.Generos, clasificacion, estreno, r.Director, r.Trailer, duracion,
+		entero(r.Anio), r.Idioma, r.Tema, r.Narrador, entero(r.Temporadas), entero(r.Temporada), r.TituloTemporada,
+		entero(r.Episodio), r.Emision,
+	}
+}
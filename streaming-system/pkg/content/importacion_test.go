@@ -0,0 +1,377 @@
+package content
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// catalogoImportacion tiene un contenido de cada clase, con textos que el CSV
+// debe entrecomillar y una serie con temporadas no consecutivas (1, 2 y 4),
+// una sin título y otra vacía, y episodios con y sin fecha.
+func catalogoImportacion(t *testing.T) *GestorDeContenido {
+	t.Helper()
+	p := NuevaPelicula("1", "Ñandú, \"el corredor\"", "Una línea\ny otra", []string{"Drama", "Acción"}, "Dir", "https://example.com/t", 95.5, 2001, "es", 13)
+	MarcarEstreno(p, true)
+
+	s := NuevaSerie("2", "Serie", "d", "Comedia", 0, 7)
+	ep1, _ := NuevoEpisodio(1, "Piloto", "El primero", 30, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC))
+	ep2, _ := NuevoEpisodio(2, "Segundo", "", 31, time.Time{})
+	ep3, _ := NuevoEpisodio(1, "Vuelta", "", 40, time.Time{})
+	for _, err := range []error{
+		s.GuardarTemporada(1, "Primera"), s.GuardarEpisodio(1, ep1), s.GuardarEpisodio(1, ep2),
+		s.GuardarTemporada(2, ""), s.GuardarEpisodio(2, ep3), s.GuardarTemporada(4, "Vacía"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NuevoDocumental("3", "Mares", "", "Naturaleza", "Océanos", "Voz", 0, 0)
+	d.AgregarEpisodio("Parte 1", 25)
+	d.AgregarEpisodio("Parte 2", 26)
+	return NuevoGestorDeContenido([]Contenible{
+		p, s, d,
+		NuevoDocumental("4", "Cielo", "", "Ciencia", "Astronomía", "", 52, 18),
+	})
+}
+
+func exportar(t *testing.T, formato string, cs []Contenible) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := ExportarCatalogo(&b, formato, cs); err != nil {
+		t.Fatalf("ExportarCatalogo: %v", err)
+	}
+	return b.Bytes()
+}
+
+func planificarTexto(t *testing.T, in *Instantanea, formato, texto string) *ResultadoImportacion {
+	t.Helper()
+	filas, err := LeerCatalogo(strings.NewReader(texto), formato)
+	if err != nil {
+		t.Fatalf("LeerCatalogo: %v", err)
+	}
+	return PlanificarImportacion(in, filas)
+}
+
+func TestExportarImportarIdaYVuelta(t *testing.T) {
+	original := catalogoImportacion(t).ObtenerTodo()
+	for _, formato := range []string{FormatoCSV, FormatoJSON, FormatoNDJSON} {
+		t.Run(formato, func(t *testing.T) {
+			archivo := exportar(t, formato, original)
+
+			// En un catálogo vacío todo se crea.
+			res := planificarTexto(t, NuevoGestorDeContenido(nil).Instantanea(), formato, string(archivo))
+			if !res.Valida() {
+				t.Fatalf("errores: %+v", res.Errores)
+			}
+			if !slices.Equal(res.Creados, []string{"4", "3", "2", "1"}) || len(res.Actualizados) != 0 || res.Temporadas != 3 || res.Episodios != 5 {
+				t.Fatalf("creados %v, actualizados %v, temporadas %d, episodios %d", res.Creados, res.Actualizados, res.Temporadas, res.Episodios)
+			}
+			for _, c := range res.Contenidos {
+				if s, ok := c.(*Serie); ok {
+					comprobarTemporadas(t, s, map[int]string{1: "Primera", 2: "", 4: "Vacía"})
+				}
+			}
+			if got := exportar(t, formato, res.Contenidos); !bytes.Equal(got, archivo) {
+				t.Fatalf("la reexportación difiere:\n%s\nde:\n%s", got, archivo)
+			}
+
+			// Sobre el mismo catálogo todo se actualiza y nada cambia.
+			res = planificarTexto(t, NuevoGestorDeContenido(original).Instantanea(), formato, string(archivo))
+			if !res.Valida() || len(res.Creados) != 0 || !slices.Equal(res.Actualizados, []string{"4", "3", "2", "1"}) {
+				t.Fatalf("creados %v, actualizados %v, errores %+v", res.Creados, res.Actualizados, res.Errores)
+			}
+			if got := exportar(t, formato, res.Contenidos); !bytes.Equal(got, archivo) {
+				t.Fatalf("la reimportación cambió el catálogo:\n%s\nde:\n%s", got, archivo)
+			}
+		})
+	}
+}
+
+// comprobarTemporadas compara número y título de las temporadas de la serie.
+func comprobarTemporadas(t *testing.T, s *Serie, esperadas map[int]string) {
+	t.Helper()
+	temporadas := s.ObtenerTemporadas()
+	if len(temporadas) != len(esperadas) {
+		t.Fatalf("%d temporadas, se esperaban %d", len(temporadas), len(esperadas))
+	}
+	for _, tm := range temporadas {
+		if titulo, ok := esperadas[tm.GetNumero()]; !ok || titulo != tm.GetTitulo() {
+			t.Fatalf("temporada %d %q, se esperaban %v", tm.GetNumero(), tm.GetTitulo(), esperadas)
+		}
+	}
+}
+
+func TestExportarCatalogoVacio(t *testing.T) {
+	casos := []struct {
+		formato, esperado string
+	}{
+		{FormatoCSV, strings.Join(columnasCSV, ",") + "\n"},
+		{FormatoJSON, "[]\n"},
+		{FormatoNDJSON, ""},
+	}
+	for _, c := range casos {
+		if got := string(exportar(t, c.formato, nil)); got != c.esperado {
+			t.Errorf("%s: %q, se esperaba %q", c.formato, got, c.esperado)
+		}
+	}
+	if err := ExportarCatalogo(&bytes.Buffer{}, "xml", nil); !errors.Is(err, ErrFormatoDesconocido) {
+		t.Errorf("formato xml: error = %v", err)
+	}
+}
+
+func TestPlanificarImportacionErrores(t *testing.T) {
+	// Cada fila con problemas informa de todos ellos; las demás se planifican
+	// igualmente, pero la importación no es válida.
+	casos := []struct {
+		nombre  string
+		ndjson  string
+		errores []ErrorFila // mensajes esperados como prefijo
+		creados []string
+	}{
+		{"título vacío",
+			`{"tipo":"pelicula","id":"10","titulo":"","clasificacion":0}`,
+			[]ErrorFila{{1, "10", []string{ErrTituloInvalido.Error()}}}, nil},
+		{"clasificación inválida",
+			`{"tipo":"serie","id":"10","titulo":"S","clasificacion":12}`,
+			[]ErrorFila{{1, "10", []string{ErrClasificacionInvalida.Error()}}}, nil},
+		{"sin clasificación",
+			`{"tipo":"documental","id":"10","titulo":"D"}`,
+			[]ErrorFila{{1, "10", []string{ErrSinClasificacion.Error()}}}, nil},
+		{"varios errores en una fila",
+			`{"tipo":"pelicula","id":"-1","titulo":"","clasificacion":12,"anio":1700,"idioma":"ESP"}`,
+			[]ErrorFila{{1, "-1", []string{ErrIDInvalido.Error(), ErrTituloInvalido.Error(), ErrClasificacionInvalida.Error(), ErrAnioInvalido.Error(), ErrIdiomaInvalido.Error()}}}, nil},
+		{"tipo desconocido",
+			`{"tipo":"corto","id":"10","titulo":"C","clasificacion":0}`,
+			[]ErrorFila{{1, "10", []string{ErrTipoRegistro.Error()}}}, nil},
+		{"cambio de tipo",
+			`{"tipo":"serie","id":"1","titulo":"S","clasificacion":0}`,
+			[]ErrorFila{{1, "1", []string{ErrCambioDeTipo.Error()}}}, nil},
+		{"ID repetido",
+			`{"tipo":"pelicula","id":"10","titulo":"A","clasificacion":0}` + "\n" + `{"tipo":"pelicula","id":"10","titulo":"B","clasificacion":0}`,
+			[]ErrorFila{{2, "10", []string{ErrIDRepetido.Error()}}}, []string{"10"}},
+		{"episodio huérfano",
+			`{"tipo":"episodio","id":"99","titulo":"E","duracion":10}`,
+			[]ErrorFila{{1, "99", []string{ErrEpisodioSinContenido.Error()}}}, nil},
+		{"episodio de una película",
+			`{"tipo":"episodio","id":"1","titulo":"E","duracion":10}`,
+			[]ErrorFila{{1, "1", []string{ErrEpisodioSinContenido.Error()}}}, nil},
+		{"episodio de un contenido con errores",
+			`{"tipo":"episodio","id":"11","titulo":"E","duracion":10,"temporada":1,"episodio":1}` + "\n" + `{"tipo":"serie","id":"11","titulo":"S","clasificacion":12}`,
+			[]ErrorFila{
+				{1, "11", []string{ErrEpisodioDeContenidoConErrores.Error()}},
+				{2, "11", []string{ErrClasificacionInvalida.Error()}},
+			}, nil},
+		{"episodio de una fila ilegible",
+			`{"tipo":"serie","id":"11","titulo":"S","clasificacion":"siete"}` + "\n" + `{"tipo":"episodio","id":"11","titulo":"E","duracion":10,"temporada":1,"episodio":1}`,
+			[]ErrorFila{
+				{1, "11", []string{"JSON no válido"}},
+				{2, "11", []string{ErrEpisodioDeContenidoConErrores.Error()}},
+			}, nil},
+		{"fecha de emisión",
+			`{"tipo":"episodio","id":"2","titulo":"E","duracion":10,"temporada":1,"episodio":3,"emision":"04/03/2021"}`,
+			[]ErrorFila{{1, "2", []string{ErrFechaEmision.Error()}}}, nil},
+		{"temporada huérfana",
+			`{"tipo":"temporada","id":"99","temporada":1}`,
+			[]ErrorFila{{1, "99", []string{ErrTemporadaSinSerie.Error()}}}, nil},
+		{"temporada de un documental",
+			`{"tipo":"temporada","id":"3","temporada":1}`,
+			[]ErrorFila{{1, "3", []string{ErrTemporadaSinSerie.Error()}}}, nil},
+		{"número de temporada inválido",
+			`{"tipo":"temporada","id":"2","temporada":0,"titulo_temporada":"Cero"}`,
+			[]ErrorFila{{1, "2", []string{ErrDatosTemporada.Error()}}}, nil},
+		{"episodio sin duración",
+			`{"tipo":"episodio","id":"3","titulo":"E"}`,
+			[]ErrorFila{{1, "3", []string{ErrDatosEpisodio.Error()}}}, nil},
+		{"temporada inválida",
+			`{"tipo":"episodio","id":"2","titulo":"E","duracion":10,"temporada":0,"episodio":1}`,
+			[]ErrorFila{{1, "2", []string{ErrDatosTemporada.Error()}}}, nil},
+		{"campo desconocido y líneas en blanco",
+			"\n" + `{"tipo":"pelicula","id":"10","titulo":"A","clasificacion":0}` + "\n\n" + `{"tipo":"pelicula","id":"12","titulo":"B","clasificacion":0,"color":"rojo"}`,
+			[]ErrorFila{{4, "12", []string{"JSON no válido"}}}, []string{"10"}},
+		{"JSON cortado",
+			`{"tipo":"pelicula",`,
+			[]ErrorFila{{1, "", []string{"JSON no válido"}}}, nil},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			res := planificarTexto(t, catalogoImportacion(t).Instantanea(), FormatoNDJSON, c.ndjson)
+			if res.Valida() {
+				t.Fatal("una importación con errores no debe ser válida")
+			}
+			if !slices.Equal(res.Creados, c.creados) {
+				t.Fatalf("creados %v, se esperaba %v", res.Creados, c.creados)
+			}
+			if len(res.Errores) != len(c.errores) {
+				t.Fatalf("errores %+v, se esperaba %+v", res.Errores, c.errores)
+			}
+			for i, esperado := range c.errores {
+				got := res.Errores[i]
+				if got.Fila != esperado.Fila || got.ID != esperado.ID || len(got.Errores) != len(esperado.Errores) {
+					t.Fatalf("error %d = %+v, se esperaba %+v", i, got, esperado)
+				}
+				for j, m := range esperado.Errores {
+					if !strings.HasPrefix(got.Errores[j], m) {
+						t.Fatalf("error %d, mensaje %d = %q, se esperaba %q", i, j, got.Errores[j], m)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPlanificarImportacionEpisodios(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		ndjson   string
+		id       string
+		esperado string // NDJSON del contenido resultante
+	}{
+		{"reemplaza un episodio de serie y conserva el título de la temporada",
+			`{"tipo":"episodio","id":"2","titulo":"Nuevo piloto","duracion":33,"temporada":2,"episodio":1}`, "2",
+			`{"tipo":"serie","id":"2","titulo":"Serie","descripcion":"d","generos":"Comedia","clasificacion":7}
+{"tipo":"temporada","id":"2","titulo":"","temporada":1,"titulo_temporada":"Primera"}
+{"tipo":"episodio","id":"2","titulo":"Piloto","descripcion":"El primero","duracion":30,"temporada":1,"episodio":1,"emision":"2021-03-04"}
+{"tipo":"episodio","id":"2","titulo":"Segundo","duracion":31,"temporada":1,"episodio":2}
+{"tipo":"temporada","id":"2","titulo":"","temporada":2}
+{"tipo":"episodio","id":"2","titulo":"Nuevo piloto","duracion":33,"temporada":2,"episodio":1}
+{"tipo":"temporada","id":"2","titulo":"","temporada":4,"titulo_temporada":"Vacía"}
+`},
+		{"crea la temporada que falta entre dos existentes",
+			`{"tipo":"episodio","id":"2","titulo":"Final","duracion":50,"temporada":3,"titulo_temporada":"Tercera","episodio":1}`, "2",
+			`{"tipo":"serie","id":"2","titulo":"Serie","descripcion":"d","generos":"Comedia","clasificacion":7}
+{"tipo":"temporada","id":"2","titulo":"","temporada":1,"titulo_temporada":"Primera"}
+{"tipo":"episodio","id":"2","titulo":"Piloto","descripcion":"El primero","duracion":30,"temporada":1,"episodio":1,"emision":"2021-03-04"}
+{"tipo":"episodio","id":"2","titulo":"Segundo","duracion":31,"temporada":1,"episodio":2}
+{"tipo":"temporada","id":"2","titulo":"","temporada":2}
+{"tipo":"episodio","id":"2","titulo":"Vuelta","duracion":40,"temporada":2,"episodio":1}
+{"tipo":"temporada","id":"2","titulo":"","temporada":3,"titulo_temporada":"Tercera"}
+{"tipo":"episodio","id":"2","titulo":"Final","duracion":50,"temporada":3,"episodio":1}
+{"tipo":"temporada","id":"2","titulo":"","temporada":4,"titulo_temporada":"Vacía"}
+`},
+		{"las filas de temporada renombran y crean temporadas vacías",
+			`{"tipo":"temporada","id":"2","temporada":1,"titulo_temporada":" Renombrada "}` + "\n" + `{"tipo":"temporada","id":"2","temporada":7}`, "2",
+			`{"tipo":"serie","id":"2","titulo":"Serie","descripcion":"d","generos":"Comedia","clasificacion":7}
+{"tipo":"temporada","id":"2","titulo":"","temporada":1,"titulo_temporada":"Renombrada"}
+{"tipo":"episodio","id":"2","titulo":"Piloto","descripcion":"El primero","duracion":30,"temporada":1,"episodio":1,"emision":"2021-03-04"}
+{"tipo":"episodio","id":"2","titulo":"Segundo","duracion":31,"temporada":1,"episodio":2}
+{"tipo":"temporada","id":"2","titulo":"","temporada":2}
+{"tipo":"episodio","id":"2","titulo":"Vuelta","duracion":40,"temporada":2,"episodio":1}
+{"tipo":"temporada","id":"2","titulo":"","temporada":4,"titulo_temporada":"Vacía"}
+{"tipo":"temporada","id":"2","titulo":"","temporada":7}
+`},
+		{"los metadatos de la serie conservan sus temporadas y episodios",
+			`{"tipo":"serie","id":"2","titulo":"Serie renombrada","generos":"Drama","clasificacion":13,"estreno":true}`, "2",
+			`{"tipo":"serie","id":"2","titulo":"Serie renombrada","generos":"Drama","clasificacion":13,"estreno":true}
+{"tipo":"temporada","id":"2","titulo":"","temporada":1,"titulo_temporada":"Primera"}
+{"tipo":"episodio","id":"2","titulo":"Piloto","descripcion":"El primero","duracion":30,"temporada":1,"episodio":1,"emision":"2021-03-04"}
+{"tipo":"episodio","id":"2","titulo":"Segundo","duracion":31,"temporada":1,"episodio":2}
+{"tipo":"temporada","id":"2","titulo":"","temporada":2}
+{"tipo":"episodio","id":"2","titulo":"Vuelta","duracion":40,"temporada":2,"episodio":1}
+{"tipo":"temporada","id":"2","titulo":"","temporada":4,"titulo_temporada":"Vacía"}
+`},
+		{"recorta los textos de una película",
+			`{"tipo":"pelicula","id":" 1 ","titulo":"  Ñandú ","descripcion":" d ","generos":" Drama , Acción ","clasificacion":13,"director":" Dir ","idioma":" es "}`, "1",
+			`{"tipo":"pelicula","id":"1","titulo":"Ñandú","descripcion":"d","generos":"Drama, Acción","clasificacion":13,"director":"Dir","idioma":"es"}
+`},
+		{"los episodios del archivo reemplazan los del documental",
+			`{"tipo":"episodio","id":"3","titulo":"Única","duracion":60}`, "3",
+			`{"tipo":"documental","id":"3","titulo":"Mares","generos":"Naturaleza","clasificacion":0,"tema":"Océanos","narrador":"Voz"}
+{"tipo":"episodio","id":"3","titulo":"Única","duracion":60}
+`},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			g := catalogoImportacion(t)
+			antes := exportar(t, FormatoNDJSON, g.ObtenerTodo())
+			res := planificarTexto(t, g.Instantanea(), FormatoNDJSON, c.ndjson)
+			if !res.Valida() || len(res.Contenidos) != 1 || res.Contenidos[0].GetID() != c.id {
+				t.Fatalf("resultado %+v", res)
+			}
+			if got := string(exportar(t, FormatoNDJSON, res.Contenidos)); got != c.esperado {
+				t.Fatalf("resultado:\n%s\nse esperaba:\n%s", got, c.esperado)
+			}
+			if !bytes.Equal(exportar(t, FormatoNDJSON, g.ObtenerTodo()), antes) {
+				t.Fatal("la planificación modificó el catálogo")
+			}
+		})
+	}
+}
+
+func TestLeerCatalogoCSV(t *testing.T) {
+	casos := []struct {
+		nombre  string
+		csv     string
+		err     error      // error del archivo; nil si se lee
+		filas   []int      // número de línea de cada fila
+		errores [][]string // mensajes de cada fila (prefijos); nil si se lee bien
+	}{
+		{"sin columna id", "tipo,titulo\npelicula,A\n", ErrCabeceraCSV, nil, nil},
+		{"columna desconocida", "tipo,id,color\npelicula,1,rojo\n", ErrCabeceraCSV, nil, nil},
+		{"BOM, mayúsculas y columnas en otro orden", "\ufeffID, Tipo ,Titulo,Clasificacion\n10,pelicula,A,0\n", nil, []int{2}, [][]string{nil}},
+		{"campo de varias líneas y filas vacías", "tipo,id,titulo,descripcion,clasificacion\npelicula,10,A,\"uno\ndos\",0\n,,,,\npelicula,11,B,,0\n", nil, []int{2, 5}, [][]string{nil, nil}},
+		{"columnas de menos", "tipo,id,titulo,clasificacion\npelicula,10\n", nil, []int{2}, [][]string{{ErrSinClasificacion.Error()}}},
+		{"valores que no son números", "tipo,id,titulo,clasificacion,duracion,estreno,anio\npelicula,10,A,trece,x,quizá,2001\n", nil, []int{2}, [][]string{{
+			`clasificacion: "trece" no es un número entero`, `duracion: "x" no es un número`, `estreno: "quizá" debe ser true/false o 1/0`,
+		}}},
+		{"comillas sin cerrar", "tipo,id,titulo,clasificacion\npelicula,10,A,0\npelicula,11,\"B,0\n", nil, []int{2, 3}, [][]string{nil, {"parse error on line 3"}}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			filas, err := LeerCatalogo(strings.NewReader(c.csv), FormatoCSV)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("error = %v, se esperaba %v", err, c.err)
+			}
+			if err != nil {
+				return
+			}
+			res := PlanificarImportacion(NuevoGestorDeContenido(nil).Instantanea(), filas)
+			var numeros []int
+			for _, f := range filas {
+				numeros = append(numeros, f.Numero)
+			}
+			if !slices.Equal(numeros, c.filas) {
+				t.Fatalf("filas en las líneas %v, se esperaban %v", numeros, c.filas)
+			}
+			errores := make(map[int][]string)
+			for _, e := range res.Errores {
+				errores[e.Fila] = e.Errores
+			}
+			for i, esperados := range c.errores {
+				got := errores[c.filas[i]]
+				if len(got) != len(esperados) {
+					t.Fatalf("línea %d: errores %q, se esperaba %q", c.filas[i], got, esperados)
+				}
+				for j, m := range esperados {
+					if !strings.HasPrefix(got[j], m) {
+						t.Fatalf("línea %d: error %q, se esperaba %q", c.filas[i], got[j], m)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLeerCatalogoArchivoInvalido(t *testing.T) {
+	casos := []struct {
+		nombre, formato, texto string
+		err                    error
+	}{
+		{"formato desconocido", "xml", "", ErrFormatoDesconocido},
+		{"JSON que no es una lista", FormatoJSON, `{"tipo":"pelicula"}`, nil},
+		{"JSON vacío", FormatoJSON, "", nil},
+		{"CSV vacío", FormatoCSV, "", nil},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			_, err := LeerCatalogo(strings.NewReader(c.texto), c.formato)
+			if err == nil || c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("error = %v, se esperaba %v", err, c.err)
+			}
+		})
+	}
+}
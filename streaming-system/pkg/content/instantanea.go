@@ -0,0 +1,120 @@
+package content
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Instantanea es el catálogo en un momento dado, con índices secundarios que
+// usa Consultar para no recorrer todo el catálogo. Es inmutable: los métodos
+// devuelven copias de sus listas, siempre en el orden estable de Todos.
+type Instantanea struct {
+	version          uint64
+	porID            map[string]Contenible
+	orden            []Contenible
+	porGenero        map[string][]Contenible // clave: ClaveGenero; cada lista en el orden de orden
+	porTipo          map[string][]Contenible
+	porClasificacion map[int][]Contenible
+	porAnio          map[int][]Contenible // sin los contenidos de año desconocido
+	generos          []string             // nombre de cada género tal como apareció primero
+}
+
+func nuevaInstantanea(porID map[string]Contenible, version uint64) *Instantanea {
+	in := &Instantanea{
+		version: version, porID: porID, orden: make([]Contenible, 0, len(porID)),
+		porGenero: make(map[string][]Contenible), porTipo: make(map[string][]Contenible),
+		porClasificacion: make(map[int][]Contenible), porAnio: make(map[int][]Contenible),
+	}
+	for _, c := range porID {
+		in.orden = append(in.orden, c)
+	}
+	slices.SortFunc(in.orden, CompararContenidos)
+
+	// Al recorrer en orden, cada lista de los índices queda también ordenada.
+	for _, c := range in.orden {
+		for _, g := range GenerosDe(c) {
+			clave := ClaveGenero(g)
+			if _, ok := in.porGenero[clave]; !ok {
+				in.generos = append(in.generos, g)
+			}
+			in.porGenero[clave] = append(in.porGenero[clave], c)
+		}
+		in.porTipo[TipoDe(c)] = append(in.porTipo[TipoDe(c)], c)
+		in.porClasificacion[c.GetClasificacionEdad()] = append(in.porClasificacion[c.GetClasificacionEdad()], c)
+		if anio := AnioDe(c); anio != 0 {
+			in.porAnio[anio] = append(in.porAnio[anio], c)
+		}
+	}
+	slices.SortFunc(in.generos, func(a, b string) int { return strings.Compare(ClaveGenero(a), ClaveGenero(b)) })
+	return in
+}
+
+// CompararContenidos define el orden estable del catálogo: por título sin
+// distinguir mayúsculas y, a igual título, por ID.
+func CompararContenidos(a, b Contenible) int {
+	return cmp.Or(
+		strings.Compare(strings.ToLower(a.GetTitulo()), strings.ToLower(b.GetTitulo())),
+		strings.Compare(a.GetID(), b.GetID()),
+	)
+}
+
+// GenerosDe devuelve los géneros de cualquier contenido.
+func GenerosDe(c Contenible) []string {
+	if p, ok := c.(*Pelicula); ok {
+		return p.GetGeneros()
+	}
+	return SepararGeneros(c.GetGenero())
+}
+
+// ClaveGenero normaliza un género para compararlo ("Drama" y "drama" son el mismo).
+func ClaveGenero(g string) string {
+	return strings.ToLower(strings.TrimSpace(g))
+}
+
+// AnioDe devuelve el año de estreno: el de la película o el del primer
+// episodio emitido de una serie. 0 si no se conoce.
+func AnioDe(c Contenible) int {
+	switch v := c.(type) {
+	case *Pelicula:
+		return v.GetAnio()
+	case *Serie:
+		anio := 0
+		for _, ep := range v.ObtenerEpisodios() {
+			if e := ep.GetEmision(); !e.IsZero() && (anio == 0 || e.Year() < anio) {
+				anio = e.Year()
+			}
+		}
+		return anio
+	}
+	return 0
+}
+
+// Version crece con cada cambio del catálogo; sirve para detectar si otra
+// estructura derivada (como un índice de búsqueda) está al día.
+func (in *Instantanea) Version() uint64 { return in.version }
+
+// Len devuelve el número de contenidos.
+func (in *Instantanea) Len() int { return len(in.orden) }
+
+// PorID recupera un contenido por su ID.
+func (in *Instantanea) PorID(id string) (Contenible, error) {
+	if c, ok := in.porID[id]; ok {
+		return c, nil
+	}
+	return nil, ErrContenidoNoEncontrado
+}
+
+// Todos devuelve el catálogo completo ordenado con CompararContenidos.
+func (in *Instantanea) Todos() []Contenible { return slices.Clone(in.orden) }
+
+// Anios devuelve los años de estreno presentes, del más reciente al más antiguo.
+func (in *Instantanea) Anios() []int {
+	res := make([]int, 0, len(in.porAnio))
+	for a := range in.porAnio {
+		res = append(res, a)
+	}
+	slices.Sort(res)
+	slices.Reverse(res)
+	return res
+}
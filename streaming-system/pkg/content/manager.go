@@ -1,64 +1,114 @@
 package content
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 // NOTA: ErrContenidoNoEncontrado y la interfaz Contenible
 // se asumen definidos de forma única en pkg/content/content.go
 
-// GestorDeContenido maneja el catálogo de películas y series.
+// GestorDeContenido maneja el catálogo de películas, series y documentales.
+// Es seguro para uso concurrente: las lecturas trabajan sobre una Instantanea
+// inmutable sin bloquear, y cada escritura construye una nueva. Los
+// contenidos guardados no se modifican nunca; las actualizaciones trabajan
+// sobre una copia, así que quien ya los leyó sigue viendo una versión coherente.
 type GestorDeContenido struct {
-	catalogo map[string]Contenible
+	escritura sync.Mutex // serializa las escrituras
+	actual    atomic.Pointer[Instantanea]
 }
 
 // NuevoGestorDeContenido crea e inicializa el catálogo.
 func NuevoGestorDeContenido(contenidos []Contenible) *GestorDeContenido {
-	g := &GestorDeContenido{
-		catalogo: make(map[string]Contenible),
-	}
-	// Insertar contenidos cargados si existen
+	g := &GestorDeContenido{}
+	porID := make(map[string]Contenible, len(contenidos))
 	for _, c := range contenidos {
-		g.InsertarContenido(c)
+		porID[c.GetID()] = c
 	}
+	g.actual.Store(nuevaInstantanea(porID, 1))
 	return g
 }
 
-// InsertarContenido añade un elemento al catálogo.
+// Instantanea devuelve el estado actual del catálogo, que no cambia aunque
+// después se modifique el gestor.
+func (g *GestorDeContenido) Instantanea() *Instantanea {
+	return g.actual.Load()
+}
+
+// modificar aplica f a una copia del catálogo y publica el resultado como
+// nueva instantánea. Si f devuelve un error no se publica nada.
+func (g *GestorDeContenido) modificar(f func(porID map[string]Contenible) error) error {
+	g.escritura.Lock()
+	defer g.escritura.Unlock()
+	anterior := g.actual.Load()
+	porID := make(map[string]Contenible, len(anterior.porID)+1)
+	for id, c := range anterior.porID {
+		porID[id] = c
+	}
+	if err := f(porID); err != nil {
+		return err
+	}
+	g.actual.Store(nuevaInstantanea(porID, anterior.version+1))
+	return nil
+}
+
+// InsertarContenido añade un elemento al catálogo, o reemplaza el que tenga
+// su ID. El llamador no debe modificar c después.
 func (g *GestorDeContenido) InsertarContenido(c Contenible) {
-	g.catalogo[c.GetID()] = c
+	g.modificar(func(porID map[string]Contenible) error {
+		porID[c.GetID()] = c
+		return nil
+	})
 }
 
 // ObtenerPorID recupera un contenido por su ID.
 func (g *GestorDeContenido) ObtenerPorID(id string) (Contenible, error) {
-	// Usamos la variable de error definida en content.go
-	if c, ok := g.catalogo[id]; ok {
-		return c, nil
-	}
-	return nil, ErrContenidoNoEncontrado
+	return g.Instantanea().PorID(id)
 }
 
-// ObtenerTodo devuelve todo el catálogo.
-func (g *GestorDeContenido) ObtenerTodo() map[string]Contenible {
-	return g.catalogo
+// ObtenerTodo devuelve todo el catálogo en orden estable (ver Instantanea.Todos).
+func (g *GestorDeContenido) ObtenerTodo() []Contenible {
+	return g.Instantanea().Todos()
 }
 
 // BorrarContenido elimina un contenido por su ID.
 func (g *GestorDeContenido) BorrarContenido(id string) {
-	delete(g.catalogo, id)
+	g.modificar(func(porID map[string]Contenible) error {
+		delete(porID, id)
+		return nil
+	})
 }
 
-// ActualizarContenidoMetadata simula la actualización de metadata.
-func (g *GestorDeContenido) ActualizarContenidoMetadata(id string, nuevoTitulo string) error {
-	c, ok := g.catalogo[id]
-	if !ok {
-		return ErrContenidoNoEncontrado
-	}
-
-	switch v := c.(type) {
-	case *Pelicula:
-		v.titulo = nuevoTitulo
-	case *Serie:
-		v.titulo = nuevoTitulo
-	default:
-		// No se puede actualizar el tipo de contenido desconocido
-	}
+// Actualizar aplica f a una copia del contenido id y la publica solo si f no
+// devuelve error. f puede guardar la copia en la base de datos: mientras se
+// ejecuta no se intercala ninguna otra escritura del catálogo, así que la
+// memoria nunca refleja un cambio que no se pudo persistir.
+func (g *GestorDeContenido) Actualizar(id string, f func(copia Contenible) error) error {
+	return g.modificar(func(porID map[string]Contenible) error {
+		c, ok := porID[id]
+		if !ok {
+			return ErrContenidoNoEncontrado
+		}
+		copia := clonar(c)
+		if copia == nil {
+			return ErrTipoNoSoportado
+		}
+		if err := f(copia); err != nil {
+			return err
+		}
+		porID[id] = copia
+		return nil
+	})
+}
 
-	return nil
+// ActualizarSerie es Actualizar para una serie: f recibe la copia ya como
+// *Serie y puede usar sus métodos de temporadas y episodios.
+func (g *GestorDeContenido) ActualizarSerie(id string, f func(s *Serie) error) error {
+	return g.Actualizar(id, func(c Contenible) error {
+		s, ok := c.(*Serie)
+		if !ok {
+			return ErrTipoNoSoportado
+		}
+		return f(s)
+	})
 }
@@ -0,0 +1,137 @@
+package content
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// comprobarInstantanea verifica que la instantánea es coherente consigo misma.
+func comprobarInstantanea(t *testing.T, in *Instantanea) {
+	if len(in.orden) != len(in.porID) {
+		t.Errorf("versión %d: %d en orden y %d por ID", in.version, len(in.orden), len(in.porID))
+		return
+	}
+	if !slices.IsSortedFunc(in.orden, CompararContenidos) {
+		t.Errorf("versión %d: el orden no es estable", in.version)
+	}
+	for _, c := range in.orden {
+		if in.porID[c.GetID()] != c {
+			t.Errorf("versión %d: %s no coincide con su entrada por ID", in.version, c.GetID())
+		}
+		if !slices.Contains(in.porTipo[TipoDe(c)], c) || !slices.Contains(in.porClasificacion[c.GetClasificacionEdad()], c) {
+			t.Errorf("versión %d: %s falta en los índices", in.version, c.GetID())
+		}
+		// Los escritores cambian título y descripción juntos: verlos distintos
+		// sería ver una escritura a medias.
+		if c.GetTitulo() != c.GetDescripcion() {
+			t.Errorf("versión %d: %s a medio actualizar: %q / %q", in.version, c.GetID(), c.GetTitulo(), c.GetDescripcion())
+		}
+	}
+}
+
+// TestGestorConcurrente tiene sentido sobre todo con go test -race.
+func TestGestorConcurrente(t *testing.T) {
+	const fijos, escritores, vueltas = 20, 4, 100
+	var cs []Contenible
+	for i := range fijos {
+		id := strconv.Itoa(i + 1)
+		cs = append(cs, NuevaPelicula(id, "t"+id, "t"+id, []string{"Drama"}, "", "", 90, 2000, "es", 0))
+	}
+	g := NuevoGestorDeContenido(cs)
+
+	var escritura, lectura sync.WaitGroup
+	fin := make(chan struct{})
+	for e := range escritores {
+		escritura.Add(1)
+		go func() {
+			defer escritura.Done()
+			for v := range vueltas {
+				id := strconv.Itoa(1000 + e*vueltas + v)
+				g.InsertarContenido(NuevaSerie(id, "n"+id, "n"+id, "Comedia", 1, 7))
+				texto := "e" + strconv.Itoa(e) + "v" + strconv.Itoa(v)
+				if err := g.Actualizar(strconv.Itoa(v%fijos+1), func(c Contenible) error {
+					EditarComunes(c, texto, texto, 13)
+					return nil
+				}); err != nil {
+					t.Error(err)
+				}
+				if v%2 == 0 {
+					g.BorrarContenido(id)
+				}
+			}
+		}()
+	}
+	for range 4 {
+		lectura.Add(1)
+		go func() {
+			defer lectura.Done()
+			var ultima uint64
+			for {
+				select {
+				case <-fin:
+					return
+				default:
+				}
+				in := g.Instantanea()
+				if in.Version() < ultima {
+					t.Errorf("la versión retrocedió de %d a %d", ultima, in.Version())
+				}
+				ultima = in.Version()
+				comprobarInstantanea(t, in)
+				if _, err := in.Consultar(ConsultaCatalogo{Generos: []string{"drama"}, Tipos: []string{TipoPelicula}}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	escritura.Wait()
+	close(fin)
+	lectura.Wait()
+
+	final := g.Instantanea()
+	comprobarInstantanea(t, final)
+	if n := final.Len(); n != fijos+escritores*vueltas/2 {
+		t.Fatalf("%d contenidos al final, se esperaban %d", n, fijos+escritores*vueltas/2)
+	}
+	// Cada escritura publica exactamente una versión nueva.
+	if v := final.Version(); v != 1+escritores*(2*vueltas+vueltas/2) {
+		t.Fatalf("versión final %d, se esperaba %d", v, 1+escritores*(2*vueltas+vueltas/2))
+	}
+}
+
+func TestActualizarConError(t *testing.T) {
+	g := catalogoConsulta()
+	antes := g.Instantanea()
+	casos := []struct {
+		nombre     string
+		actualizar func() error
+		err        error
+	}{
+		{"f falla", func() error {
+			return g.Actualizar("1", func(c Contenible) error {
+				EditarComunes(c, "Otro", "", 0)
+				return ErrTituloInvalido
+			})
+		}, ErrTituloInvalido},
+		{"no existe", func() error { return g.Actualizar("99", func(Contenible) error { return nil }) }, ErrContenidoNoEncontrado},
+		{"cambio de tipo", func() error {
+			return g.Actualizar("1", func(c Contenible) error { return ReemplazarDatos(c, NuevaSerie("1", "S", "", "", 0, 0)) })
+		}, ErrCambioDeTipo},
+		{"no es una serie", func() error { return g.ActualizarSerie("1", func(*Serie) error { return nil }) }, ErrTipoNoSoportado},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if err := c.actualizar(); err != c.err {
+				t.Fatalf("error = %v, se esperaba %v", err, c.err)
+			}
+			if g.Instantanea() != antes {
+				t.Fatal("una actualización fallida publicó una instantánea")
+			}
+		})
+	}
+	if c, _ := g.ObtenerPorID("1"); c.GetTitulo() != "Amanecer" {
+		t.Fatalf("el contenido guardado cambió: %q", c.GetTitulo())
+	}
+}
@@ -0,0 +1,112 @@
+package content
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Errores de validación de los metadatos; Validar los devuelve unidos con errors.Join.
+var (
+	ErrIDInvalido            = errors.New("el ID debe ser un entero positivo")
+	ErrTituloInvalido        = errors.New("el título es obligatorio y admite hasta 50 caracteres")
+	ErrDescripcionLarga      = errors.New("la descripción admite hasta 200 caracteres")
+	ErrClasificacionInvalida = errors.New("la clasificación por edad no es válida")
+	ErrGenerosInvalidos      = errors.New("se admiten hasta 5 géneros de hasta 30 caracteres cada uno")
+	ErrDirectorLargo         = errors.New("el director admite hasta 100 caracteres")
+	ErrTrailerInvalido       = errors.New("el tráiler debe ser una URL http o https de hasta 255 caracteres")
+	ErrDuracionInvalida      = errors.New("la duración debe estar entre 0 y 1440 minutos")
+	ErrAnioInvalido          = errors.New("el año de estreno debe estar entre 1888 y el año que viene")
+	ErrIdiomaInvalido        = errors.New("el idioma original debe ser un código ISO 639-1 de dos letras (es, en, fr...)")
+	ErrDatosDocumental       = errors.New("el género admite hasta 50 caracteres, y el tema y el narrador hasta 100")
+)
+
+// ValidarComunes comprueba los campos que comparten todos los tipos de contenido.
+func ValidarComunes(id, titulo, descripcion string, edad int) error {
+	var errs []error
+	if n, err := strconv.ParseInt(id, 10, 64); err != nil || n <= 0 {
+		errs = append(errs, ErrIDInvalido)
+	}
+	if titulo == "" || utf8.RuneCountInString(titulo) > 50 {
+		errs = append(errs, ErrTituloInvalido)
+	}
+	if utf8.RuneCountInString(descripcion) > 200 {
+		errs = append(errs, ErrDescripcionLarga)
+	}
+	if !EsClasificacionValida(edad) {
+		errs = append(errs, ErrClasificacionInvalida)
+	}
+	return errors.Join(errs...)
+}
+
+// Validar comprueba todos los metadatos del contenido y devuelve cada
+// problema encontrado, o nil.
+func Validar(c Contenible) error {
+	errs := []error{ValidarComunes(c.GetID(), c.GetTitulo(), c.GetDescripcion(), c.GetClasificacionEdad())}
+	switch v := c.(type) {
+	case *Pelicula:
+		errs = append(errs, v.validar())
+	case *Documental:
+		if utf8.RuneCountInString(v.genero) > 50 || utf8.RuneCountInString(v.tema) > 100 || utf8.RuneCountInString(v.narrador) > 100 {
+			errs = append(errs, ErrDatosDocumental)
+		}
+		if v.duracion < 0 || v.duracion > 1440 {
+			errs = append(errs, ErrDuracionInvalida)
+		}
+	case *Serie:
+		if utf8.RuneCountInString(v.genero) > 50 {
+			errs = append(errs, ErrGenerosInvalidos)
+		}
+	default:
+		errs = append(errs, ErrTipoNoSoportado)
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pelicula) validar() error {
+	var errs []error
+	if len(p.generos) > 5 {
+		errs = append(errs, ErrGenerosInvalidos)
+	} else {
+		for _, g := range p.generos {
+			if utf8.RuneCountInString(g) > 30 {
+				errs = append(errs, ErrGenerosInvalidos)
+				break
+			}
+		}
+	}
+	if utf8.RuneCountInString(p.director) > 100 {
+		errs = append(errs, ErrDirectorLargo)
+	}
+	if p.trailerLink != "" {
+		u, err := url.Parse(p.trailerLink)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(p.trailerLink) > 255 {
+			errs = append(errs, ErrTrailerInvalido)
+		}
+	}
+	if p.duracion < 0 || p.duracion > 1440 {
+		errs = append(errs, ErrDuracionInvalida)
+	}
+	if p.anio != 0 && (p.anio < 1888 || p.anio > time.Now().Year()+1) {
+		errs = append(errs, ErrAnioInvalido)
+	}
+	if p.idioma != "" && !esCodigoIdioma(p.idioma) {
+		errs = append(errs, ErrIdiomaInvalido)
+	}
+	return errors.Join(errs...)
+}
+
+func esCodigoIdioma(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
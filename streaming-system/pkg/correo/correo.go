@@ -0,0 +1,137 @@
+package correo
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDestinatarioInvalido se devuelve cuando el mensaje no tiene destinatario.
+	ErrDestinatarioInvalido = errors.New("destinatario de correo inválido")
+	// ErrRemitenteInvalido indica un remitente que no es una dirección RFC 5322.
+	ErrRemitenteInvalido = errors.New("remitente de correo inválido")
+)
+
+// Mensaje es un correo de texto plano.
+type Mensaje struct {
+	Para   string
+	Asunto string
+	Cuerpo string
+}
+
+// Mailer abstrae el envío de correos (SMTP en producción, archivos en local).
+type Mailer interface {
+	Enviar(m Mensaje) error
+}
+
+// --- SMTP ---
+
+// MailerSMTP envía correos a través de un servidor SMTP con autenticación PLAIN.
+type MailerSMTP struct {
+	host, puerto, usuario, password, remitente string
+}
+
+// NuevoMailerSMTP crea un mailer SMTP.
+func NuevoMailerSMTP(host, puerto, usuario, password, remitente string) *MailerSMTP {
+	return &MailerSMTP{host: host, puerto: puerto, usuario: usuario, password: password, remitente: remitente}
+}
+
+func (s *MailerSMTP) Enviar(m Mensaje) error {
+	if err := validar(m); err != nil {
+		return err
+	}
+	// El remitente puede llevar nombre ("StreamGo <no-reply@...>"), pero el
+	// sobre SMTP (MAIL FROM) solo admite la dirección.
+	sobre, err := mail.ParseAddress(s.remitente)
+	if err != nil {
+		return ErrRemitenteInvalido
+	}
+	var a smtp.Auth
+	if s.usuario != "" {
+		a = smtp.PlainAuth("", s.usuario, s.password, s.host)
+	}
+	return smtp.SendMail(s.host+":"+s.puerto, a, sobre.Address, []string{m.Para}, componer(s.remitente, m))
+}
+
+// --- Bandeja de salida en disco ---
+
+// MailerArchivo escribe cada correo como un archivo .eml en un directorio.
+// Pensado para desarrollo local y pruebas.
+type MailerArchivo struct {
+	mu         sync.Mutex
+	directorio string
+	remitente  string
+}
+
+// NuevoMailerArchivo crea un mailer que escribe en el directorio indicado.
+func NuevoMailerArchivo(directorio, remitente string) *MailerArchivo {
+	return &MailerArchivo{directorio: directorio, remitente: remitente}
+}
+
+func (f *MailerArchivo) Enviar(m Mensaje) error {
+	if err := validar(m); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.MkdirAll(f.directorio, 0o755); err != nil {
+		return err
+	}
+	nombre := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), sanear(m.Para))
+	return os.WriteFile(filepath.Join(f.directorio, nombre), componer(f.remitente, m), 0o600)
+}
+
+// Mensajes lee los correos de la bandeja en orden de envío.
+func (f *MailerArchivo) Mensajes() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	archivos, err := filepath.Glob(filepath.Join(f.directorio, "*.eml"))
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, a := range archivos {
+		b, err := os.ReadFile(a)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, string(b))
+	}
+	return res, nil
+}
+
+func validar(m Mensaje) error {
+	if m.Para == "" || strings.ContainsAny(m.Para, "\r\n") {
+		return ErrDestinatarioInvalido
+	}
+	return nil
+}
+
+// componer arma el mensaje. El asunto va codificado (RFC 2047) porque las
+// cabeceras solo admiten ASCII.
+func componer(remitente string, m Mensaje) []byte {
+	asunto := mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r", "", "\n", " ").Replace(m.Asunto))
+	return []byte("From: " + remitente + "\r\n" +
+		"To: " + m.Para + "\r\n" +
+		"Subject: " + asunto + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + m.Cuerpo + "\r\n")
+}
+
+func sanear(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -1,6 +1,7 @@
 package playback
 
 import (
+	"errors"
 	"fmt" // Se requiere para el fmt.Printf en GuardarProgreso
 	"streaming-system/pkg/content"
 	"time"
@@ -24,6 +25,19 @@ func NuevoVisualizacion(id string, c content.Contenible) *Visualizacion {
 	}
 }
 
+// ErrContenidoRestringido indica que la clasificación del contenido supera la
+// permitida para el perfil.
+var ErrContenidoRestringido = errors.New("este contenido no está disponible para el perfil")
+
+// IniciarReproduccion crea la visualización solo si el contenido es apto para
+// la clasificación máxima del perfil que reproduce.
+func IniciarReproduccion(id string, c content.Contenible, edadMaxima int) (*Visualizacion, error) {
+	if !content.ApropiadoPara(c, edadMaxima) {
+		return nil, ErrContenidoRestringido
+	}
+	return NuevoVisualizacion(id, c), nil
+}
+
 // GetContenido devuelve el contenido que se está visualizando.
 func (v *Visualizacion) GetContenido() content.Contenible {
 	return v.contenido
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"streaming-system/pkg/auth"
+	"strings"
+	"time"
+)
+
+// --- Auditoría ---
+
+func (s *MySQLStorage) AppendAuditEvent(e *auth.EventoAuditoria) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Bloquear la cabeza serializa las inserciones de todas las instancias.
+	var anterior string
+	if err := tx.QueryRow("SELECT ULTIMO_HASH FROM auditoria_cabeza WHERE ID = 1 FOR UPDATE").Scan(&anterior); err != nil {
+		return err
+	}
+	e.Encadenar(anterior)
+	_, err = tx.Exec("INSERT INTO auditoria (FECHA, ACTOR, ACCION, OBJETIVO, IP, DETALLE, HASH_ANTERIOR, HASH) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		e.GetFecha(), e.GetActor(), string(e.GetAccion()), e.GetObjetivo(), e.GetIP(), e.GetDetalle(), e.GetHashAnterior(), e.GetHash())
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE auditoria_cabeza SET ULTIMO_HASH = ? WHERE ID = 1", e.GetHash()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const columnasAuditoria = "EVENTO_ID, FECHA, ACTOR, ACCION, OBJETIVO, IP, DETALLE, HASH_ANTERIOR, HASH"
+
+func (s *MySQLStorage) LoadAuditEvents(f auth.FiltroAuditoria) ([]*auth.EventoAuditoria, error) {
+	var where []string
+	var args []any
+	if f.Actor != "" {
+		where, args = append(where, "ACTOR LIKE ?"), append(args, "%"+escaparLike(f.Actor)+"%")
+	}
+	if f.Accion != "" {
+		where, args = append(where, "ACCION = ?"), append(args, string(f.Accion))
+	}
+	if f.Objetivo != "" {
+		where, args = append(where, "OBJETIVO LIKE ?"), append(args, "%"+escaparLike(f.Objetivo)+"%")
+	}
+	if f.IP != "" {
+		where, args = append(where, "IP = ?"), append(args, f.IP)
+	}
+	if !f.Desde.IsZero() {
+		where, args = append(where, "FECHA >= ?"), append(args, f.Desde)
+	}
+	if !f.Hasta.IsZero() {
+		where, args = append(where, "FECHA < ?"), append(args, f.Hasta)
+	}
+	query := "SELECT " + columnasAuditoria + " FROM auditoria"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY EVENTO_ID DESC"
+	if f.Limite > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limite)
+	}
+	return s.cargarEventosAuditoria(query, args...)
+}
+
+func (s *MySQLStorage) LoadAuditChain() ([]*auth.EventoAuditoria, error) {
+	return s.cargarEventosAuditoria("SELECT " + columnasAuditoria + " FROM auditoria ORDER BY EVENTO_ID")
+}
+
+func (s *MySQLStorage) cargarEventosAuditoria(query string, args ...any) ([]*auth.EventoAuditoria, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*auth.EventoAuditoria
+	for rows.Next() {
+		var id int64
+		var fecha time.Time
+		var actor, accion, objetivo, ip, detalle, anterior, hash string
+		if err := rows.Scan(&id, &fecha, &actor, &accion, &objetivo, &ip, &detalle, &anterior, &hash); err != nil {
+			return nil, err
+		}
+		res = append(res, auth.RecreateEventoAuditoriaFromDB(id, fecha, actor, auth.AccionAuditoria(accion), objetivo, ip, detalle, anterior, hash))
+	}
+	return res, rows.Err()
+}
+
+// escaparLike evita que % y _ del usuario actúen como comodines.
+func escaparLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
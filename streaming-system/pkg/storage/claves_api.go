@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"streaming-system/pkg/auth"
+	"strings"
+	"time"
+)
+
+// --- Claves de API ---
+
+const columnasClaveAPI = `CLAVE_ID, NOMBRE, PREFIJO, CLAVE_HASH, COALESCE(USUARIO_ID, ''), COALESCE(CUENTA_SERVICIO, ''), ALCANCES,
+	CREADA_POR, FECHA_CREACION, FECHA_EXPIRACION, ULTIMO_USO, COALESCE(ULTIMA_IP, ''), FECHA_REVOCACION`
+
+func (s *MySQLStorage) SaveAPIKey(c *auth.ClaveAPI) error {
+	alcances := make([]string, len(c.GetAlcances()))
+	for i, a := range c.GetAlcances() {
+		alcances[i] = string(a)
+	}
+	_, err := s.DB.Exec(`INSERT INTO claves_api (NOMBRE, PREFIJO, CLAVE_HASH, USUARIO_ID, CUENTA_SERVICIO, ALCANCES, CREADA_POR, FECHA_CREACION, FECHA_EXPIRACION)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.GetNombre(), c.GetPrefijo(), c.GetHash(), nuloSiVacio(c.GetUsuarioID()), nuloSiVacio(c.GetCuentaServicio()),
+		strings.Join(alcances, ","), c.GetCreadaPor(), c.GetCreada(), nuloSiCero(c.GetExpira()))
+	return err
+}
+
+// LoadAPIKeyByHash devuelve la clave con ese hash, o nil si no existe.
+func (s *MySQLStorage) LoadAPIKeyByHash(hash string) (*auth.ClaveAPI, error) {
+	c, err := escanearClaveAPI(s.DB.QueryRow("SELECT "+columnasClaveAPI+" FROM claves_api WHERE CLAVE_HASH = ?", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return c, err
+}
+
+// LoadAPIKeys devuelve todas las claves, la más reciente primero.
+func (s *MySQLStorage) LoadAPIKeys() ([]*auth.ClaveAPI, error) {
+	rows, err := s.DB.Query("SELECT " + columnasClaveAPI + " FROM claves_api ORDER BY CLAVE_ID DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*auth.ClaveAPI
+	for rows.Next() {
+		c, err := escanearClaveAPI(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// RevokeAPIKey revoca la clave; devuelve auth.ErrClaveAPIInvalida si no
+// existe o ya estaba revocada.
+func (s *MySQLStorage) RevokeAPIKey(id int64, fecha time.Time) error {
+	res, err := s.DB.Exec("UPDATE claves_api SET FECHA_REVOCACION = ? WHERE CLAVE_ID = ? AND FECHA_REVOCACION IS NULL", fecha, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return auth.ErrClaveAPIInvalida
+	}
+	return nil
+}
+
+// TouchAPIKey registra la hora y la IP del último uso.
+func (s *MySQLStorage) TouchAPIKey(id int64, fecha time.Time, ip string) error {
+	_, err := s.DB.Exec("UPDATE claves_api SET ULTIMO_USO = ?, ULTIMA_IP = ? WHERE CLAVE_ID = ?", fecha, truncar(ip, 45), id)
+	return err
+}
+
+func escanearClaveAPI(fila interface{ Scan(...any) error }) (*auth.ClaveAPI, error) {
+	var (
+		id                                                               int64
+		nombre, prefijo, hash, uid, servicio, alcances, creadaPor, ultIP string
+		creada                                                           time.Time
+		expira, ultimoUso, revocada                                      sql.NullTime
+	)
+	if err := fila.Scan(&id, &nombre, &prefijo, &hash, &uid, &servicio, &alcances, &creadaPor, &creada, &expira, &ultimoUso, &ultIP, &revocada); err != nil {
+		return nil, err
+	}
+	var lista []auth.AlcanceAPI
+	for _, a := range strings.Split(alcances, ",") {
+		if a != "" {
+			lista = append(lista, auth.AlcanceAPI(a))
+		}
+	}
+	return auth.RecreateClaveAPIFromDB(id, nombre, prefijo, hash, uid, servicio, lista, creadaPor, creada,
+		expira.Time, ultimoUso.Time, ultIP, revocada.Time), nil
+}
+
+// nuloSiVacio guarda NULL en lugar de una cadena vacía.
+func nuloSiVacio(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+// nuloSiCero guarda NULL en lugar de la fecha cero.
+func nuloSiCero(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
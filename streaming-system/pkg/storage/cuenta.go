@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// --- Exportación y baja de cuentas ---
+
+// idAnonimo es el usuario, método de pago y perfil centinela (migración 013)
+// al que se reasignan las filas que deben sobrevivir a una cuenta eliminada.
+const idAnonimo = -1
+
+// ExportUserData devuelve los datos del usuario agrupados por archivo de la
+// exportación. Los hashes de contraseña y de PIN no se incluyen.
+func (s *MySQLStorage) ExportUserData(uid string) (map[string][]map[string]any, error) {
+	consultas := []struct {
+		archivo, query string
+		args           []any
+	}{
+		{"usuario", "SELECT USUARIO_ID, NOMBRE_USUARIO, EMAIL, EMAIL_VERIFICADO, FECHA_REGISTRO, FECHA_BAJA_PROGRAMADA FROM usuarios WHERE USUARIO_ID = ?", []any{uid}},
+		{"roles", "SELECT ROL FROM usuario_roles WHERE USUARIO_ID = ?", []any{uid}},
+		{"perfiles", `SELECT PERFIL_ID, NOMBRE_PERFIL, AVATAR, CLASIFICACION_EDAD_MAXIMA, IDIOMA_INTERFAZ, IDIOMA_SUBTITULOS, AUTOPLAY, PIN_HASH IS NOT NULL AS TIENE_PIN
+			FROM perfiles WHERE USUARIOS_USUARIO_ID = ? ORDER BY PERFIL_ID`, []any{uid}},
+		{"suscripciones", "SELECT * FROM planes_suscripcion WHERE USUARIOS_USUARIO_ID = ? ORDER BY FECHA_INICIO", []any{uid}},
+		{"pagos", "SELECT * FROM transacciones WHERE USUARIOS_USUARIO_ID = ? OR USUARIO_ID = ? ORDER BY FECHA_TRANSACCION", []any{uid, uid}},
+		{"metodos_pago", "SELECT * FROM metodos_pago WHERE USUARIOS_USUARIO_ID = ?", []any{uid}},
+		{"historial", `SELECT h.* FROM historial_visualizaciones h JOIN perfiles p ON p.PERFIL_ID = h.PERFILES_PERFIL_ID
+			WHERE p.USUARIOS_USUARIO_ID = ? ORDER BY h.ULTIMA_VISUALIZACION`, []any{uid}},
+		{"sesiones", "SELECT FECHA_CREACION, FECHA_EXPIRACION, ULTIMO_ACCESO, USER_AGENT, IP FROM sesiones WHERE USUARIO_ID = ?", []any{uid}},
+		{"identidades_externas", "SELECT ISSUER, SUBJECT, EMAIL, FECHA_VINCULACION FROM identidades_externas WHERE USUARIO_ID = ?", []any{uid}},
+		{"claves_api", "SELECT NOMBRE, PREFIJO, ALCANCES, FECHA_CREACION, FECHA_EXPIRACION, ULTIMO_USO, ULTIMA_IP, FECHA_REVOCACION FROM claves_api WHERE USUARIO_ID = ?", []any{uid}},
+	}
+	res := make(map[string][]map[string]any, len(consultas))
+	for _, c := range consultas {
+		filas, err := s.exportarFilas(c.query, c.args...)
+		if err != nil {
+			return nil, err
+		}
+		res[c.archivo] = filas
+	}
+	return res, nil
+}
+
+// exportarFilas lee cualquier consulta como una lista de objetos columna -> valor.
+func (s *MySQLStorage) exportarFilas(query string, args ...any) ([]map[string]any, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columnas, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	res := []map[string]any{}
+	for rows.Next() {
+		valores := make([]any, len(columnas))
+		punteros := make([]any, len(columnas))
+		for i := range valores {
+			punteros[i] = &valores[i]
+		}
+		if err := rows.Scan(punteros...); err != nil {
+			return nil, err
+		}
+		fila := make(map[string]any, len(columnas))
+		for i, col := range columnas {
+			// El driver devuelve DECIMAL y VARCHAR como []byte.
+			if b, ok := valores[i].([]byte); ok {
+				valores[i] = string(b)
+			}
+			fila[col] = valores[i]
+		}
+		res = append(res, fila)
+	}
+	return res, rows.Err()
+}
+
+// ScheduleAccountDeletion programa la baja definitiva de la cuenta.
+func (s *MySQLStorage) ScheduleAccountDeletion(uid string, fecha time.Time) error {
+	_, err := s.DB.Exec("UPDATE usuarios SET FECHA_BAJA_PROGRAMADA = ? WHERE USUARIO_ID = ?", fecha, uid)
+	return err
+}
+
+// CancelAccountDeletion anula una baja pendiente.
+func (s *MySQLStorage) CancelAccountDeletion(uid string) error {
+	_, err := s.DB.Exec("UPDATE usuarios SET FECHA_BAJA_PROGRAMADA = NULL WHERE USUARIO_ID = ?", uid)
+	return err
+}
+
+// LoadScheduledDeletion devuelve la fecha de baja programada, o cero si no hay ninguna.
+func (s *MySQLStorage) LoadScheduledDeletion(uid string) (time.Time, error) {
+	var fecha sql.NullTime
+	err := s.DB.QueryRow("SELECT FECHA_BAJA_PROGRAMADA FROM usuarios WHERE USUARIO_ID = ?", uid).Scan(&fecha)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return fecha.Time, err
+}
+
+// LoadDueAccountDeletions devuelve los usuarios cuyo periodo de gracia terminó.
+func (s *MySQLStorage) LoadDueAccountDeletions(ahora time.Time) ([]string, error) {
+	rows, err := s.DB.Query("SELECT USUARIO_ID FROM usuarios WHERE FECHA_BAJA_PROGRAMADA <= ?", ahora)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		res = append(res, uid)
+	}
+	return res, rows.Err()
+}
+
+// DeleteAccount elimina la cuenta definitivamente. Las transacciones y
+// suscripciones se conservan anonimizadas (reasignadas al usuario centinela);
+// perfiles, historial y métodos de pago se borran, y el resto de tablas del
+// usuario caen por ON DELETE CASCADE.
+func (s *MySQLStorage) DeleteAccount(uid string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	pasos := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE transacciones SET USUARIO_ID = NULL, METODO_PAGO_ID = NULL, USUARIOS_USUARIO_ID = ?, METODOS_PAGO_METODO_PAGO_ID = ?
+			WHERE USUARIOS_USUARIO_ID = ? OR USUARIO_ID = ?`, []any{idAnonimo, idAnonimo, uid, uid}},
+		{`UPDATE planes_suscripcion SET USUARIO_ID = NULL, METODO_PAGO_ID = NULL, USUARIOS_USUARIO_ID = ?, METODOS_PAGO_METODO_PAGO_ID = ?,
+			ESTADO_SUSCRIPCION = IF(ESTADO_SUSCRIPCION = 'ACTIVO', 'CANCELADO', ESTADO_SUSCRIPCION)
+			WHERE USUARIOS_USUARIO_ID = ?`, []any{idAnonimo, idAnonimo, uid}},
+		{`DELETE h FROM historial_visualizaciones h JOIN perfiles p ON p.PERFIL_ID = h.PERFILES_PERFIL_ID OR p.PERFIL_ID = h.PERFIL_ID
+			WHERE p.USUARIOS_USUARIO_ID = ?`, []any{uid}},
+		// El contenido no desaparece con la cuenta; solo deja de apuntar a sus perfiles (migración 020).
+		{`UPDATE contenidos c JOIN perfiles p ON p.PERFIL_ID = c.PERFILES_PERFIL_ID SET c.PERFILES_PERFIL_ID = NULL
+			WHERE p.USUARIOS_USUARIO_ID = ?`, []any{uid}},
+		{"DELETE FROM perfiles WHERE USUARIOS_USUARIO_ID = ?", []any{uid}},
+		{"DELETE FROM metodos_pago WHERE USUARIOS_USUARIO_ID = ?", []any{uid}},
+		{"DELETE FROM usuarios WHERE USUARIO_ID = ?", []any{uid}},
+	}
+	for _, p := range pasos {
+		if _, err := tx.Exec(p.query, p.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
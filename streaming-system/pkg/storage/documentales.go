@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"database/sql"
+	"streaming-system/pkg/content"
+)
+
+// guardarDocumental escribe tema, narrador y episodios del documental,
+// reemplazando los episodios que tuviera.
+func guardarDocumental(tx *sql.Tx, d *content.Documental) error {
+	var duracion any
+	if !d.EsEpisodico() {
+		duracion = d.GetDuracionTotal()
+	}
+	_, err := tx.Exec(`INSERT INTO documentales (CONTENIDO_ID, GENERO, TEMA, NARRADOR, DURACION_MINUTOS) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE GENERO = VALUES(GENERO), TEMA = VALUES(TEMA), NARRADOR = VALUES(NARRADOR), DURACION_MINUTOS = VALUES(DURACION_MINUTOS)`,
+		d.GetID(), nuloSiVacio(d.GetGenero()), nuloSiVacio(d.GetTema()), nuloSiVacio(d.GetNarrador()), duracion)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM documental_episodios WHERE CONTENIDO_ID = ?", d.GetID()); err != nil {
+		return err
+	}
+	for i, ep := range d.ObtenerEpisodios() {
+		if _, err := tx.Exec("INSERT INTO documental_episodios (CONTENIDO_ID, NUMERO, TITULO, DURACION_MINUTOS) VALUES (?, ?, ?, ?)",
+			d.GetID(), i+1, ep.GetTitulo(), ep.GetDuracion()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cargarEpisodiosDocumentales añade a cada documental sus episodios, en orden.
+func (s *MySQLStorage) cargarEpisodiosDocumentales(docs map[string]*content.Documental) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	rows, err := s.DB.Query("SELECT CONTENIDO_ID, TITULO, DURACION_MINUTOS FROM documental_episodios ORDER BY CONTENIDO_ID, NUMERO")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, titulo string
+		var duracion float32
+		if err := rows.Scan(&id, &titulo, &duracion); err != nil {
+			return err
+		}
+		if d, ok := docs[id]; ok {
+			d.AgregarEpisodio(titulo, duracion)
+		}
+	}
+	return rows.Err()
+}
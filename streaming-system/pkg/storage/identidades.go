@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"errors"
+	"streaming-system/pkg/auth"
+	"time"
+)
+
+// --- Identidades externas (OIDC) ---
+
+// LoadUserByExternalIdentity devuelve el usuario vinculado a la identidad, o
+// nil si la identidad aún no se vinculó.
+func (s *MySQLStorage) LoadUserByExternalIdentity(issuer, subject string) (*auth.Usuario, error) {
+	u, err := s.cargarUsuario(`SELECT u.USUARIO_ID, u.NOMBRE_USUARIO, u.EMAIL, u.PASSWORD_HASH, u.EMAIL_VERIFICADO
+		FROM identidades_externas i JOIN usuarios u ON u.USUARIO_ID = i.USUARIO_ID
+		WHERE i.ISSUER = ? AND i.SUBJECT = ?`, issuer, subject)
+	if errors.Is(err, auth.ErrUsuarioNoEncontrado) {
+		return nil, nil
+	}
+	return u, err
+}
+
+// HasExternalIdentity indica si el usuario tiene alguna identidad externa vinculada.
+func (s *MySQLStorage) HasExternalIdentity(uid string) (bool, error) {
+	var n int
+	err := s.DB.QueryRow("SELECT COUNT(*) FROM identidades_externas WHERE USUARIO_ID = ?", uid).Scan(&n)
+	return n > 0, err
+}
+
+// LinkExternalIdentity vincula la identidad al usuario. Si ya estaba vinculada
+// no hace nada.
+func (s *MySQLStorage) LinkExternalIdentity(uid string, id *auth.IdentidadOIDC) error {
+	_, err := s.DB.Exec(`INSERT IGNORE INTO identidades_externas (ISSUER, SUBJECT, USUARIO_ID, EMAIL, FECHA_VINCULACION) VALUES (?, ?, ?, ?, ?)`,
+		id.Issuer, id.Subject, uid, id.Email, time.Now())
+	return err
+}
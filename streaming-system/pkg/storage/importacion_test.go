@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"streaming-system/pkg/content"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// dsnPruebas apunta a una base de datos vacía que las pruebas de este paquete
+// sobrescriben con el esquema publicado (BDD_Streaming.sql y las migraciones).
+// Sin ella, las pruebas que necesitan MySQL se omiten.
+const dsnPruebas = "STREAMGO_TEST_DSN"
+
+// baseDePrueba carga el esquema publicado y devuelve un almacén en modo
+// estricto, como el de producción.
+func baseDePrueba(t *testing.T) *MySQLStorage {
+	t.Helper()
+	dsn := os.Getenv(dsnPruebas)
+	if dsn == "" {
+		t.Skipf("define %s para probar contra MySQL", dsnPruebas)
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carga := cfg.Clone()
+	carga.MultiStatements = true
+	db, err := sql.Open("mysql", carga.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Una sola conexión: las variables de sesión del volcado deben valer para
+	// todos los archivos.
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	migraciones, _ := filepath.Glob("../../../Base de datos/migraciones/*.sql")
+	slices.Sort(migraciones)
+	archivos := append([]string{"../../../Base de datos/BDD_Streaming.sql"}, migraciones...)
+	if _, err := conn.ExecContext(context.Background(), "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range archivos {
+		sqlArchivo, err := os.ReadFile(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.ExecContext(context.Background(), string(sqlArchivo)); err != nil {
+			t.Fatalf("%s: %v", filepath.Base(a), err)
+		}
+	}
+
+	estricto := cfg.Clone()
+	estricto.ParseTime = true
+	estricto.Params = map[string]string{"sql_mode": "'TRADITIONAL'"}
+	s, err := sql.Open("mysql", estricto.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return &MySQLStorage{DB: s}
+}
+
+// catalogoImportacion crea tres contenidos (uno de cada tipo, con episodios)
+// y actualiza la película 201 del volcado.
+const catalogoImportacion = `{"tipo":"pelicula","id":"901","titulo":"Película importada","descripcion":"d","generos":"Drama, Acción","clasificacion":13,"estreno":true,"director":"Dir","duracion":100,"anio":2020,"idioma":"es"}
+{"tipo":"serie","id":"902","titulo":"Serie importada","descripcion":"d","generos":"Comedia","clasificacion":7}
+{"tipo":"episodio","id":"902","titulo":"Piloto","duracion":30,"temporada":1,"titulo_temporada":"Primera","episodio":1,"emision":"2021-03-04"}
+{"tipo":"documental","id":"903","titulo":"Documental importado","descripcion":"d","generos":"Naturaleza","clasificacion":0,"tema":"Mar","narrador":"Voz"}
+{"tipo":"episodio","id":"903","titulo":"Parte 1","duracion":25}
+{"tipo":"pelicula","id":"201","titulo":"El Viaje de Chihiro (remasterizada)","descripcion":"d","generos":"Animación","clasificacion":7,"duracion":125,"anio":2001,"idioma":"ja"}
+`
+
+func contarContenidos(t *testing.T, s *MySQLStorage) int {
+	t.Helper()
+	var n int
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM contenidos").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func planificar(t *testing.T, s *MySQLStorage) *content.ResultadoImportacion {
+	t.Helper()
+	cs, err := s.LoadAllContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filas, err := content.LeerCatalogo(strings.NewReader(catalogoImportacion), content.FormatoNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := content.PlanificarImportacion(content.NuevoGestorDeContenido(cs).Instantanea(), filas)
+	if !res.Valida() {
+		t.Fatalf("errores de importación: %v", res.Errores)
+	}
+	return res
+}
+
+func TestImportContentEsquemaPublicado(t *testing.T) {
+	s := baseDePrueba(t)
+	antes := contarContenidos(t, s)
+
+	// La simulación solo planifica: no escribe nada.
+	res := planificar(t, s)
+	if len(res.Creados) != 3 || len(res.Actualizados) != 1 {
+		t.Fatalf("creados %v, actualizados %v", res.Creados, res.Actualizados)
+	}
+	if n := contarContenidos(t, s); n != antes {
+		t.Fatalf("la simulación escribió en la base de datos: %d contenidos, antes %d", n, antes)
+	}
+
+	if err := s.ImportContent(res.Contenidos); err != nil {
+		t.Fatalf("ImportContent: %v", err)
+	}
+	if n := contarContenidos(t, s); n != antes+3 {
+		t.Fatalf("%d contenidos, se esperaban %d", n, antes+3)
+	}
+	cs, err := s.LoadAllContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	guardados := content.NuevoGestorDeContenido(cs)
+	for _, esperado := range res.Contenidos {
+		leido, err := guardados.ObtenerPorID(esperado.GetID())
+		if err != nil {
+			t.Fatalf("%s no se guardó: %v", esperado.GetID(), err)
+		}
+		var a, b strings.Builder
+		content.ExportarCatalogo(&a, content.FormatoNDJSON, []content.Contenible{esperado})
+		content.ExportarCatalogo(&b, content.FormatoNDJSON, []content.Contenible{leido})
+		if a.String() != b.String() {
+			t.Errorf("%s guardado distinto:\n%s\nleído:\n%s", esperado.GetID(), a.String(), b.String())
+		}
+	}
+
+	// Repetir la importación solo actualiza.
+	res = planificar(t, s)
+	if len(res.Creados) != 0 || len(res.Actualizados) != 4 {
+		t.Fatalf("segunda importación: creados %v, actualizados %v", res.Creados, res.Actualizados)
+	}
+	if err := s.ImportContent(res.Contenidos); err != nil {
+		t.Fatalf("segunda ImportContent: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"streaming-system/pkg/auth"
+	"time"
+)
+
+// --- INTENTOS DE LOGIN (implementa auth.AlmacenIntentos) ---
+
+func (s *MySQLStorage) LoadLoginAttempts(clave string) (*auth.EstadoIntentos, error) {
+	var fallos int
+	var ultimo time.Time
+	var hasta sql.NullTime
+	err := s.DB.QueryRow("SELECT FALLOS, ULTIMO_FALLO, BLOQUEADO_HASTA FROM login_intentos WHERE CLAVE = ?", clave).Scan(&fallos, &ultimo, &hasta)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return auth.RecreateEstadoIntentosFromDB(clave, fallos, ultimo, hasta.Time), nil
+}
+
+// RecordLoginFailure incrementa el contador en una sola sentencia para que
+// varias instancias puedan compartirlo.
+func (s *MySQLStorage) RecordLoginFailure(clave string, ahora time.Time, ventana time.Duration) (int, error) {
+	limite := ahora.Add(-ventana)
+	query := `INSERT INTO login_intentos (CLAVE, FALLOS, ULTIMO_FALLO) VALUES (?, 1, ?)
+		ON DUPLICATE KEY UPDATE FALLOS = IF(ULTIMO_FALLO < ?, 1, FALLOS + 1), ULTIMO_FALLO = VALUES(ULTIMO_FALLO)`
+	if _, err := s.DB.Exec(query, clave, ahora, limite); err != nil {
+		return 0, err
+	}
+	var fallos int
+	err := s.DB.QueryRow("SELECT FALLOS FROM login_intentos WHERE CLAVE = ?", clave).Scan(&fallos)
+	return fallos, err
+}
+
+func (s *MySQLStorage) LockLogin(clave string, hasta time.Time) error {
+	_, err := s.DB.Exec("UPDATE login_intentos SET BLOQUEADO_HASTA = ? WHERE CLAVE = ?", hasta, clave)
+	return err
+}
+
+func (s *MySQLStorage) ResetLoginAttempts(clave string) error {
+	_, err := s.DB.Exec("DELETE FROM login_intentos WHERE CLAVE = ?", clave)
+	return err
+}
+
+func (s *MySQLStorage) RecordLockout(e *auth.EventoBloqueo) error {
+	_, err := s.DB.Exec("INSERT INTO login_bloqueos (CLAVE, FALLOS, FECHA, BLOQUEADO_HASTA) VALUES (?, ?, ?, ?)",
+		e.GetClave(), e.GetFallos(), e.GetFecha(), e.GetHasta())
+	return err
+}
+
+func (s *MySQLStorage) LoadActiveLocks(ahora time.Time) ([]*auth.EstadoIntentos, error) {
+	rows, err := s.DB.Query("SELECT CLAVE, FALLOS, ULTIMO_FALLO, BLOQUEADO_HASTA FROM login_intentos WHERE BLOQUEADO_HASTA > ? ORDER BY CLAVE", ahora)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*auth.EstadoIntentos
+	for rows.Next() {
+		var clave string
+		var fallos int
+		var ultimo, hasta time.Time
+		if err := rows.Scan(&clave, &fallos, &ultimo, &hasta); err != nil {
+			return nil, err
+		}
+		res = append(res, auth.RecreateEstadoIntentosFromDB(clave, fallos, ultimo, hasta))
+	}
+	return res, rows.Err()
+}
+
+func (s *MySQLStorage) LoadLockoutEvents(limite int) ([]*auth.EventoBloqueo, error) {
+	rows, err := s.DB.Query("SELECT CLAVE, FALLOS, FECHA, BLOQUEADO_HASTA FROM login_bloqueos ORDER BY FECHA DESC LIMIT ?", limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*auth.EventoBloqueo
+	for rows.Next() {
+		var clave string
+		var fallos int
+		var fecha, hasta time.Time
+		if err := rows.Scan(&clave, &fallos, &fecha, &hasta); err != nil {
+			return nil, err
+		}
+		res = append(res, auth.RecreateEventoBloqueoFromDB(clave, fallos, fecha, hasta))
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"streaming-system/pkg/auth"
+)
+
+// --- MFA (TOTP y códigos de recuperación) ---
+
+// LoadMFA devuelve la configuración TOTP del usuario, o nil si nunca se inscribió.
+func (s *MySQLStorage) LoadMFA(uid string) (*auth.ConfigMFA, error) {
+	var secreto string
+	var activo bool
+	var ultimoPaso int64
+	err := s.DB.QueryRow("SELECT SECRETO, ACTIVO, ULTIMO_PASO FROM mfa_usuarios WHERE USUARIO_ID = ?", uid).Scan(&secreto, &activo, &ultimoPaso)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return auth.RecreateConfigMFAFromDB(uid, secreto, activo, ultimoPaso), nil
+}
+
+func (s *MySQLStorage) SaveMFA(c *auth.ConfigMFA) error {
+	query := `INSERT INTO mfa_usuarios (USUARIO_ID, SECRETO, ACTIVO, ULTIMO_PASO) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE SECRETO = VALUES(SECRETO), ACTIVO = VALUES(ACTIVO), ULTIMO_PASO = VALUES(ULTIMO_PASO)`
+	_, err := s.DB.Exec(query, c.GetUsuarioID(), c.GetSecreto(), c.EstaActivo(), c.GetUltimoPaso())
+	return err
+}
+
+// UpdateMFALastStep registra el paso TOTP usado. Falla si otro inicio de sesión
+// ya usó ese paso o uno posterior (protección contra reutilización del código).
+func (s *MySQLStorage) UpdateMFALastStep(uid string, paso int64) error {
+	res, err := s.DB.Exec("UPDATE mfa_usuarios SET ULTIMO_PASO = ? WHERE USUARIO_ID = ? AND ULTIMO_PASO < ?", paso, uid, paso)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return auth.ErrCodigoMFAInvalido
+	}
+	return nil
+}
+
+func (s *MySQLStorage) DeleteMFA(uid string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM codigos_recuperacion WHERE USUARIO_ID = ?", uid); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM mfa_usuarios WHERE USUARIO_ID = ?", uid); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveRecoveryCodes reemplaza los códigos de recuperación del usuario.
+func (s *MySQLStorage) SaveRecoveryCodes(uid string, hashes []string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM codigos_recuperacion WHERE USUARIO_ID = ?", uid); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		if _, err := tx.Exec("INSERT INTO codigos_recuperacion (USUARIO_ID, CODIGO_HASH, USADO) VALUES (?, ?, 0)", uid, h); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ConsumeRecoveryCode marca un código como usado; cada código vale una vez.
+func (s *MySQLStorage) ConsumeRecoveryCode(uid, hash string) error {
+	res, err := s.DB.Exec("UPDATE codigos_recuperacion SET USADO = 1 WHERE USUARIO_ID = ? AND CODIGO_HASH = ? AND USADO = 0", uid, hash)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return auth.ErrCodigoMFAInvalido
+	}
+	return nil
+}
+
+// --- POLÍTICA MFA POR ROL ---
+
+func (s *MySQLStorage) LoadMFARequiredRoles() ([]auth.Rol, error) {
+	rows, err := s.DB.Query("SELECT ROL FROM roles_mfa WHERE MFA_OBLIGATORIO = 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []auth.Rol
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			return nil, err
+		}
+		res = append(res, auth.Rol(r))
+	}
+	return res, rows.Err()
+}
+
+func (s *MySQLStorage) SetMFARequiredForRole(rol auth.Rol, obligatorio bool) error {
+	_, err := s.DB.Exec("INSERT INTO roles_mfa (ROL, MFA_OBLIGATORIO) VALUES (?, ?) ON DUPLICATE KEY UPDATE MFA_OBLIGATORIO = VALUES(MFA_OBLIGATORIO)", string(rol), obligatorio)
+	return err
+}
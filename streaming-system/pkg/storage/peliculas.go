@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"database/sql"
+	"streaming-system/pkg/content"
+	"strings"
+)
+
+// guardarPelicula escribe los metadatos propios de la película.
+func guardarPelicula(tx *sql.Tx, p *content.Pelicula) error {
+	_, err := tx.Exec(`INSERT INTO peliculas (CONTENIDO_ID, GENEROS, DIRECTOR, TRAILER_URL, DURACION_MINUTOS, ANIO_ESTRENO, IDIOMA_ORIGINAL) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE GENEROS = VALUES(GENEROS), DIRECTOR = VALUES(DIRECTOR), TRAILER_URL = VALUES(TRAILER_URL),
+		DURACION_MINUTOS = VALUES(DURACION_MINUTOS), ANIO_ESTRENO = VALUES(ANIO_ESTRENO), IDIOMA_ORIGINAL = VALUES(IDIOMA_ORIGINAL)`,
+		p.GetID(), nuloSiVacio(strings.Join(p.GetGeneros(), ",")), nuloSiVacio(p.GetDirector()), nuloSiVacio(p.GetTrailerLink()),
+		nuloSiNumeroCero(p.GetDuracionTotal()), nuloSiNumeroCero(p.GetAnio()), nuloSiVacio(p.GetIdioma()))
+	return err
+}
+
+// nuloSiNumeroCero guarda NULL para los datos numéricos desconocidos.
+func nuloSiNumeroCero[T int | float32](n T) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"streaming-system/pkg/auth"
+)
+
+// --- TOKENS DE RECUPERACIÓN DE CONTRASEÑA ---
+
+func (s *MySQLStorage) SavePasswordResetToken(t *auth.TokenRecuperacion) error {
+	_, err := s.DB.Exec("INSERT INTO tokens_recuperacion (TOKEN_HASH, USUARIO_ID, FECHA_EXPIRACION, USADO) VALUES (?, ?, ?, 0)",
+		t.GetHash(), t.GetUsuarioID(), t.GetExpira())
+	return err
+}
+
+// PeekPasswordResetToken comprueba, sin consumirlo, que el token sea válido.
+func (s *MySQLStorage) PeekPasswordResetToken(hash string) error {
+	var uid string
+	err := s.DB.QueryRow("SELECT USUARIO_ID FROM tokens_recuperacion WHERE TOKEN_HASH = ? AND USADO = 0 AND FECHA_EXPIRACION > UTC_TIMESTAMP()", hash).Scan(&uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.ErrTokenRecuperacionInvalido
+	}
+	return err
+}
+
+// ConsumePasswordResetToken marca el token como usado y devuelve su usuario.
+// Un token solo puede consumirse una vez.
+func (s *MySQLStorage) ConsumePasswordResetToken(hash string) (string, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	var uid string
+	err = tx.QueryRow("SELECT USUARIO_ID FROM tokens_recuperacion WHERE TOKEN_HASH = ? AND USADO = 0 AND FECHA_EXPIRACION > UTC_TIMESTAMP() FOR UPDATE", hash).Scan(&uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", auth.ErrTokenRecuperacionInvalido
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("UPDATE tokens_recuperacion SET USADO = 1 WHERE USUARIO_ID = ?", uid); err != nil {
+		return "", err
+	}
+	return uid, tx.Commit()
+}
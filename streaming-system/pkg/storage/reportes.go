@@ -0,0 +1,73 @@
+package storage
+
+// --- Reportes agregados (API con alcance "reporting") ---
+
+// ReportePlan resume las suscripciones activas de un plan.
+type ReportePlan struct {
+	Plan     string  `json:"plan"`
+	Activas  int     `json:"active"`
+	Ingresos float64 `json:"revenue"`
+}
+
+// ReporteContenido resume las visualizaciones de un contenido.
+type ReporteContenido struct {
+	ContenidoID     string `json:"content_id"`
+	Titulo          string `json:"title"`
+	Visualizaciones int    `json:"views"`
+	Completadas     int    `json:"completed"`
+	Segundos        int64  `json:"seconds_watched"`
+}
+
+// Reporte es el resumen general que consumen los trabajos de reporting.
+// No incluye datos personales: solo totales.
+type Reporte struct {
+	Usuarios   int                `json:"users"`
+	Contenidos int                `json:"contents"`
+	Planes     []ReportePlan      `json:"plans"`
+	MasVistos  []ReporteContenido `json:"top_contents"`
+}
+
+// LoadReport calcula el resumen; limite acota la lista de contenidos más vistos.
+func (s *MySQLStorage) LoadReport(limite int) (*Reporte, error) {
+	r := &Reporte{}
+	// El usuario centinela de las cuentas eliminadas no cuenta.
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM usuarios WHERE USUARIO_ID <> ?", idAnonimo).Scan(&r.Usuarios); err != nil {
+		return nil, err
+	}
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM contenidos").Scan(&r.Contenidos); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`SELECT TIPO_PLAN, COUNT(*), COALESCE(SUM(PRECIO_MENSUAL_ANUAL), 0) FROM planes_suscripcion
+		WHERE ESTADO_SUSCRIPCION = 'ACTIVO' GROUP BY TIPO_PLAN ORDER BY TIPO_PLAN`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p ReportePlan
+		if err := rows.Scan(&p.Plan, &p.Activas, &p.Ingresos); err != nil {
+			return nil, err
+		}
+		r.Planes = append(r.Planes, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.Query(`SELECT c.CONTENIDO_ID, COALESCE(c.TITULO, ''), COUNT(*), COALESCE(SUM(h.COMPLETADO), 0), COALESCE(SUM(h.TIEMPO_VISUALIZADO_SEGUNDOS), 0)
+		FROM historial_visualizaciones h JOIN contenidos c ON c.CONTENIDO_ID = h.CONTENIDOS_CONTENIDO_ID
+		GROUP BY c.CONTENIDO_ID, c.TITULO ORDER BY COUNT(*) DESC, c.CONTENIDO_ID LIMIT ?`, limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c ReporteContenido
+		if err := rows.Scan(&c.ContenidoID, &c.Titulo, &c.Visualizaciones, &c.Completadas, &c.Segundos); err != nil {
+			return nil, err
+		}
+		r.MasVistos = append(r.MasVistos, c)
+	}
+	return r, rows.Err()
+}
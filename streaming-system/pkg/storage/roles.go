@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"streaming-system/pkg/auth"
+)
+
+// --- ROLES ---
+
+func (s *MySQLStorage) LoadUserRoles(uid string) ([]auth.Rol, error) {
+	rows, err := s.DB.Query("SELECT ROL FROM usuario_roles WHERE USUARIO_ID = ?", uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []auth.Rol
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			return nil, err
+		}
+		if auth.EsRolValido(auth.Rol(r)) {
+			res = append(res, auth.Rol(r))
+		}
+	}
+	return res, rows.Err()
+}
+
+// SaveUserRoles reemplaza los roles del usuario por los indicados.
+func (s *MySQLStorage) SaveUserRoles(uid string, roles []auth.Rol) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM usuario_roles WHERE USUARIO_ID = ?", uid); err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if _, err := tx.Exec("INSERT INTO usuario_roles (USUARIO_ID, ROL) VALUES (?, ?)", uid, string(r)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// cargarRoles completa los roles de un usuario recién leído de la base.
+func (s *MySQLStorage) cargarRoles(u *auth.Usuario) (*auth.Usuario, error) {
+	roles, err := s.LoadUserRoles(u.GetID())
+	if err != nil {
+		return nil, err
+	}
+	u.AsignarRoles(roles)
+	return u, nil
+}
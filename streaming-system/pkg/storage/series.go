@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"database/sql"
+	"streaming-system/pkg/content"
+	"time"
+)
+
+// guardarSerie escribe el género, las temporadas y los episodios de la serie,
+// reemplazando los que tuviera.
+func guardarSerie(tx *sql.Tx, s *content.Serie) error {
+	if err := guardarGeneroSerie(tx, s); err != nil {
+		return err
+	}
+	// Borrar las temporadas borra en cascada sus episodios.
+	if _, err := tx.Exec("DELETE FROM temporadas WHERE CONTENIDO_ID = ?", s.GetID()); err != nil {
+		return err
+	}
+	for _, t := range s.ObtenerTemporadas() {
+		if err := guardarTemporada(tx, s.GetID(), t.GetNumero(), t.GetTitulo()); err != nil {
+			return err
+		}
+		for _, ep := range t.ObtenerEpisodios() {
+			if err := guardarEpisodio(tx, s.GetID(), t.GetNumero(), ep); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ejecutor es lo común a *sql.DB y *sql.Tx que necesitan las escrituras de series.
+type ejecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func guardarGeneroSerie(e ejecutor, s *content.Serie) error {
+	_, err := e.Exec("INSERT INTO series (CONTENIDO_ID, GENERO) VALUES (?, ?) ON DUPLICATE KEY UPDATE GENERO = VALUES(GENERO)",
+		s.GetID(), nuloSiVacio(s.GetGenero()))
+	return err
+}
+
+func guardarTemporada(e ejecutor, serieID string, numero int, titulo string) error {
+	_, err := e.Exec("INSERT INTO temporadas (CONTENIDO_ID, NUMERO, TITULO) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE TITULO = VALUES(TITULO)",
+		serieID, numero, nuloSiVacio(titulo))
+	return err
+}
+
+func guardarEpisodio(e ejecutor, serieID string, temporada int, ep *content.Episodio) error {
+	var emision any
+	if !ep.GetEmision().IsZero() {
+		emision = ep.GetEmision().Format(time.DateOnly)
+	}
+	_, err := e.Exec(`INSERT INTO episodios (CONTENIDO_ID, TEMPORADA, NUMERO, TITULO, DESCRIPCION, DURACION_MINUTOS, FECHA_EMISION) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE TITULO = VALUES(TITULO), DESCRIPCION = VALUES(DESCRIPCION), DURACION_MINUTOS = VALUES(DURACION_MINUTOS), FECHA_EMISION = VALUES(FECHA_EMISION)`,
+		serieID, temporada, ep.GetNumero(), ep.GetTitulo(), nuloSiVacio(ep.GetDescripcion()), ep.GetDuracion(), emision)
+	return err
+}
+
+// SaveSeason crea una temporada de la serie o cambia su título.
+func (s *MySQLStorage) SaveSeason(serieID string, numero int, titulo string) error {
+	return guardarTemporada(s.DB, serieID, numero, titulo)
+}
+
+// DeleteSeason borra una temporada con todos sus episodios.
+func (s *MySQLStorage) DeleteSeason(serieID string, numero int) error {
+	_, err := s.DB.Exec("DELETE FROM temporadas WHERE CONTENIDO_ID = ? AND NUMERO = ?", serieID, numero)
+	return err
+}
+
+// SaveEpisode crea o reemplaza un episodio de una temporada existente.
+func (s *MySQLStorage) SaveEpisode(serieID string, temporada int, ep *content.Episodio) error {
+	return guardarEpisodio(s.DB, serieID, temporada, ep)
+}
+
+// DeleteEpisode borra un episodio de una temporada.
+func (s *MySQLStorage) DeleteEpisode(serieID string, temporada, numero int) error {
+	_, err := s.DB.Exec("DELETE FROM episodios WHERE CONTENIDO_ID = ? AND TEMPORADA = ? AND NUMERO = ?", serieID, temporada, numero)
+	return err
+}
+
+// cargarTemporadasSeries añade a cada serie sus temporadas y episodios, en orden.
+func (s *MySQLStorage) cargarTemporadasSeries(series map[string]*content.Serie) error {
+	if len(series) == 0 {
+		return nil
+	}
+	rows, err := s.DB.Query("SELECT CONTENIDO_ID, NUMERO, COALESCE(TITULO, '') FROM temporadas ORDER BY CONTENIDO_ID, NUMERO")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, titulo string
+		var numero int
+		if err := rows.Scan(&id, &numero, &titulo); err != nil {
+			return err
+		}
+		if serie, ok := series[id]; ok {
+			serie.GuardarTemporada(numero, titulo)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.DB.Query(`SELECT CONTENIDO_ID, TEMPORADA, NUMERO, TITULO, COALESCE(DESCRIPCION, ''), DURACION_MINUTOS, FECHA_EMISION
+		FROM episodios ORDER BY CONTENIDO_ID, TEMPORADA, NUMERO`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, titulo, descripcion string
+		var temporada, numero int
+		var duracion float32
+		var emision sql.NullTime
+		if err := rows.Scan(&id, &temporada, &numero, &titulo, &descripcion, &duracion, &emision); err != nil {
+			return err
+		}
+		if serie, ok := series[id]; ok {
+			serie.GuardarEpisodio(temporada, content.RecreateEpisodioFromDB(numero, titulo, descripcion, duracion, emision.Time))
+		}
+	}
+	return rows.Err()
+}
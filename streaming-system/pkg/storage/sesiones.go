@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"streaming-system/pkg/auth"
+	"time"
+)
+
+// --- SESIONES (implementa auth.AlmacenSesiones) ---
+
+func (s *MySQLStorage) SaveSession(ses *auth.Sesion) error {
+	var perfil sql.NullInt64
+	if ses.GetPerfilID() != 0 {
+		perfil = sql.NullInt64{Int64: int64(ses.GetPerfilID()), Valid: true}
+	}
+	d := ses.GetDispositivo()
+	query := `INSERT INTO sesiones (TOKEN, USUARIO_ID, PERFIL_ID, MFA_PENDIENTE, FECHA_CREACION, FECHA_EXPIRACION, ULTIMO_ACCESO, USER_AGENT, IP, SUPLANTANDO_A) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE PERFIL_ID = VALUES(PERFIL_ID), MFA_PENDIENTE = VALUES(MFA_PENDIENTE), FECHA_EXPIRACION = VALUES(FECHA_EXPIRACION), SUPLANTANDO_A = VALUES(SUPLANTANDO_A)`
+	_, err := s.DB.Exec(query, ses.GetToken(), ses.GetUsuarioID(), perfil, ses.MFAPendiente(), ses.GetCreada(), ses.GetExpiracion(),
+		ses.GetUltimoAcceso(), truncar(d.UserAgent, 255), d.IP, nuloSiVacio(ses.GetSuplantadoID()))
+	return err
+}
+
+const columnasSesion = "TOKEN, USUARIO_ID, PERFIL_ID, MFA_PENDIENTE, FECHA_CREACION, FECHA_EXPIRACION, COALESCE(ULTIMO_ACCESO, FECHA_CREACION), COALESCE(USER_AGENT, ''), COALESCE(IP, ''), COALESCE(SUPLANTANDO_A, '')"
+
+func (s *MySQLStorage) LoadSession(token string) (*auth.Sesion, error) {
+	ses, err := escanearSesion(s.DB.QueryRow("SELECT "+columnasSesion+" FROM sesiones WHERE TOKEN = ?", token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrSesionInvalida
+	}
+	return ses, err
+}
+
+func (s *MySQLStorage) LoadSessionsByUserID(uid string) ([]*auth.Sesion, error) {
+	rows, err := s.DB.Query("SELECT "+columnasSesion+" FROM sesiones WHERE USUARIO_ID = ? AND FECHA_EXPIRACION > UTC_TIMESTAMP()", uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*auth.Sesion
+	for rows.Next() {
+		ses, err := escanearSesion(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ses)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySQLStorage) TouchSession(token string, ultimoAcceso time.Time, ip string) error {
+	_, err := s.DB.Exec("UPDATE sesiones SET ULTIMO_ACCESO = ?, IP = ? WHERE TOKEN = ?", ultimoAcceso, ip, token)
+	return err
+}
+
+func escanearSesion(fila interface{ Scan(...any) error }) (*auth.Sesion, error) {
+	var token, uid, suplantado string
+	var perfil sql.NullInt64
+	var mfaPendiente bool
+	var creada, expira, ultimo time.Time
+	var d auth.Dispositivo
+	if err := fila.Scan(&token, &uid, &perfil, &mfaPendiente, &creada, &expira, &ultimo, &d.UserAgent, &d.IP, &suplantado); err != nil {
+		return nil, err
+	}
+	a := auth.RecreateAutenticacionFromDB(token, uid, expira)
+	return auth.RecreateSesionFromDB(a, int(perfil.Int64), mfaPendiente, creada, ultimo, d, suplantado), nil
+}
+
+func truncar(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func (s *MySQLStorage) DeleteSession(token string) error {
+	_, err := s.DB.Exec("DELETE FROM sesiones WHERE TOKEN = ?", token)
+	return err
+}
+
+func (s *MySQLStorage) DeleteSessionsByUserID(uid string) error {
+	_, err := s.DB.Exec("DELETE FROM sesiones WHERE USUARIO_ID = ?", uid)
+	return err
+}
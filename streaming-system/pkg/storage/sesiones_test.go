@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"streaming-system/pkg/auth"
+	"testing"
+	"time"
+)
+
+// TestAlmacenSesiones ejecuta las mismas comprobaciones contra el almacén en
+// memoria y contra MySQL, para que los dos se comporten igual.
+func TestAlmacenSesiones(t *testing.T) {
+	almacenes := []struct {
+		nombre string
+		nuevo  func(t *testing.T) auth.AlmacenSesiones
+	}{
+		{"memoria", func(t *testing.T) auth.AlmacenSesiones { return auth.NuevoAlmacenSesionesMemoria() }},
+		{"mysql", func(t *testing.T) auth.AlmacenSesiones { return baseDePrueba(t) }},
+	}
+	for _, a := range almacenes {
+		t.Run(a.nombre, func(t *testing.T) { probarAlmacenSesiones(t, a.nuevo(t)) })
+	}
+}
+
+// sesionDePrueba crea una sesión con fechas en segundos enteros, que es lo
+// que conserva una columna DATETIME.
+func sesionDePrueba(uid string, perfil int, mfa bool, expira time.Time, d auth.Dispositivo, suplantado string) *auth.Sesion {
+	a := &auth.Autenticacion{}
+	a.GenerarToken(uid)
+	creada := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	return auth.RecreateSesionFromDB(auth.RecreateAutenticacionFromDB(a.GetToken(), uid, expira), perfil, mfa, creada, creada, d, suplantado)
+}
+
+func tokensDe(t *testing.T, almacen auth.AlmacenSesiones, uid string) []string {
+	t.Helper()
+	sesiones, err := almacen.LoadSessionsByUserID(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens []string
+	for _, s := range sesiones {
+		tokens = append(tokens, s.GetToken())
+	}
+	slices.Sort(tokens)
+	return tokens
+}
+
+func cargarSesion(t *testing.T, almacen auth.AlmacenSesiones, token string) *auth.Sesion {
+	t.Helper()
+	s, err := almacen.LoadSession(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func probarAlmacenSesiones(t *testing.T, almacen auth.AlmacenSesiones) {
+	ahora := time.Now().UTC().Truncate(time.Second)
+	// Usuarios 1, 2 y 3 y perfil 101 (del usuario 1) existen en el volcado.
+	principal := sesionDePrueba("1", 101, true, ahora.Add(time.Hour), auth.Dispositivo{UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0", IP: "198.51.100.1"}, "3")
+	otra := sesionDePrueba("1", 0, false, ahora.Add(2*time.Hour), auth.Dispositivo{}, "")
+	ajena := sesionDePrueba("2", 0, false, ahora.Add(time.Hour), auth.Dispositivo{}, "")
+	caducada := sesionDePrueba("1", 0, false, ahora.Add(-time.Minute), auth.Dispositivo{}, "")
+	for _, s := range []*auth.Sesion{principal, otra, ajena, caducada} {
+		if err := almacen.SaveSession(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := cargarSesion(t, almacen, principal.GetToken())
+	campos := []struct {
+		campo          string
+		obtenido, dado any
+	}{
+		{"usuario", s.GetUsuarioID(), "1"},
+		{"perfil", s.GetPerfilID(), 101},
+		{"MFA pendiente", s.MFAPendiente(), true},
+		{"creada", s.GetCreada().Unix(), principal.GetCreada().Unix()},
+		{"expiración", s.GetExpiracion().Unix(), principal.GetExpiracion().Unix()},
+		{"último acceso", s.GetUltimoAcceso().Unix(), principal.GetUltimoAcceso().Unix()},
+		{"dispositivo", s.GetDispositivo(), principal.GetDispositivo()},
+		{"suplantado", s.GetSuplantadoID(), "3"},
+	}
+	for _, c := range campos {
+		if c.obtenido != c.dado {
+			t.Errorf("%s: %v, se esperaba %v", c.campo, c.obtenido, c.dado)
+		}
+	}
+	if _, err := almacen.LoadSession("no-existe"); !errors.Is(err, auth.ErrSesionInvalida) {
+		t.Fatalf("LoadSession de un token desconocido: %v", err)
+	}
+
+	// Lo cargado es una copia: no cambia nada hasta guardarlo.
+	s.SeleccionarPerfil(0)
+	s.CompletarMFA()
+	s.TerminarSuplantacion()
+	if r := cargarSesion(t, almacen, principal.GetToken()); r.GetPerfilID() != 101 || !r.MFAPendiente() || r.GetSuplantadoID() != "3" {
+		t.Fatalf("la sesión cambió sin guardarla: perfil %d, MFA %v, suplantado %q", r.GetPerfilID(), r.MFAPendiente(), r.GetSuplantadoID())
+	}
+	if err := almacen.SaveSession(s); err != nil {
+		t.Fatal(err)
+	}
+	if r := cargarSesion(t, almacen, principal.GetToken()); r.GetPerfilID() != 0 || r.MFAPendiente() || r.GetSuplantadoID() != "" || !r.GetCreada().Equal(principal.GetCreada()) {
+		t.Fatalf("tras guardar: perfil %d, MFA %v, suplantado %q, creada %v", r.GetPerfilID(), r.MFAPendiente(), r.GetSuplantadoID(), r.GetCreada())
+	}
+
+	// Solo las sesiones vigentes del usuario.
+	esperadas := []string{principal.GetToken(), otra.GetToken()}
+	slices.Sort(esperadas)
+	if got := tokensDe(t, almacen, "1"); !slices.Equal(got, esperadas) {
+		t.Fatalf("sesiones del usuario 1: %v, se esperaba %v", got, esperadas)
+	}
+
+	if err := almacen.TouchSession(principal.GetToken(), ahora, "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if r := cargarSesion(t, almacen, principal.GetToken()); !r.GetUltimoAcceso().Equal(ahora) || r.GetDispositivo().IP != "203.0.113.7" || r.GetDispositivo().UserAgent != principal.GetDispositivo().UserAgent {
+		t.Fatalf("tras TouchSession: %v desde %+v", r.GetUltimoAcceso(), r.GetDispositivo())
+	}
+
+	if err := almacen.DeleteSession(otra.GetToken()); err != nil {
+		t.Fatal(err)
+	}
+	if got := tokensDe(t, almacen, "1"); !slices.Equal(got, []string{principal.GetToken()}) {
+		t.Fatalf("tras DeleteSession: %v", got)
+	}
+	if err := almacen.DeleteSessionsByUserID("1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{principal.GetToken(), caducada.GetToken()} {
+		if _, err := almacen.LoadSession(token); !errors.Is(err, auth.ErrSesionInvalida) {
+			t.Fatalf("la sesión %s sigue tras DeleteSessionsByUserID: %v", token, err)
+		}
+	}
+	if got := tokensDe(t, almacen, "2"); !slices.Equal(got, []string{ajena.GetToken()}) {
+		t.Fatalf("DeleteSessionsByUserID borró sesiones de otro usuario: %v", got)
+	}
+}
@@ -2,13 +2,15 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"streaming-system/pkg/auth"
 	"streaming-system/pkg/billing"
 	"streaming-system/pkg/content"
+	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 type DBConfig struct {
@@ -32,31 +34,151 @@ func NewMySQLStorage(config DBConfig) (*MySQLStorage, error) {
 // --- CRUD CONTENIDOS ---
 
 func (s *MySQLStorage) LoadAllContent() ([]content.Contenible, error) {
-	rows, err := s.DB.Query("SELECT CONTENIDO_ID, TITULO, DESCRIPCION FROM contenidos")
+	// Sin clasificación se asume la más restrictiva, para no exponerlo a perfiles infantiles.
+	rows, err := s.DB.Query(`SELECT c.CONTENIDO_ID, c.TITULO, COALESCE(c.DESCRIPCION, ''), COALESCE(c.CLASIFICACION_EDAD, 18), c.TIPO,
+		COALESCE(p.GENEROS, d.GENERO, s.GENERO, ''), COALESCE(p.DIRECTOR, ''), COALESCE(p.TRAILER_URL, ''),
+		COALESCE(p.DURACION_MINUTOS, d.DURACION_MINUTOS, 0), COALESCE(p.ANIO_ESTRENO, 0), COALESCE(p.IDIOMA_ORIGINAL, ''),
+		COALESCE(d.TEMA, ''), COALESCE(d.NARRADOR, ''), COALESCE(c.ES_ESTRENO, 0)
+		FROM contenidos c LEFT JOIN peliculas p ON p.CONTENIDO_ID = c.CONTENIDO_ID
+		LEFT JOIN documentales d ON d.CONTENIDO_ID = c.CONTENIDO_ID
+		LEFT JOIN series s ON s.CONTENIDO_ID = c.CONTENIDO_ID`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var res []content.Contenible
+	documentales := make(map[string]*content.Documental)
+	series := make(map[string]*content.Serie)
 	for rows.Next() {
-		var id, t, d string
-		rows.Scan(&id, &t, &d)
-		res = append(res, content.NuevaPelicula(id, t, d, "General", "N/A", "N/A", 0))
+		var id, t, d, tipo, genero, director, trailer, idioma, tema, narrador string
+		var edad, anio int
+		var duracion float32
+		var estreno bool
+		if err := rows.Scan(&id, &t, &d, &edad, &tipo, &genero, &director, &trailer, &duracion, &anio, &idioma, &tema, &narrador, &estreno); err != nil {
+			return nil, err
+		}
+		var c content.Contenible
+		switch tipo {
+		case content.TipoDocumental:
+			doc := content.NuevoDocumental(id, t, d, genero, tema, narrador, duracion, edad)
+			documentales[id] = doc
+			c = doc
+		case content.TipoSerie:
+			serie := content.NuevaSerie(id, t, d, genero, 0, edad)
+			series[id] = serie
+			c = serie
+		default:
+			c = content.NuevaPelicula(id, t, d, content.SepararGeneros(genero), director, trailer, duracion, anio, idioma, edad)
+		}
+		content.MarcarEstreno(c, estreno)
+		res = append(res, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.cargarEpisodiosDocumentales(documentales); err != nil {
+		return nil, err
+	}
+	if err := s.cargarTemporadasSeries(series); err != nil {
+		return nil, err
 	}
 	return res, nil
 }
 
+// SaveContent inserta el contenido y los datos propios de su tipo en la misma
+// transacción.
 func (s *MySQLStorage) SaveContent(c content.Contenible) error {
-	// Corregido: GetDescripcion() para evitar el error de compilación
-	query := `INSERT INTO contenidos (CONTENIDO_ID, TITULO, DESCRIPCION, CLASIFICACION_EDAD, ES_ESTRENO, PRECIO_COMPRA) VALUES (?, ?, ?, '13+', 1, 0)`
-	_, err := s.DB.Exec(query, c.GetID(), c.GetTitulo(), c.GetDescripcion())
-	return err
+	tipo := content.TipoDe(c)
+	if tipo == "" {
+		return content.ErrTipoNoSoportado
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `INSERT INTO contenidos (CONTENIDO_ID, TITULO, DESCRIPCION, CLASIFICACION_EDAD, ES_ESTRENO, PRECIO_COMPRA, TIPO) VALUES (?, ?, ?, ?, ?, 0, ?)`
+	if _, err := tx.Exec(query, c.GetID(), c.GetTitulo(), c.GetDescripcion(), c.GetClasificacionEdad(), c.EsEstreno(), tipo); err != nil {
+		return err
+	}
+	if err := guardarPorTipo(tx, c); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *MySQLStorage) UpdateContentFull(id string, t string, d string) error {
-	query := `UPDATE contenidos SET TITULO = ?, DESCRIPCION = ? WHERE CONTENIDO_ID = ?`
-	_, err := s.DB.Exec(query, t, d, id)
-	return err
+// guardarPorTipo escribe todos los datos propios del tipo de contenido,
+// incluidas las temporadas y episodios.
+func guardarPorTipo(tx *sql.Tx, c content.Contenible) error {
+	switch v := c.(type) {
+	case *content.Pelicula:
+		return guardarPelicula(tx, v)
+	case *content.Documental:
+		return guardarDocumental(tx, v)
+	case *content.Serie:
+		return guardarSerie(tx, v)
+	}
+	return content.ErrTipoNoSoportado
+}
+
+// UpdateContentFull reescribe todos los metadatos de un contenido existente.
+// De una serie solo actualiza el género: sus temporadas y episodios tienen
+// sus propios métodos (SaveSeason, SaveEpisode...).
+func (s *MySQLStorage) UpdateContentFull(c content.Contenible) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`UPDATE contenidos SET TITULO = ?, DESCRIPCION = ?, CLASIFICACION_EDAD = ?, ES_ESTRENO = ? WHERE CONTENIDO_ID = ? AND TIPO = ?`,
+		c.GetTitulo(), c.GetDescripcion(), c.GetClasificacionEdad(), c.EsEstreno(), c.GetID(), content.TipoDe(c))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		// MySQL cuenta 0 filas si nada cambió, así que se comprueba que exista.
+		var existe bool
+		if err := tx.QueryRow("SELECT 1 FROM contenidos WHERE CONTENIDO_ID = ? AND TIPO = ?", c.GetID(), content.TipoDe(c)).Scan(&existe); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return content.ErrContenidoNoEncontrado
+			}
+			return err
+		}
+	}
+	switch v := c.(type) {
+	case *content.Pelicula:
+		err = guardarPelicula(tx, v)
+	case *content.Documental:
+		err = guardarDocumental(tx, v)
+	case *content.Serie:
+		err = guardarGeneroSerie(tx, v)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ImportContent crea o reemplaza por completo cada contenido (con sus
+// temporadas y episodios) en una sola transacción: o se importan todos o ninguno.
+func (s *MySQLStorage) ImportContent(cs []content.Contenible) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, c := range cs {
+		_, err := tx.Exec(`INSERT INTO contenidos (CONTENIDO_ID, TITULO, DESCRIPCION, CLASIFICACION_EDAD, ES_ESTRENO, PRECIO_COMPRA, TIPO) VALUES (?, ?, ?, ?, ?, 0, ?)
+			ON DUPLICATE KEY UPDATE TITULO = VALUES(TITULO), DESCRIPCION = VALUES(DESCRIPCION), CLASIFICACION_EDAD = VALUES(CLASIFICACION_EDAD), ES_ESTRENO = VALUES(ES_ESTRENO)`,
+			c.GetID(), c.GetTitulo(), c.GetDescripcion(), c.GetClasificacionEdad(), c.EsEstreno(), content.TipoDe(c))
+		if err != nil {
+			return fmt.Errorf("contenido %s: %w", c.GetID(), err)
+		}
+		if err := guardarPorTipo(tx, c); err != nil {
+			return fmt.Errorf("contenido %s: %w", c.GetID(), err)
+		}
+	}
+	return tx.Commit()
 }
 
 func (s *MySQLStorage) DeleteContent(id string) error {
@@ -67,34 +189,144 @@ func (s *MySQLStorage) DeleteContent(id string) error {
 // --- USUARIOS, PERFILES Y SUSCRIPCIONES ---
 
 func (s *MySQLStorage) SaveUser(u *auth.Usuario) error {
-	_, err := s.DB.Exec("INSERT INTO usuarios (USUARIO_ID, NOMBRE_USUARIO, EMAIL, PASSWORD_HASH, METODOS_PAGO_METODO_PAGO_ID) VALUES (?, ?, ?, ?, 0)", u.GetID(), u.GetNombre(), u.GetCorreo(), u.GetContraseniaHash())
+	_, err := s.DB.Exec("INSERT INTO usuarios (USUARIO_ID, NOMBRE_USUARIO, EMAIL, PASSWORD_HASH, EMAIL_VERIFICADO, METODOS_PAGO_METODO_PAGO_ID) VALUES (?, ?, ?, ?, ?, 0)", u.GetID(), u.GetNombre(), u.GetCorreo(), u.GetContraseniaHash(), u.EmailVerificado())
+	// La unicidad del correo la garantiza la restricción UK_EMAIL, no una consulta previa.
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1062 && strings.Contains(me.Message, "UK_EMAIL") {
+		return auth.ErrEmailEnUso
+	}
 	return err
 }
 
 func (s *MySQLStorage) LoadUserByEmail(e string) (*auth.Usuario, error) {
+	return s.cargarUsuario("SELECT USUARIO_ID, NOMBRE_USUARIO, EMAIL, PASSWORD_HASH, EMAIL_VERIFICADO FROM usuarios WHERE EMAIL = ?", e)
+}
+
+func (s *MySQLStorage) LoadUserByID(uid string) (*auth.Usuario, error) {
+	return s.cargarUsuario("SELECT USUARIO_ID, NOMBRE_USUARIO, EMAIL, PASSWORD_HASH, EMAIL_VERIFICADO FROM usuarios WHERE USUARIO_ID = ?", uid)
+}
+
+func (s *MySQLStorage) cargarUsuario(query string, args ...any) (*auth.Usuario, error) {
 	var id, n, em, h string
-	err := s.DB.QueryRow("SELECT USUARIO_ID, NOMBRE_USUARIO, EMAIL, PASSWORD_HASH FROM usuarios WHERE EMAIL = ?", e).Scan(&id, &n, &em, &h)
+	var verificado bool
+	err := s.DB.QueryRow(query, args...).Scan(&id, &n, &em, &h, &verificado)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrUsuarioNoEncontrado
+	}
 	if err != nil {
 		return nil, err
 	}
-	return auth.RecreateUsuarioFromDB(id, n, em, h, nil), nil
+	u := auth.RecreateUsuarioFromDB(id, n, em, h, nil)
+	if verificado {
+		u.MarcarEmailVerificado()
+	}
+	return s.cargarRoles(u)
+}
+
+// MarkEmailVerified marca el correo como verificado solo si sigue siendo el
+// correo para el que se emitió el enlace.
+func (s *MySQLStorage) MarkEmailVerified(uid, email string) error {
+	_, err := s.DB.Exec("UPDATE usuarios SET EMAIL_VERIFICADO = 1 WHERE USUARIO_ID = ? AND EMAIL = ?", uid, email)
+	return err
+}
+
+func (s *MySQLStorage) UpdatePasswordHash(uid, hash string) error {
+	_, err := s.DB.Exec("UPDATE usuarios SET PASSWORD_HASH = ? WHERE USUARIO_ID = ?", hash, uid)
+	return err
 }
 
+const columnasPerfil = "PERFIL_ID, NOMBRE_PERFIL, AVATAR, IDIOMA_INTERFAZ, IDIOMA_SUBTITULOS, AUTOPLAY, COALESCE(CLASIFICACION_EDAD_MAXIMA, 18), COALESCE(PIN_HASH, '')"
+
 func (s *MySQLStorage) SaveProfile(uid string, p *auth.Perfil) error {
-	_, err := s.DB.Exec("INSERT INTO perfiles (PERFIL_ID, USUARIO_ID, NOMBRE_PERFIL, CLASIFICACION_EDAD_MAXIMA, USUARIOS_USUARIO_ID) VALUES (?, ?, ?, 18, ?)", p.GetID(), uid, p.GetNombre(), uid)
+	pref := p.GetPreferencias()
+	_, err := s.DB.Exec("INSERT INTO perfiles (PERFIL_ID, USUARIO_ID, NOMBRE_PERFIL, CLASIFICACION_EDAD_MAXIMA, USUARIOS_USUARIO_ID, AVATAR, IDIOMA_INTERFAZ, IDIOMA_SUBTITULOS, AUTOPLAY) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		p.GetID(), uid, p.GetNombre(), p.GetClasificacionMaxima(), uid, p.GetAvatar().ID, pref.IdiomaInterfaz, pref.IdiomaSubtitulos, pref.Autoplay)
+	return err
+}
+
+// UpdateProfile guarda nombre, avatar y preferencias de un perfil del usuario.
+func (s *MySQLStorage) UpdateProfile(uid string, p *auth.Perfil) error {
+	pref := p.GetPreferencias()
+	res, err := s.DB.Exec("UPDATE perfiles SET NOMBRE_PERFIL = ?, CLASIFICACION_EDAD_MAXIMA = ?, AVATAR = ?, IDIOMA_INTERFAZ = ?, IDIOMA_SUBTITULOS = ?, AUTOPLAY = ? WHERE PERFIL_ID = ? AND USUARIOS_USUARIO_ID = ?",
+		p.GetNombre(), p.GetClasificacionMaxima(), p.GetAvatar().ID, pref.IdiomaInterfaz, pref.IdiomaSubtitulos, pref.Autoplay, p.GetID(), uid)
+	if err != nil {
+		return err
+	}
+	// Sin cambios MySQL informa 0 filas afectadas, así que se comprueba la existencia aparte.
+	if n, _ := res.RowsAffected(); n == 0 {
+		_, err = s.LoadProfile(uid, p.GetID())
+	}
+	return err
+}
+
+// UpdateProfilePIN guarda el hash del PIN del perfil; vacío lo elimina.
+func (s *MySQLStorage) UpdateProfilePIN(uid string, id int, hash string) error {
+	var pin sql.NullString
+	if hash != "" {
+		pin = sql.NullString{String: hash, Valid: true}
+	}
+	_, err := s.DB.Exec("UPDATE perfiles SET PIN_HASH = ? WHERE PERFIL_ID = ? AND USUARIOS_USUARIO_ID = ?", pin, id, uid)
 	return err
 }
 
+// DeleteProfile elimina un perfil del usuario junto con su historial de visualizaciones.
+func (s *MySQLStorage) DeleteProfile(uid string, id int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var existe int
+	err = tx.QueryRow("SELECT 1 FROM perfiles WHERE PERFIL_ID = ? AND USUARIOS_USUARIO_ID = ? FOR UPDATE", id, uid).Scan(&existe)
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.ErrPerfilNoEncontrado
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM historial_visualizaciones WHERE PERFILES_PERFIL_ID = ? OR PERFIL_ID = ?", id, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM perfiles WHERE PERFIL_ID = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadProfile devuelve un perfil solo si pertenece al usuario.
+func (s *MySQLStorage) LoadProfile(uid string, id int) (*auth.Perfil, error) {
+	p, err := escanearPerfil(s.DB.QueryRow("SELECT "+columnasPerfil+" FROM perfiles WHERE PERFIL_ID = ? AND USUARIOS_USUARIO_ID = ?", id, uid))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrPerfilNoEncontrado
+	}
+	return p, err
+}
+
 func (s *MySQLStorage) LoadProfilesByUserID(uid string) ([]*auth.Perfil, error) {
-	rows, _ := s.DB.Query("SELECT PERFIL_ID, NOMBRE_PERFIL FROM perfiles WHERE USUARIOS_USUARIO_ID = ?", uid)
+	rows, err := s.DB.Query("SELECT "+columnasPerfil+" FROM perfiles WHERE USUARIOS_USUARIO_ID = ? ORDER BY PERFIL_ID", uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	var res []*auth.Perfil
 	for rows.Next() {
-		var id int
-		var n string
-		rows.Scan(&id, &n)
-		res = append(res, auth.NuevoPerfil(id, n))
+		p, err := escanearPerfil(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
 	}
-	return res, nil
+	return res, rows.Err()
+}
+
+func escanearPerfil(fila interface{ Scan(...any) error }) (*auth.Perfil, error) {
+	var id, edadMaxima int
+	var n, avatar, pin string
+	var pref auth.PreferenciasPerfil
+	if err := fila.Scan(&id, &n, &avatar, &pref.IdiomaInterfaz, &pref.IdiomaSubtitulos, &pref.Autoplay, &edadMaxima, &pin); err != nil {
+		return nil, err
+	}
+	return auth.RecreatePerfilFromDB(id, n, avatar, edadMaxima, pin, pref), nil
 }
 
 func (s *MySQLStorage) SaveSubscription(uid string, sub *billing.Suscripcion) error {
@@ -113,3 +345,28 @@ func (s *MySQLStorage) LoadActiveSubscription(uid string) (*billing.Suscripcion,
 	}
 	return billing.RecreateSuscripcionFromDB("S-"+uid, billing.NuevoPlan(tp, tp, pr, pr), si, sf, es), nil
 }
+
+// ResumenSuscripcion es una fila del listado de facturación del panel admin.
+type ResumenSuscripcion struct {
+	UsuarioID, Email, Plan, Estado string
+	FechaFin                       time.Time
+	Precio                         float64
+}
+
+func (s *MySQLStorage) LoadSubscriptionSummaries() ([]ResumenSuscripcion, error) {
+	rows, err := s.DB.Query(`SELECT u.USUARIO_ID, u.EMAIL, p.TIPO_PLAN, p.ESTADO_SUSCRIPCION, p.FECHA_FIN, p.PRECIO_MENSUAL_ANUAL
+		FROM planes_suscripcion p JOIN usuarios u ON u.USUARIO_ID = p.USUARIOS_USUARIO_ID ORDER BY p.FECHA_FIN DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []ResumenSuscripcion
+	for rows.Next() {
+		var r ResumenSuscripcion
+		if err := rows.Scan(&r.UsuarioID, &r.Email, &r.Plan, &r.Estado, &r.FechaFin, &r.Precio); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"streaming-system/pkg/auth"
+	"time"
+)
+
+// --- REFRESH TOKENS (implementa auth.AlmacenRefresco) ---
+
+func (s *MySQLStorage) SaveRefreshToken(t *auth.TokenRefresco) error {
+	_, err := s.DB.Exec("INSERT INTO refresh_tokens (TOKEN_HASH, USUARIO_ID, FAMILIA, FECHA_EXPIRACION, USADO, REVOCADO) VALUES (?, ?, ?, ?, ?, ?)",
+		t.GetHash(), t.GetUsuarioID(), t.GetFamilia(), t.GetExpira(), t.EstaUsado(), t.EstaRevocado())
+	return err
+}
+
+func (s *MySQLStorage) LoadRefreshToken(hash string) (*auth.TokenRefresco, error) {
+	var uid, familia string
+	var expira time.Time
+	var usado, revocado bool
+	err := s.DB.QueryRow("SELECT USUARIO_ID, FAMILIA, FECHA_EXPIRACION, USADO, REVOCADO FROM refresh_tokens WHERE TOKEN_HASH = ?", hash).Scan(&uid, &familia, &expira, &usado, &revocado)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrTokenInvalido
+	}
+	if err != nil {
+		return nil, err
+	}
+	return auth.RecreateTokenRefrescoFromDB(hash, uid, familia, expira, usado, revocado), nil
+}
+
+func (s *MySQLStorage) MarkRefreshTokenUsed(hash string) error {
+	res, err := s.DB.Exec("UPDATE refresh_tokens SET USADO = 1 WHERE TOKEN_HASH = ? AND USADO = 0", hash)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return auth.ErrReutilizacionRefresco
+	}
+	return nil
+}
+
+func (s *MySQLStorage) RevokeRefreshFamily(familia string) error {
+	_, err := s.DB.Exec("UPDATE refresh_tokens SET REVOCADO = 1 WHERE FAMILIA = ?", familia)
+	return err
+}
+
+func (s *MySQLStorage) RevokeRefreshTokensByUserID(uid string) error {
+	_, err := s.DB.Exec("UPDATE refresh_tokens SET REVOCADO = 1 WHERE USUARIO_ID = ?", uid)
+	return err
+}